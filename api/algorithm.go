@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+)
+
+type AlgorithmResponse struct {
+	Name string `json:"name"`
+}
+
+// ShowAllAlgorithms lists the algorithms registered in the crypto package.
+func (s *Server) ShowAllAlgorithms(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	names := crypto.Algorithms()
+	algorithmResponses := make([]AlgorithmResponse, 0, len(names))
+	for _, name := range names {
+		algorithmResponses = append(algorithmResponses, AlgorithmResponse{
+			Name: name,
+		})
+	}
+
+	WriteAPIResponse(response, http.StatusOK, algorithmResponses)
+}
@@ -1,14 +1,40 @@
 package api
 
 import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto/ca"
 	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
 	mock_persistence "github.com/fiskaly/coding-challenges/signing-service-challenge/persistence/mocks"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -31,6 +57,7 @@ var _ = Describe("Device Management", func() {
 		mockDeviceRepository = mock_persistence.NewMockIDeviceRepository(ctrl)
 		server = &Server{
 			DeviceRepository: mockDeviceRepository,
+			UnitOfWork: persistence.NewUnitOfWork(mockDeviceRepository, nil, nil, nil),
 		}
 	})
 
@@ -47,6 +74,223 @@ var _ = Describe("Device Management", func() {
 
 			Expect(w.Code).To(Equal(http.StatusCreated))
 		})
+
+		It("should create an Ed25519 signature device whose key can sign and verify", func() {
+			var createdDevice *domain.Device
+			mockDeviceRepository.EXPECT().CreateDevice(gomock.Any()).DoAndReturn(func(device *domain.Device) error {
+				createdDevice = device
+				return nil
+			})
+
+			req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "Ed25519", "label": "test-device"}`))
+			w := httptest.NewRecorder()
+
+			server.CreateSignatureDevice(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusCreated))
+			keyPair, err := crypto.NewEd25519Marshaler().Decode([]byte(createdDevice.KeyReference))
+			Expect(err).NotTo(HaveOccurred())
+			signature, err := crypto.NewEd25519Signer(keyPair).Sign([]byte("test-data"))
+			Expect(err).NotTo(HaveOccurred())
+			verifier, err := newVerifier(createdDevice)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(verifier.Verify([]byte("test-data"), signature)).To(Succeed())
+		})
+
+		DescribeTable("should sign and verify with the requested key parameters",
+			func(body string, expected domain.KeyParameters) {
+				var createdDevice *domain.Device
+				mockDeviceRepository.EXPECT().CreateDevice(gomock.Any()).DoAndReturn(func(device *domain.Device) error {
+					createdDevice = device
+					return nil
+				})
+
+				req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(body))
+				w := httptest.NewRecorder()
+
+				server.CreateSignatureDevice(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusCreated))
+				Expect(createdDevice.KeyParameters).To(Equal(expected))
+
+				signature, err := signDetached(server, createdDevice, "test-data")
+				Expect(err).NotTo(HaveOccurred())
+				verifier, err := newVerifier(createdDevice)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(verifySignatureValue(verifier, signature.SignedData, signature.SignatureValue)).To(Succeed())
+			},
+			Entry("RSA defaults", `{"algorithm": "RSA"}`,
+				domain.KeyParameters{KeySize: 2048, Hash: "SHA-256", Padding: "PSS"}),
+			Entry("RSA PKCS#1 v1.5 with SHA-384", `{"algorithm": "RSA", "hash": "SHA-384", "padding": "PKCS1v15"}`,
+				domain.KeyParameters{KeySize: 2048, Hash: "SHA-384", Padding: "PKCS1v15"}),
+			Entry("ECC defaults", `{"algorithm": "ECC"}`,
+				domain.KeyParameters{Curve: "P-384", Hash: "SHA-384"}),
+			Entry("ECC P-256", `{"algorithm": "ECC", "curve": "P-256"}`,
+				domain.KeyParameters{Curve: "P-256", Hash: "SHA-256"}),
+			Entry("ECC P-521 with SHA-256", `{"algorithm": "ECC", "curve": "P-521", "hash": "SHA-256"}`,
+				domain.KeyParameters{Curve: "P-521", Hash: "SHA-256"}),
+		)
+
+		DescribeTable("should reject key parameters that do not fit the algorithm",
+			func(body string) {
+				req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(body))
+				w := httptest.NewRecorder()
+
+				server.CreateSignatureDevice(w, req)
+
+				Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			},
+			Entry("RSA with 1024 bits", `{"algorithm": "RSA", "key_size": 1024}`),
+			Entry("RSA with a curve", `{"algorithm": "RSA", "curve": "P-256"}`),
+			Entry("ECC with a key size", `{"algorithm": "ECC", "key_size": 2048}`),
+			Entry("ECC with an unknown curve", `{"algorithm": "ECC", "curve": "P-192"}`),
+			Entry("Ed25519 with a hash", `{"algorithm": "Ed25519", "hash": "SHA-512"}`),
+		)
+
+		Context("When importing an existing private key", func() {
+			pemKey := func(blockType string, der []byte) interface{} {
+				return string(pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}))
+			}
+			marshaled := func(der []byte, err error) []byte {
+				Expect(err).NotTo(HaveOccurred())
+				return der
+			}
+			b64 := func(value []byte) string {
+				return base64.RawURLEncoding.EncodeToString(value)
+			}
+
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			Expect(err).NotTo(HaveOccurred())
+			ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+			_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+			Expect(err).NotTo(HaveOccurred())
+
+			importDevice := func(algorithm string, privateKey interface{}) (*httptest.ResponseRecorder, *domain.Device) {
+				var createdDevice *domain.Device
+				mockDeviceRepository.EXPECT().CreateDevice(gomock.Any()).DoAndReturn(func(device *domain.Device) error {
+					createdDevice = device
+					return nil
+				}).MaxTimes(1)
+
+				body, err := json.Marshal(map[string]interface{}{"algorithm": algorithm, "private_key": privateKey})
+				Expect(err).NotTo(HaveOccurred())
+				req := httptest.NewRequest("POST", "/api/v0/device", bytes.NewReader(body))
+				w := httptest.NewRecorder()
+
+				server.CreateSignatureDevice(w, req)
+				return w, createdDevice
+			}
+
+			DescribeTable("should keep the imported key",
+				func(algorithm string, privateKey func() interface{}, publicKey interface{}, expected domain.KeyParameters) {
+					w, createdDevice := importDevice(algorithm, privateKey())
+
+					Expect(w.Code).To(Equal(http.StatusCreated))
+					Expect(createdDevice.KeyParameters).To(Equal(expected))
+
+					block, _ := pem.Decode([]byte(createdDevice.PublicKey))
+					Expect(block).NotTo(BeNil())
+					importedPublicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+					if err != nil {
+						importedPublicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+					}
+					Expect(err).NotTo(HaveOccurred())
+					Expect(marshaled(x509.MarshalPKIXPublicKey(importedPublicKey))).To(Equal(marshaled(x509.MarshalPKIXPublicKey(publicKey))))
+
+					verifier, err := newVerifier(createdDevice)
+					Expect(err).NotTo(HaveOccurred())
+					signature, err := signDetached(server, createdDevice, "test-data")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(verifySignatureValue(verifier, signature.SignedData, signature.SignatureValue)).To(Succeed())
+				},
+				Entry("RSA as PKCS#1", "RSA", func() interface{} {
+					return pemKey("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+				}, &rsaKey.PublicKey, domain.KeyParameters{KeySize: 2048, Hash: "SHA-256", Padding: "PSS"}),
+				Entry("RSA as PKCS#8", "RSA", func() interface{} {
+					return pemKey("PRIVATE KEY", marshaled(x509.MarshalPKCS8PrivateKey(rsaKey)))
+				}, &rsaKey.PublicKey, domain.KeyParameters{KeySize: 2048, Hash: "SHA-256", Padding: "PSS"}),
+				Entry("RSA as JWK", "RSA", func() interface{} {
+					return map[string]string{
+						"kty": "RSA",
+						"n":   b64(rsaKey.N.Bytes()),
+						"e":   b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+						"d":   b64(rsaKey.D.Bytes()),
+						"p":   b64(rsaKey.Primes[0].Bytes()),
+						"q":   b64(rsaKey.Primes[1].Bytes()),
+					}
+				}, &rsaKey.PublicKey, domain.KeyParameters{KeySize: 2048, Hash: "SHA-256", Padding: "PSS"}),
+				Entry("ECC as SEC1", "ECC", func() interface{} {
+					return pemKey("EC PRIVATE KEY", marshaled(x509.MarshalECPrivateKey(ecdsaKey)))
+				}, &ecdsaKey.PublicKey, domain.KeyParameters{Curve: "P-256", Hash: "SHA-256"}),
+				Entry("ECC as PKCS#8", "ECC", func() interface{} {
+					return pemKey("PRIVATE KEY", marshaled(x509.MarshalPKCS8PrivateKey(ecdsaKey)))
+				}, &ecdsaKey.PublicKey, domain.KeyParameters{Curve: "P-256", Hash: "SHA-256"}),
+				Entry("ECC as JWK", "ECC", func() interface{} {
+					return map[string]string{
+						"kty": "EC",
+						"crv": "P-256",
+						"x":   b64(ecdsaKey.X.FillBytes(make([]byte, 32))),
+						"y":   b64(ecdsaKey.Y.FillBytes(make([]byte, 32))),
+						"d":   b64(ecdsaKey.D.FillBytes(make([]byte, 32))),
+					}
+				}, &ecdsaKey.PublicKey, domain.KeyParameters{Curve: "P-256", Hash: "SHA-256"}),
+				Entry("Ed25519 as PKCS#8", "Ed25519", func() interface{} {
+					return pemKey("PRIVATE KEY", marshaled(x509.MarshalPKCS8PrivateKey(ed25519Key)))
+				}, ed25519Key.Public(), domain.KeyParameters{}),
+				Entry("Ed25519 as JWK", "Ed25519", func() interface{} {
+					return map[string]string{"kty": "OKP", "crv": "Ed25519", "d": b64(ed25519Key.Seed())}
+				}, ed25519Key.Public(), domain.KeyParameters{}),
+			)
+
+			DescribeTable("should reject keys that do not fit the device",
+				func(algorithm string, privateKey func() interface{}) {
+					w, _ := importDevice(algorithm, privateKey())
+
+					Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+				},
+				Entry("an ECC key for an RSA device", "RSA", func() interface{} {
+					return pemKey("PRIVATE KEY", marshaled(x509.MarshalPKCS8PrivateKey(ecdsaKey)))
+				}),
+				Entry("an RSA key for an Ed25519 device", "Ed25519", func() interface{} {
+					return pemKey("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+				}),
+				Entry("an RSA key below 2048 bits", "RSA", func() interface{} {
+					weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+					Expect(err).NotTo(HaveOccurred())
+					return pemKey("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(weakKey))
+				}),
+				Entry("an ECC key on P-224", "ECC", func() interface{} {
+					weakKey, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+					Expect(err).NotTo(HaveOccurred())
+					return pemKey("EC PRIVATE KEY", marshaled(x509.MarshalECPrivateKey(weakKey)))
+				}),
+				Entry("a JWK whose public point does not match", "ECC", func() interface{} {
+					return map[string]string{
+						"kty": "EC",
+						"crv": "P-256",
+						"x":   b64(ecdsaKey.Y.FillBytes(make([]byte, 32))),
+						"y":   b64(ecdsaKey.X.FillBytes(make([]byte, 32))),
+						"d":   b64(ecdsaKey.D.FillBytes(make([]byte, 32))),
+					}
+				}),
+				Entry("a public key", "RSA", func() interface{} {
+					return pemKey("PUBLIC KEY", marshaled(x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)))
+				}),
+				Entry("a key that is neither PEM nor JWK", "RSA", func() interface{} {
+					return "not a key"
+				}),
+			)
+		})
+
+		It("should reject an unknown algorithm", func() {
+			req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "DSA", "label": "test-device"}`))
+			w := httptest.NewRecorder()
+
+			server.CreateSignatureDevice(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+		})
 	})
 })
 
@@ -65,6 +309,7 @@ var _ = Describe("Transaction Signing", func() {
 		server = &Server{
 			DeviceRepository: mockDeviceRepository,
 			SignatureRepository: mockSignatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(mockDeviceRepository, mockSignatureRepository, nil, nil),
 		}
 	})
 
@@ -79,7 +324,7 @@ MIGJAoGBAM/tvE/dja6Y8T8TbYSZHpve3ytzv1yiDwhVlF7avZRdiFRU7srNkaRR
 8r746jm/VYYA5rLftyhteEHzZHZgXKHjS+ehavTAtFe4BEcUsk7PudebgD+cFC4E
 F9Sa+aRvyTn0Rg3NFtf9s+MiixfdkDfybuqQ8lN+SqK7uOMqpnFJAgMBAAE=
 -----END RSA_PUBLIC_KEY-----`,
-				PrivateKey:       `-----BEGIN RSA_PRIVATE_KEY-----
+				KeyReference:     `-----BEGIN RSA_PRIVATE_KEY-----
 MIICXQIBAAKBgQDP7bxP3Y2umPE/E22EmR6b3t8rc79cog8IVZRe2r2UXYhUVO7K
 zZGkUfK++Oo5v1WGAOay37cobXhB82R2YFyh40vnoWr0wLRXuARHFLJOz7nXm4A/
 nBQuBBfUmvmkb8k59EYNzRbX/bPjIosX3ZA38m7qkPJTfkqiu7jjKqZxSQIDAQAB
@@ -101,7 +346,7 @@ pAQzbR1h7p39hZYB5AGCnslhokTBQiuGzUts9VVGqZ38
 			mutex := &sync.Mutex{}
 			
 			mockDeviceRepository.EXPECT().GetDeviceMutex(gomock.Any()).Return(mutex)
-			mockDeviceRepository.EXPECT().GetDevice(gomock.Any()).Return(mockDevice, nil)
+			mockDeviceRepository.EXPECT().GetDevice(gomock.Any()).Return(mockDevice, nil).Times(2)
 			mockDeviceRepository.EXPECT().IncrementSignatureCounter(gomock.Any()).Return(nil)
 			mockSignatureRepository.EXPECT().CreateSignature(gomock.Any()).Return(nil)
 
@@ -124,7 +369,7 @@ MHYwEAYHKoZIzj0CAQYFK4EEACIDYgAE6ZZszV54k4v8ssIlpj4dYFUo8SLBt11M
 QR9EDRUeYaUYweqPG8j9pgBksx1wr9yIG7PjLkcg9dxPYst8zVQpk9ULCusPJ1d9
 aOMrD5ANM8IoRAkYBUtJEirWGiCRk5/k
 -----END PUBLIC_KEY-----`,
-				PrivateKey:       `-----BEGIN PRIVATE_KEY-----
+				KeyReference:     `-----BEGIN PRIVATE_KEY-----
 MIGkAgEBBDDjn7xR+VY2ST5b/WAZ5jO/tYik3vNANKdWSaYhggvJKolorpM0JcZu
 Tqos5vIvuYqgBwYFK4EEACKhZANiAATplmzNXniTi/yywiWmPh1gVSjxIsG3XUxB
 H0QNFR5hpRjB6o8byP2mAGSzHXCv3Igbs+MuRyD13E9iy3zNVCmT1QsK6w8nV31o
@@ -137,7 +382,7 @@ H0QNFR5hpRjB6o8byP2mAGSzHXCv3Igbs+MuRyD13E9iy3zNVCmT1QsK6w8nV31o
 			mutex := &sync.Mutex{}
 			
 			mockDeviceRepository.EXPECT().GetDeviceMutex(gomock.Any()).Return(mutex)
-			mockDeviceRepository.EXPECT().GetDevice(gomock.Any()).Return(mockDevice, nil)
+			mockDeviceRepository.EXPECT().GetDevice(gomock.Any()).Return(mockDevice, nil).Times(2)
 			mockDeviceRepository.EXPECT().IncrementSignatureCounter(gomock.Any()).Return(nil)
 			mockSignatureRepository.EXPECT().CreateSignature(gomock.Any()).Return(nil)
 
@@ -151,4 +396,2247 @@ H0QNFR5hpRjB6o8byP2mAGSzHXCv3Igbs+MuRyD13E9iy3zNVCmT1QsK6w8nV31o
 			Expect(w.Code).To(Equal(http.StatusOK))
 		})
 	})
-})
\ No newline at end of file
+})
+
+var _ = Describe("Chain Verification", func() {
+	var (
+		server *Server
+		device *domain.Device
+	)
+
+	signTransaction := func(data string) {
+		req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "test-device", "data": "`+data+`"}`))
+		w := httptest.NewRecorder()
+
+		server.SignTransaction(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+	}
+
+	verifyChain := func() ChainVerificationResponse {
+		req := httptest.NewRequest("GET", "/api/v0/devices/test-device/verify-chain", nil)
+		req.SetPathValue("id", "test-device")
+		w := httptest.NewRecorder()
+
+		server.VerifyChain(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var result struct {
+			Data ChainVerificationResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &result)).To(Succeed())
+		return result.Data
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+		}
+
+		generator := crypto.ECCGenerator{}
+		keyPair, err := generator.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		public, private, err := crypto.NewECCMarshaler().Encode(*keyPair)
+		Expect(err).NotTo(HaveOccurred())
+
+		device = &domain.Device{
+			ID:         "test-device",
+			Algorithm:  "ECC",
+			PublicKey:  string(public),
+			KeyReference: string(private),
+		}
+		Expect(deviceRepository.CreateDevice(device)).To(Succeed())
+
+		signTransaction("first")
+		signTransaction("second")
+		signTransaction("third")
+	})
+
+	Context("When the chain is intact", func() {
+		It("should verify every signature", func() {
+			result := verifyChain()
+
+			Expect(result.Valid).To(BeTrue())
+			Expect(result.VerifiedSignatures).To(Equal(3))
+			Expect(result.Issues).To(BeEmpty())
+		})
+	})
+
+	Context("When the chain has been tampered with", func() {
+		It("should report a bad signature and the broken link after it", func() {
+			signatures, err := server.SignatureRepository.GetAllSignaturesByDeviceID("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			for _, signature := range signatures {
+				if signature.SignatureCounter == 1 {
+					signature.SignatureValue = base64.StdEncoding.EncodeToString([]byte("forged"))
+				}
+			}
+
+			result := verifyChain()
+
+			Expect(result.Valid).To(BeFalse())
+			Expect(result.Issues).To(HaveLen(2))
+			Expect(result.Issues[0].SignatureCounter).To(Equal(1))
+			Expect(result.Issues[0].Reason).To(Equal(ChainIssueInvalidSignature))
+			Expect(result.Issues[1].SignatureCounter).To(Equal(2))
+			Expect(result.Issues[1].Reason).To(Equal(ChainIssueBrokenLink))
+		})
+
+		It("should report signed data that does not match the recorded data", func() {
+			signatures, err := server.SignatureRepository.GetAllSignaturesByDeviceID("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			for _, signature := range signatures {
+				if signature.SignatureCounter == 1 {
+					signature.Data = "altered"
+				}
+			}
+
+			result := verifyChain()
+
+			Expect(result.Valid).To(BeFalse())
+			Expect(result.Issues).To(HaveLen(1))
+			Expect(result.Issues[0].SignatureCounter).To(Equal(1))
+			Expect(result.Issues[0].Reason).To(Equal(ChainIssueBrokenLink))
+		})
+
+		It("should report a gap in the counters", func() {
+			device.SignatureCounter++
+			Expect(server.SignatureRepository.CreateSignature(&domain.Signature{
+				ID:               "skipped",
+				DeviceID:         "test-device",
+				SignatureCounter: 4,
+				SignatureValue:   "skipped",
+				SignedData:       "4_skipped_skipped",
+			})).To(Succeed())
+
+			result := verifyChain()
+
+			Expect(result.Valid).To(BeFalse())
+			Expect(result.Issues[0].Reason).To(Equal(ChainIssueCounterGap))
+		})
+	})
+
+	Context("When the device key was rotated", func() {
+		var oldPublicKey string
+
+		BeforeEach(func() {
+			oldPublicKey = device.PublicKey
+
+			req := httptest.NewRequest("POST", "/api/v0/devices/test-device/rotate-key", nil)
+			req.SetPathValue("id", "test-device")
+			w := httptest.NewRecorder()
+
+			server.RotateKey(w, req)
+
+			Expect(w.Code).To(Equal(http.StatusOK))
+			var response struct {
+				Data RotateKeyResponse `json:"data"`
+			}
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.Data.Device.SignatureCounter).To(Equal(4))
+			Expect(response.Data.Device.PublicKey).NotTo(Equal(oldPublicKey))
+			Expect(response.Data.Device.KeyHistory).To(HaveLen(1))
+			Expect(response.Data.Device.KeyHistory[0].PublicKey).To(Equal(oldPublicKey))
+			Expect(response.Data.Device.KeyHistory[0].FirstCounter).To(Equal(0))
+			Expect(response.Data.Device.KeyHistory[0].LastCounter).To(Equal(3))
+			Expect(response.Data.RotationSignature.SignedData).To(HavePrefix("3_key_rotation:"))
+
+			signTransaction("fourth")
+		})
+
+		It("should continue the chain with the new key", func() {
+			result := verifyChain()
+
+			Expect(result.Valid).To(BeTrue())
+			Expect(result.VerifiedSignatures).To(Equal(5))
+			Expect(result.Issues).To(BeEmpty())
+		})
+
+		It("should verify signatures made before the rotation with the old key", func() {
+			signatures, err := server.SignatureRepository.GetAllSignaturesByDeviceID("test-device")
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, signature := range signatures {
+				body, err := json.Marshal(VerifySignatureRequest{
+					DeviceID:   "test-device",
+					SignedData: signature.SignedData,
+					Signature:  signature.SignatureValue,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				req := httptest.NewRequest("POST", "/api/v0/verify", bytes.NewReader(body))
+				w := httptest.NewRecorder()
+
+				server.VerifySignature(w, req)
+
+				var response struct {
+					Data VerifySignatureResponse `json:"data"`
+				}
+				Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Data.Valid).To(BeTrue(), "signature %d", signature.SignatureCounter)
+			}
+		})
+
+		It("should report a current key that the rotation record does not bind", func() {
+			keyPair, err := (&crypto.ECCGenerator{}).Generate()
+			Expect(err).NotTo(HaveOccurred())
+			public, _, err := crypto.NewECCMarshaler().Encode(*keyPair)
+			Expect(err).NotTo(HaveOccurred())
+
+			rotatedDevice, err := server.DeviceRepository.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			rotatedDevice.PublicKey = string(public)
+
+			result := verifyChain()
+
+			Expect(result.Valid).To(BeFalse())
+			Expect(result.Issues[0].SignatureCounter).To(Equal(3))
+			Expect(result.Issues[0].Reason).To(Equal(ChainIssueKeyRotation))
+			Expect(result.Issues[1].SignatureCounter).To(Equal(4))
+			Expect(result.Issues[1].Reason).To(Equal(ChainIssueInvalidSignature))
+		})
+	})
+})
+
+var _ = Describe("Signature Verification", func() {
+	var (
+		server *Server
+		signer crypto.Signer
+	)
+
+	verifySignature := func(signedData string, signature string) VerifySignatureResponse {
+		body, err := json.Marshal(VerifySignatureRequest{
+			DeviceID:   "test-device",
+			SignedData: signedData,
+			Signature:  signature,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		req := httptest.NewRequest("POST", "/api/v0/verify", strings.NewReader(string(body)))
+		w := httptest.NewRecorder()
+
+		server.VerifySignature(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var result struct {
+			Data VerifySignatureResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &result)).To(Succeed())
+		return result.Data
+	}
+
+	BeforeEach(func() {
+		generator := crypto.RSAGenerator{}
+		keyPair, err := generator.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		marshaler := crypto.NewRSAMarshaler()
+		public, private, err := marshaler.Marshal(*keyPair)
+		Expect(err).NotTo(HaveOccurred())
+		signer = crypto.NewRSASigner(keyPair)
+
+		deviceRepository := persistence.NewDeviceRepository()
+		Expect(deviceRepository.CreateDevice(&domain.Device{
+			ID:         "test-device",
+			Algorithm:  "RSA",
+			PublicKey:  string(public),
+			KeyReference: string(private),
+		})).To(Succeed())
+		server = &Server{
+			DeviceRepository: deviceRepository,
+		}
+	})
+
+	It("should accept a signature created with the device key", func() {
+		signature, err := signer.Sign([]byte("0_test-data_dGVzdC1kZXZpY2U="))
+		Expect(err).NotTo(HaveOccurred())
+
+		result := verifySignature("0_test-data_dGVzdC1kZXZpY2U=", base64.StdEncoding.EncodeToString(signature))
+
+		Expect(result.Valid).To(BeTrue())
+		Expect(result.Reason).To(BeEmpty())
+	})
+
+	It("should reject a signature over different data", func() {
+		signature, err := signer.Sign([]byte("0_test-data_dGVzdC1kZXZpY2U="))
+		Expect(err).NotTo(HaveOccurred())
+
+		result := verifySignature("0_other-data_dGVzdC1kZXZpY2U=", base64.StdEncoding.EncodeToString(signature))
+
+		Expect(result.Valid).To(BeFalse())
+		Expect(result.Reason).NotTo(BeEmpty())
+	})
+
+	It("should answer 404 for an unknown device", func() {
+		body, err := json.Marshal(VerifySignatureRequest{
+			DeviceID:   "unknown-device",
+			SignedData: "0_test-data_dW5rbm93bi1kZXZpY2U=",
+			Signature:  "c2lnbmF0dXJl",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		req := httptest.NewRequest("POST", "/api/v0/verify", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+
+		server.VerifySignature(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+	})
+})
+
+var _ = Describe("Device Lifecycle", func() {
+	var (
+		deviceID string
+		server *Server
+	)
+
+	patchDevice := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/v0/devices/"+deviceID, strings.NewReader(body))
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+
+		server.Device(w, req)
+		return w
+	}
+
+	getDevice := func() DeviceResponse {
+		req := httptest.NewRequest("GET", "/api/v0/devices/"+deviceID, nil)
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+
+		server.Device(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var result struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &result)).To(Succeed())
+		return result.Data
+	}
+
+	signTransaction := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "`+deviceID+`", "data": "receipt"}`))
+		w := httptest.NewRecorder()
+
+		server.SignTransaction(w, req)
+		return w
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+		}
+
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "Ed25519", "label": "till-1", "metadata": {"store": "berlin-01"}}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		deviceID = created.Data.ID
+	})
+
+	It("should return a single device", func() {
+		device := getDevice()
+
+		Expect(device.ID).To(Equal(deviceID))
+		Expect(device.Label).To(Equal("till-1"))
+		Expect(device.Status).To(Equal("active"))
+		Expect(device.Metadata).To(Equal(map[string]string{"store": "berlin-01"}))
+	})
+
+	It("should update the label and merge the metadata", func() {
+		w := patchDevice(`{"label": "till-2", "metadata": {"store": null, "floor": "2"}}`)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		device := getDevice()
+		Expect(device.Label).To(Equal("till-2"))
+		Expect(device.Metadata).To(Equal(map[string]string{"floor": "2"}))
+	})
+
+	It("should refuse to sign while the device is suspended", func() {
+		Expect(patchDevice(`{"status": "suspended"}`).Code).To(Equal(http.StatusOK))
+
+		w := signTransaction()
+		Expect(w.Code).To(Equal(http.StatusConflict))
+		Expect(w.Body.String()).To(ContainSubstring("is suspended"))
+
+		Expect(patchDevice(`{"status": "active"}`).Code).To(Equal(http.StatusOK))
+		Expect(signTransaction().Code).To(Equal(http.StatusOK))
+	})
+
+	It("should keep the chain of a decommissioned device readable but frozen", func() {
+		Expect(signTransaction().Code).To(Equal(http.StatusOK))
+		Expect(patchDevice(`{"status": "decommissioned"}`).Code).To(Equal(http.StatusOK))
+
+		Expect(signTransaction().Code).To(Equal(http.StatusConflict))
+		Expect(patchDevice(`{"status": "active"}`).Code).To(Equal(http.StatusConflict))
+		Expect(patchDevice(`{"label": "renamed"}`).Code).To(Equal(http.StatusConflict))
+		Expect(getDevice().Status).To(Equal("decommissioned"))
+
+		req := httptest.NewRequest("GET", "/api/v0/devices/"+deviceID+"/verify-chain", nil)
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+		server.VerifyChain(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var result struct {
+			Data ChainVerificationResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &result)).To(Succeed())
+		Expect(result.Data.Valid).To(BeTrue())
+		Expect(result.Data.VerifiedSignatures).To(Equal(1))
+	})
+
+	It("should leave inactive devices out of the JWK Set", func() {
+		Expect(patchDevice(`{"status": "suspended"}`).Code).To(Equal(http.StatusOK))
+
+		req := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+		w := httptest.NewRecorder()
+		server.ShowJWKS(w, req)
+
+		var jwks JWKSResponse
+		Expect(json.Unmarshal(w.Body.Bytes(), &jwks)).To(Succeed())
+		Expect(jwks.Keys).To(BeEmpty())
+	})
+
+	It("should reject an unknown status", func() {
+		Expect(patchDevice(`{"status": "lost"}`).Code).To(Equal(http.StatusUnprocessableEntity))
+	})
+
+	It("should answer 404 for an unknown device", func() {
+		deviceID = "unknown"
+
+		req := httptest.NewRequest("GET", "/api/v0/devices/"+deviceID, nil)
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+		server.Device(w, req)
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+
+		Expect(patchDevice(`{"label": "renamed"}`).Code).To(Equal(http.StatusNotFound))
+		Expect(signTransaction().Code).To(Equal(http.StatusNotFound))
+	})
+})
+
+var _ = Describe("Idempotent Signing", func() {
+	var (
+		deviceID string
+		server *Server
+	)
+
+	sign := func(deviceID string, key string, data string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "`+deviceID+`", "data": "`+data+`"}`))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		w := httptest.NewRecorder()
+
+		server.SignTransaction(w, req)
+		return w
+	}
+
+	signatureCounter := func(deviceID string) int {
+		device, err := server.DeviceRepository.GetDevice(deviceID)
+		Expect(err).NotTo(HaveOccurred())
+		return device.SignatureCounter
+	}
+
+	createDevice := func() string {
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "Ed25519"}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		return created.Data.ID
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+		}
+		deviceID = createDevice()
+	})
+
+	It("should answer a retry with the original signature", func() {
+		first := sign(deviceID, "receipt-1", "receipt")
+		Expect(first.Code).To(Equal(http.StatusOK))
+		Expect(first.Header().Get(IdempotentReplayedHeader)).To(BeEmpty())
+
+		retry := sign(deviceID, "receipt-1", "receipt")
+		Expect(retry.Code).To(Equal(http.StatusOK))
+		Expect(retry.Header().Get(IdempotentReplayedHeader)).To(Equal("true"))
+		Expect(retry.Body.String()).To(Equal(first.Body.String()))
+		Expect(signatureCounter(deviceID)).To(Equal(1))
+	})
+
+	It("should reject the key for a different transaction", func() {
+		Expect(sign(deviceID, "receipt-1", "receipt").Code).To(Equal(http.StatusOK))
+
+		w := sign(deviceID, "receipt-1", "other-receipt")
+
+		Expect(w.Code).To(Equal(http.StatusConflict))
+		Expect(signatureCounter(deviceID)).To(Equal(1))
+	})
+
+	It("should scope keys to a device", func() {
+		otherDeviceID := createDevice()
+		Expect(sign(deviceID, "receipt-1", "receipt").Code).To(Equal(http.StatusOK))
+
+		w := sign(otherDeviceID, "receipt-1", "receipt")
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get(IdempotentReplayedHeader)).To(BeEmpty())
+		Expect(signatureCounter(otherDeviceID)).To(Equal(1))
+	})
+
+	It("should sign again once the key expired", func() {
+		server.IdempotencyRetention = time.Nanosecond
+		Expect(sign(deviceID, "receipt-1", "receipt").Code).To(Equal(http.StatusOK))
+
+		w := sign(deviceID, "receipt-1", "other-receipt")
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get(IdempotentReplayedHeader)).To(BeEmpty())
+		Expect(signatureCounter(deviceID)).To(Equal(2))
+	})
+
+	It("should sign every request without a key", func() {
+		Expect(sign(deviceID, "", "receipt").Code).To(Equal(http.StatusOK))
+		Expect(sign(deviceID, "", "receipt").Code).To(Equal(http.StatusOK))
+
+		Expect(signatureCounter(deviceID)).To(Equal(2))
+	})
+
+	It("should reject overlong keys", func() {
+		w := sign(deviceID, strings.Repeat("k", 256), "receipt")
+
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+})
+
+var _ = Describe("Listings", func() {
+	var server *Server
+
+	list := func(handler http.HandlerFunc, target string) (PageResponse, int) {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+
+		handler(w, req)
+
+		var page PageResponse
+		if w.Code == http.StatusOK {
+			Expect(json.Unmarshal(w.Body.Bytes(), &page)).To(Succeed())
+		}
+		return page, w.Code
+	}
+
+	createDevice := func(body string) string {
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		return created.Data.ID
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+		}
+	})
+
+	It("should page through the signatures of a device in counter order", func() {
+		deviceID := createDevice(`{"algorithm": "Ed25519"}`)
+		for i := 0; i < 5; i++ {
+			req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "`+deviceID+`", "data": "receipt"}`))
+			w := httptest.NewRecorder()
+			server.SignTransaction(w, req)
+			Expect(w.Code).To(Equal(http.StatusOK))
+		}
+
+		var counters []float64
+		target := "/api/v0/signatures?device_id=" + deviceID + "&limit=2"
+		for pages := 1; ; pages++ {
+			page, code := list(server.ShowAllSignaturesByDevice, target)
+			Expect(code).To(Equal(http.StatusOK))
+			for _, signature := range page.Data.([]interface{}) {
+				counters = append(counters, signature.(map[string]interface{})["signature_counter"].(float64))
+			}
+			if page.NextCursor == "" {
+				Expect(pages).To(Equal(3))
+				break
+			}
+			target = "/api/v0/signatures?device_id=" + deviceID + "&limit=2&cursor=" + page.NextCursor
+		}
+		Expect(counters).To(Equal([]float64{0, 1, 2, 3, 4}))
+
+		page, code := list(server.ShowAllSignaturesByDevice, "/api/v0/signatures?device_id="+deviceID+"&from_counter=1&to_counter=3&order=desc")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(page.Data).To(HaveLen(3))
+		Expect(page.Data.([]interface{})[0].(map[string]interface{})["signature_counter"]).To(BeEquivalentTo(3))
+	})
+
+	It("should return the signed payload and its context with each signature", func() {
+		deviceID := createDevice(`{"algorithm": "ECC"}`)
+		signed := make([]SignatureResponse, 0, 2)
+		for _, data := range []string{"first-receipt", "second-receipt"} {
+			req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "`+deviceID+`", "data": "`+data+`"}`))
+			w := httptest.NewRecorder()
+			server.SignTransaction(w, req)
+			Expect(w.Code).To(Equal(http.StatusOK))
+
+			var response struct {
+				Data SignatureResponse `json:"data"`
+			}
+			Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+			signed = append(signed, response.Data)
+		}
+
+		req := httptest.NewRequest("GET", "/api/v0/signatures?device_id="+deviceID, nil)
+		w := httptest.NewRecorder()
+		server.ShowAllSignaturesByDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var page struct {
+			Data []GetSignatureResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &page)).To(Succeed())
+		Expect(page.Data).To(HaveLen(2))
+
+		// The first signature is chained to the genesis reference and has no predecessor
+		genesis := page.Data[0]
+		Expect(genesis.ID).NotTo(BeEmpty())
+		Expect(genesis.DeviceID).To(Equal(deviceID))
+		Expect(genesis.SignatureCounter).To(Equal(0))
+		Expect(genesis.SignatureValue).To(Equal(signed[0].Signature))
+		Expect(genesis.Data).To(Equal("first-receipt"))
+		Expect(genesis.SignedData).To(Equal("0_first-receipt_" + base64.StdEncoding.EncodeToString([]byte(deviceID))))
+		Expect(genesis.SignedData).To(Equal(signed[0].SignedData))
+		Expect(genesis.Algorithm).To(Equal("ECC"))
+		Expect(genesis.PreviousSignatureID).To(BeEmpty())
+		Expect(genesis.CreatedAt).NotTo(BeZero())
+
+		second := page.Data[1]
+		Expect(second.SignatureCounter).To(Equal(1))
+		Expect(second.Data).To(Equal("second-receipt"))
+		Expect(second.SignedData).To(Equal("1_second-receipt_" + genesis.SignatureValue))
+		Expect(second.PreviousSignatureID).To(Equal(genesis.ID))
+		Expect(second.CreatedAt).NotTo(BeTemporally("<", genesis.CreatedAt))
+	})
+
+	It("should filter devices by algorithm, label prefix and status", func() {
+		createDevice(`{"algorithm": "Ed25519", "label": "till-1"}`)
+		createDevice(`{"algorithm": "Ed25519", "label": "kiosk-1"}`)
+		createDevice(`{"algorithm": "ECC", "label": "till-2"}`)
+
+		page, code := list(server.ShowAllDevices, "/api/v0/devices?algorithm=Ed25519&label_prefix=till-&status=active")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(page.Data).To(HaveLen(1))
+		Expect(page.Data.([]interface{})[0].(map[string]interface{})["label"]).To(Equal("till-1"))
+		Expect(page.NextCursor).To(BeEmpty())
+	})
+
+	It("should list every device when neither limit nor cursor is given", func() {
+		for i := 0; i <= DefaultPageLimit; i++ {
+			Expect(server.DeviceRepository.CreateDevice(&domain.Device{ID: fmt.Sprintf("device-%03d", i), Algorithm: "ECC"})).To(Succeed())
+		}
+
+		page, code := list(server.ShowAllDevices, "/api/v0/devices")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(page.Data).To(HaveLen(DefaultPageLimit + 1))
+		Expect(page.NextCursor).To(BeEmpty())
+
+		page, code = list(server.ShowAllDevices, "/api/v0/devices?limit=")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(page.Data).To(HaveLen(DefaultPageLimit))
+		Expect(page.NextCursor).NotTo(BeEmpty())
+	})
+
+	It("should not find the signatures of an unknown device", func() {
+		_, code := list(server.ShowAllSignaturesByDevice, "/api/v0/signatures?device_id=unknown-device")
+		Expect(code).To(Equal(http.StatusNotFound))
+	})
+
+	DescribeTable("should reject invalid paging parameters",
+		func(target string) {
+			_, code := list(server.ShowAllSignaturesByDevice, target)
+			Expect(code).To(Equal(http.StatusBadRequest))
+		},
+		Entry("limit of zero", "/api/v0/signatures?device_id=test-device&limit=0"),
+		Entry("limit above the maximum", "/api/v0/signatures?device_id=test-device&limit=5000"),
+		Entry("unknown order", "/api/v0/signatures?device_id=test-device&order=random"),
+		Entry("malformed cursor", "/api/v0/signatures?device_id=test-device&cursor=bm90LWpzb24"),
+		Entry("negative counter", "/api/v0/signatures?device_id=test-device&from_counter=-1"),
+		Entry("malformed time", "/api/v0/signatures?device_id=test-device&from=yesterday"),
+	)
+})
+
+var _ = Describe("Public Key Export", func() {
+	const legacyPublicKey = `-----BEGIN RSA_PUBLIC_KEY-----
+MIGJAoGBAM/tvE/dja6Y8T8TbYSZHpve3ytzv1yiDwhVlF7avZRdiFRU7srNkaRR
+8r746jm/VYYA5rLftyhteEHzZHZgXKHjS+ehavTAtFe4BEcUsk7PudebgD+cFC4E
+F9Sa+aRvyTn0Rg3NFtf9s+MiixfdkDfybuqQ8lN+SqK7uOMqpnFJAgMBAAE=
+-----END RSA_PUBLIC_KEY-----`
+
+	var (
+		server *Server
+		eccKeyPair *crypto.ECCKeyPair
+	)
+
+	exportPublicKey := func(deviceID string, format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/v0/devices/"+deviceID+"/public-key?format="+format, nil)
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+
+		server.ShowPublicKey(w, req)
+		return w
+	}
+
+	BeforeEach(func() {
+		var err error
+		generator := crypto.ECCGenerator{Curve: elliptic.P256()}
+		eccKeyPair, err = generator.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		public, private, err := crypto.NewECCMarshaler().Encode(*eccKeyPair)
+		Expect(err).NotTo(HaveOccurred())
+
+		deviceRepository := persistence.NewDeviceRepository()
+		Expect(deviceRepository.CreateDevice(&domain.Device{
+			ID:            "ecc-device",
+			Algorithm:     "ECC",
+			PublicKey:     string(public),
+			KeyReference:  string(private),
+			KeyParameters: domain.KeyParameters{Curve: "P-256", Hash: "SHA-256"},
+		})).To(Succeed())
+		Expect(deviceRepository.CreateDevice(&domain.Device{
+			ID:        "legacy-device",
+			Algorithm: "RSA",
+			PublicKey: legacyPublicKey,
+		})).To(Succeed())
+		server = &Server{
+			DeviceRepository: deviceRepository,
+		}
+	})
+
+	It("should encode new keys with the standard PEM labels", func() {
+		generator := crypto.RSAGenerator{}
+		rsaKeyPair, err := generator.Generate()
+		Expect(err).NotTo(HaveOccurred())
+		marshaler := crypto.NewRSAMarshaler()
+		public, private, err := marshaler.Marshal(*rsaKeyPair)
+		Expect(err).NotTo(HaveOccurred())
+
+		publicBlock, _ := pem.Decode(public)
+		Expect(publicBlock.Type).To(Equal("PUBLIC KEY"))
+		privateBlock, _ := pem.Decode(private)
+		Expect(privateBlock.Type).To(Equal("RSA PRIVATE KEY"))
+		_, err = x509.ParsePKIXPublicKey(publicBlock.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should export a standard SPKI PEM by default", func() {
+		w := exportPublicKey("ecc-device", "")
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/x-pem-file"))
+		block, _ := pem.Decode(w.Body.Bytes())
+		Expect(block.Type).To(Equal("PUBLIC KEY"))
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publicKey.(*ecdsa.PublicKey).Equal(eccKeyPair.Public)).To(BeTrue())
+	})
+
+	It("should convert keys stored with legacy PEM labels", func() {
+		w := exportPublicKey("legacy-device", "pem")
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		block, _ := pem.Decode(w.Body.Bytes())
+		Expect(block.Type).To(Equal("PUBLIC KEY"))
+		publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publicKey).To(BeAssignableToTypeOf(&rsa.PublicKey{}))
+	})
+
+	It("should export DER", func() {
+		w := exportPublicKey("ecc-device", "der")
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		publicKey, err := x509.ParsePKIXPublicKey(w.Body.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(publicKey.(*ecdsa.PublicKey).Equal(eccKeyPair.Public)).To(BeTrue())
+	})
+
+	It("should export a JWK that verifies the signatures of the device", func() {
+		w := exportPublicKey("ecc-device", "jwk")
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/jwk+json"))
+		var jwk crypto.JWK
+		Expect(json.Unmarshal(w.Body.Bytes(), &jwk)).To(Succeed())
+		Expect(jwk.Kty).To(Equal("EC"))
+		Expect(jwk.Crv).To(Equal("P-256"))
+		Expect(jwk.Kid).To(Equal("ecc-device"))
+		Expect(jwk.D).To(BeEmpty())
+
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		Expect(err).NotTo(HaveOccurred())
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(x).To(HaveLen(32))
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+		device, err := server.DeviceRepository.GetDevice("ecc-device")
+		Expect(err).NotTo(HaveOccurred())
+		signature, err := signDetached(server, device, "test-data")
+		Expect(err).NotTo(HaveOccurred())
+		signatureValue, err := base64.StdEncoding.DecodeString(signature.SignatureValue)
+		Expect(err).NotTo(HaveOccurred())
+		digest := sha256.Sum256([]byte(signature.SignedData))
+		Expect(ecdsa.VerifyASN1(publicKey, digest[:], signatureValue)).To(BeTrue())
+	})
+
+	It("should reject an unknown format", func() {
+		w := exportPublicKey("ecc-device", "xml")
+
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should publish the keys of all devices as a JWK Set", func() {
+		req := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+		w := httptest.NewRecorder()
+
+		server.ShowJWKS(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var jwks JWKSResponse
+		Expect(json.Unmarshal(w.Body.Bytes(), &jwks)).To(Succeed())
+		Expect(jwks.Keys).To(ConsistOf(
+			And(HaveField("Kid", "ecc-device"), HaveField("Kty", "EC")),
+			And(HaveField("Kid", "legacy-device"), HaveField("Kty", "RSA"), HaveField("E", "AQAB")),
+		))
+	})
+})
+
+var _ = Describe("Certificate Authority", func() {
+	var (
+		caDir string
+		deviceID string
+		sealer *crypto.KeySealer
+		server *Server
+	)
+
+	getCertificate := func(handler http.HandlerFunc, target string) *x509.Certificate {
+		req := httptest.NewRequest("GET", target, nil)
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+
+		handler(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		block, _ := pem.Decode(w.Body.Bytes())
+		Expect(block).NotTo(BeNil())
+		Expect(block.Type).To(Equal("CERTIFICATE"))
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		return certificate
+	}
+
+	// revokedSerialNumbers fetches the revocation list and checks it is signed by the root.
+	revokedSerialNumbers := func() []string {
+		req := httptest.NewRequest("GET", "/api/v0/ca/crl?format=der", nil)
+		w := httptest.NewRecorder()
+		server.ShowCRL(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Header().Get("Content-Type")).To(Equal("application/pkix-crl"))
+
+		crl, err := x509.ParseRevocationList(w.Body.Bytes())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(crl.CheckSignatureFrom(server.CertificateAuthority.Certificate())).To(Succeed())
+
+		serialNumbers := []string{}
+		for _, entry := range crl.RevokedCertificateEntries {
+			serialNumbers = append(serialNumbers, entry.SerialNumber.String())
+		}
+		return serialNumbers
+	}
+
+	BeforeEach(func() {
+		masterKey, err := crypto.NewMasterKey(bytes.Repeat([]byte{7}, 32))
+		Expect(err).NotTo(HaveOccurred())
+		sealer = crypto.NewKeySealer(masterKey)
+
+		caDir = GinkgoT().TempDir()
+		authority, err := ca.LoadOrCreate(caDir, sealer)
+		Expect(err).NotTo(HaveOccurred())
+
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+			CertificateAuthority: authority,
+		}
+
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "Ed25519", "label": "till-1"}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		deviceID = created.Data.ID
+	})
+
+	It("should issue the certificate along with the device key and serve the same one on every request", func() {
+		issued, err := os.ReadDir(filepath.Join(caDir, "issued"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issued).To(HaveLen(1))
+
+		certificate := getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate")
+		Expect(getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate").Raw).To(Equal(certificate.Raw))
+
+		stored, err := server.CertificateAuthority.IssuedCertificate(certificate.SerialNumber)
+		Expect(err).NotTo(HaveOccurred())
+		block, _ := pem.Decode(stored)
+		Expect(block).NotTo(BeNil())
+		Expect(block.Bytes).To(Equal(certificate.Raw))
+
+		issued, err = os.ReadDir(filepath.Join(caDir, "issued"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issued).To(HaveLen(1))
+	})
+
+	It("should certify the new key when the key is rotated", func() {
+		previous := getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate")
+
+		req := httptest.NewRequest("POST", "/api/v0/devices/"+deviceID+"/rotate-key", nil)
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+		server.RotateKey(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		certificate := getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate")
+		Expect(certificate.SerialNumber).NotTo(Equal(previous.SerialNumber))
+		device, err := server.DeviceRepository.GetDevice(deviceID)
+		Expect(err).NotTo(HaveOccurred())
+		publicKey, err := crypto.ParsePublicKey([]byte(device.PublicKey))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate.PublicKey).To(Equal(publicKey))
+
+		Expect(revokedSerialNumbers()).To(ConsistOf(previous.SerialNumber.String()))
+	})
+
+	It("should revoke the certificate of a suspended device and certify it again once activated", func() {
+		setStatus := func(status string) {
+			req := httptest.NewRequest("PATCH", "/api/v0/devices/"+deviceID, strings.NewReader(`{"status": "`+status+`"}`))
+			req.SetPathValue("id", deviceID)
+			w := httptest.NewRecorder()
+			server.UpdateDevice(w, req)
+			Expect(w.Code).To(Equal(http.StatusOK))
+		}
+		suspended := getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate")
+
+		setStatus("suspended")
+		Expect(revokedSerialNumbers()).To(ConsistOf(suspended.SerialNumber.String()))
+		req := httptest.NewRequest("GET", "/api/v0/devices/"+deviceID+"/certificate", nil)
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+		server.ShowDeviceCertificate(w, req)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+
+		setStatus("active")
+		certificate := getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate")
+		Expect(certificate.SerialNumber).NotTo(Equal(suspended.SerialNumber))
+		Expect(revokedSerialNumbers()).To(ConsistOf(suspended.SerialNumber.String()))
+	})
+
+	It("should certify the keys of devices created without a certificate authority once", func() {
+		authority := server.CertificateAuthority
+		server.CertificateAuthority = nil
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "ECC"}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		deviceID = created.Data.ID
+
+		server.CertificateAuthority = authority
+		certificate := getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate")
+		Expect(getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate").Raw).To(Equal(certificate.Raw))
+		Expect(certificate.Subject.CommonName).To(Equal(deviceID))
+	})
+
+	It("should serve the root certificate", func() {
+		root := getCertificate(server.ShowCACertificate, "/api/v0/ca/certificate")
+
+		Expect(root.IsCA).To(BeTrue())
+		Expect(root.CheckSignatureFrom(root)).To(Succeed())
+	})
+
+	It("should issue a device certificate that chains to the root and verifies device signatures", func() {
+		root := getCertificate(server.ShowCACertificate, "/api/v0/ca/certificate")
+		certificate := getCertificate(server.ShowDeviceCertificate, "/api/v0/devices/"+deviceID+"/certificate")
+
+		roots := x509.NewCertPool()
+		roots.AddCert(root)
+		_, err := certificate.Verify(x509.VerifyOptions{
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(certificate.Subject.CommonName).To(Equal(deviceID))
+		Expect(certificate.Subject.Names).To(ContainElement(HaveField("Value", "till-1")))
+		Expect(certificate.URIs).To(HaveLen(1))
+		Expect(certificate.URIs[0].String()).To(Equal("urn:uuid:" + deviceID))
+		Expect(certificate.Extensions).To(ContainElement(HaveField("Id", Equal(asn1.ObjectIdentifier{2, 5, 29, 9}))))
+
+		device, err := server.DeviceRepository.GetDevice(deviceID)
+		Expect(err).NotTo(HaveOccurred())
+		signature, err := signDetached(server, device, "test-data")
+		Expect(err).NotTo(HaveOccurred())
+		signatureValue, err := base64.StdEncoding.DecodeString(signature.SignatureValue)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate.CheckSignature(x509.PureEd25519, []byte(signature.SignedData), signatureValue)).To(Succeed())
+	})
+
+	It("should report a missing certificate authority", func() {
+		server.CertificateAuthority = nil
+		req := httptest.NewRequest("GET", "/api/v0/ca/certificate", nil)
+		w := httptest.NewRecorder()
+
+		server.ShowCACertificate(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+})
+
+// testAlgorithm is registered by the tests to show that the API picks up new algorithms.
+type testAlgorithm struct {
+	crypto.Ed25519Algorithm
+}
+
+func (testAlgorithm) Name() string {
+	return "Test"
+}
+
+var _ = Describe("Algorithm Registry", func() {
+	var (
+		ctrl *gomock.Controller
+		mockDeviceRepository *mock_persistence.MockIDeviceRepository
+		server *Server
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mockDeviceRepository = mock_persistence.NewMockIDeviceRepository(ctrl)
+		server = &Server{
+			DeviceRepository: mockDeviceRepository,
+			UnitOfWork: persistence.NewUnitOfWork(mockDeviceRepository, nil, nil, nil),
+		}
+	})
+
+	It("should list the registered algorithms", func() {
+		req := httptest.NewRequest("GET", "/api/v0/algorithms", nil)
+		w := httptest.NewRecorder()
+
+		server.ShowAllAlgorithms(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var result struct {
+			Data []AlgorithmResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &result)).To(Succeed())
+		Expect(result.Data).To(ContainElements(
+			AlgorithmResponse{Name: "ECC"},
+			AlgorithmResponse{Name: "Ed25519"},
+			AlgorithmResponse{Name: "RSA"},
+		))
+	})
+
+	It("should create devices for a newly registered algorithm", func() {
+		if _, err := crypto.LookupAlgorithm("Test"); err != nil {
+			crypto.Register(testAlgorithm{})
+		}
+		mockDeviceRepository.EXPECT().CreateDevice(gomock.Any()).Return(nil)
+
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "Test", "label": "test-device"}`))
+		w := httptest.NewRecorder()
+
+		server.CreateSignatureDevice(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(w.Body.String()).To(ContainSubstring(`"algorithm": "Test"`))
+	})
+})
+
+var _ = Describe("Private Key Encryption", func() {
+	var (
+		server *Server
+		oldMasterKey *crypto.MasterKey
+		newMasterKey *crypto.MasterKey
+	)
+
+	newMasterKeyFor := func(seed byte) *crypto.MasterKey {
+		masterKey, err := crypto.NewMasterKey(bytes.Repeat([]byte{seed}, 32))
+		Expect(err).NotTo(HaveOccurred())
+		return masterKey
+	}
+
+	createDevice := func() *domain.Device {
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "ECC", "label": "test-device"}`))
+		w := httptest.NewRecorder()
+
+		server.CreateSignatureDevice(w, req)
+
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		devices, err := server.DeviceRepository.GetAllDevices()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(devices).To(HaveLen(1))
+		return devices[0]
+	}
+
+	BeforeEach(func() {
+		oldMasterKey = newMasterKeyFor(1)
+		newMasterKey = newMasterKeyFor(2)
+
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+			KeyProvider: crypto.NewLocalKeyProvider(crypto.NewKeySealer(oldMasterKey)),
+		}
+	})
+
+	It("should re-wrap every device key when the master key is rotated", func() {
+		device := createDevice()
+
+		server.KeyProvider = crypto.NewLocalKeyProvider(crypto.NewKeySealer(newMasterKey, oldMasterKey))
+		rewrapped, err := server.RewrapDeviceKeys()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rewrapped).To(Equal(1))
+
+		// The old master key is no longer needed
+		device, err = server.DeviceRepository.GetDevice(device.ID)
+		Expect(err).NotTo(HaveOccurred())
+		server.KeyProvider = crypto.NewLocalKeyProvider(crypto.NewKeySealer(newMasterKey))
+		_, err = signDetached(server, device, "test-data")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should seal the keys of devices created before a master key was set", func() {
+		server.KeyProvider = crypto.NewLocalKeyProvider(nil)
+		device := createDevice()
+
+		server.KeyProvider = crypto.NewLocalKeyProvider(crypto.NewKeySealer(newMasterKey))
+		_, err := signDetached(server, device, "test-data")
+		Expect(err).To(MatchError(crypto.ErrKeyNotSealed))
+
+		Expect(server.RewrapDeviceKeys()).To(Equal(1))
+		device, err = server.DeviceRepository.GetDevice(device.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(crypto.IsSealed([]byte(device.KeyReference))).To(BeTrue())
+		_, err = signDetached(server, device, "test-data")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should refuse to keep private keys unencrypted in a persistent storage", func() {
+		_, err := newKeyProvider(Config{Storage: StorageFile})
+		Expect(err).To(MatchError(ContainSubstring("file storage would keep device private keys unencrypted")))
+
+		_, err = newKeyProvider(Config{Storage: StorageFile, AllowPlaintextKeys: true})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = newKeyProvider(Config{Storage: StorageMemory})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should not leave the key wrapped with the old master key in the journal", func() {
+		dir := GinkgoT().TempDir()
+		store, err := persistence.NewFileStore(dir)
+		Expect(err).NotTo(HaveOccurred())
+		defer store.Close()
+		server.DeviceRepository = store
+		server.SignatureRepository = store
+		server.UnitOfWork = store
+
+		device := createDevice()
+		oldBlock, _ := pem.Decode([]byte(device.KeyReference))
+
+		server.KeyProvider = crypto.NewLocalKeyProvider(crypto.NewKeySealer(newMasterKey, oldMasterKey))
+		Expect(server.RewrapDeviceKeys()).To(Equal(1))
+
+		journal, err := os.ReadFile(filepath.Join(dir, "journal.log"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(journal)).NotTo(ContainSubstring(oldBlock.Headers["Data-Key"]))
+		Expect(string(journal)).NotTo(ContainSubstring(oldMasterKey.ID))
+	})
+})
+
+// signDetached signs data as the first signature of device with the keys of server, without
+// storing anything in the repositories of server.
+func signDetached(server *Server, device *domain.Device, data string) (*domain.Signature, error) {
+	deviceRepository := persistence.NewDeviceRepository()
+	signatureRepository := persistence.NewSignatureRepository()
+	detached := *device
+	detached.SignatureCounter = 0
+	if err := deviceRepository.CreateDevice(&detached); err != nil {
+		return nil, err
+	}
+
+	signing := &service.SigningService{
+		DeviceRepository: deviceRepository,
+		SignatureRepository: signatureRepository,
+		UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+		KeyProvider: server.KeyProvider,
+	}
+	signature, _, err := signing.Sign(device.ID, data, "")
+	return signature, err
+}
+
+var _ = Describe("Batch Signing", func() {
+	var (
+		deviceID string
+		server *Server
+	)
+
+	signBatch := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v0/devices/"+deviceID+"/sign-batch", strings.NewReader(body))
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+
+		server.SignBatch(w, req)
+		return w
+	}
+
+	signatureCounter := func() int {
+		device, err := server.DeviceRepository.GetDevice(deviceID)
+		Expect(err).NotTo(HaveOccurred())
+		return device.SignatureCounter
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+		}
+
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "ECC"}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		deviceID = created.Data.ID
+	})
+
+	It("should sign the items in order and continue the chain", func() {
+		req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "`+deviceID+`", "data": "single"}`))
+		w := httptest.NewRecorder()
+		server.SignTransaction(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w = signBatch(`{"data": ["first", "second", "third"]}`)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var response struct {
+			Data SignBatchResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Data.Signatures).To(HaveLen(3))
+		Expect(response.Data.Signatures[0].SignedData).To(HavePrefix("1_first_"))
+		Expect(response.Data.Signatures[1].SignedData).To(Equal("2_second_" + response.Data.Signatures[0].Signature))
+		Expect(response.Data.Signatures[2].SignedData).To(Equal("3_third_" + response.Data.Signatures[1].Signature))
+		Expect(signatureCounter()).To(Equal(4))
+
+		req = httptest.NewRequest("GET", "/api/v0/devices/"+deviceID+"/verify-chain", nil)
+		req.SetPathValue("id", deviceID)
+		w = httptest.NewRecorder()
+		server.VerifyChain(w, req)
+
+		var verification struct {
+			Data ChainVerificationResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &verification)).To(Succeed())
+		Expect(verification.Data.Valid).To(BeTrue())
+		Expect(verification.Data.VerifiedSignatures).To(Equal(4))
+	})
+
+	It("should start the chain at the genesis of a new device", func() {
+		w := signBatch(`{"data": ["first"]}`)
+
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(w.Body.String()).To(ContainSubstring("0_first_" + service.ChainGenesis(deviceID)))
+		Expect(signatureCounter()).To(Equal(1))
+	})
+
+	DescribeTable("should reject invalid batches without signing anything",
+		func(body string, message string) {
+			w := signBatch(body)
+
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(w.Body.String()).To(ContainSubstring(message))
+			Expect(signatureCounter()).To(Equal(0))
+		},
+		Entry("missing data", `{}`, "Data is required"),
+		Entry("empty batch", `{"data": []}`, "Data must contain at least 1 items"),
+		Entry("empty item", `{"data": ["first", ""]}`, "Data[1] is required"),
+		Entry("oversized batch", `{"data": [`+strings.Repeat(`"item",`, DefaultMaxBatchItems)+`"item"]}`, "Data must contain at most 10000 items"),
+	)
+
+	It("should accept thousands of items and stop at the configured maximum", func() {
+		w := signBatch(`{"data": [` + strings.Repeat(`"item",`, 4999) + `"item"]}`)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		Expect(signatureCounter()).To(Equal(5000))
+
+		server.MaxBatchItems = 2
+		w = signBatch(`{"data": ["first", "second", "third"]}`)
+		Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(w.Body.String()).To(ContainSubstring("Data must contain at most 2 items"))
+		Expect(signatureCounter()).To(Equal(5000))
+	})
+
+	It("should not sign for a suspended device", func() {
+		device, err := server.DeviceRepository.GetDevice(deviceID)
+		Expect(err).NotTo(HaveOccurred())
+		suspended := *device
+		suspended.Status = domain.DeviceStatusSuspended
+		Expect(server.DeviceRepository.UpdateDevice(&suspended)).To(Succeed())
+
+		w := signBatch(`{"data": ["first"]}`)
+
+		Expect(w.Code).To(Equal(http.StatusConflict))
+		Expect(signatureCounter()).To(Equal(0))
+	})
+})
+
+var _ = Describe("Signing Jobs", func() {
+	var (
+		deviceID string
+		server *Server
+	)
+
+	submit := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v0/jobs", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		server.SubmitJob(w, req)
+		return w
+	}
+
+	submitted := func(w *httptest.ResponseRecorder) JobResponse {
+		Expect(w.Code).To(Equal(http.StatusAccepted))
+		var response struct {
+			Data JobResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		Expect(w.Header().Get("Location")).To(Equal("/api/v0/jobs/" + response.Data.ID))
+		return response.Data
+	}
+
+	showJob := func(jobID string) JobResponse {
+		req := httptest.NewRequest("GET", "/api/v0/jobs/"+jobID, nil)
+		req.SetPathValue("id", jobID)
+		w := httptest.NewRecorder()
+
+		server.ShowJob(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		var response struct {
+			Data JobResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		return response.Data
+	}
+
+	jobStatus := func(jobID string) func() domain.JobStatus {
+		return func() domain.JobStatus {
+			return showJob(jobID).Status
+		}
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		jobRepository := persistence.NewJobRepository()
+		webhookRepository := persistence.NewWebhookRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, jobRepository, webhookRepository),
+			JobRepository: jobRepository,
+			WebhookRepository: webhookRepository,
+			AllowPrivateEndpoints: true, // The callbacks go to httptest servers on the loopback interface
+		}
+		Expect(server.StartJobWorkers(2, 10)).To(Succeed())
+		server.StartWebhookDispatcher(3, 10*time.Millisecond)
+		DeferCleanup(func() { server.Stop() })
+
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "ECC"}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		deviceID = created.Data.ID
+	})
+
+	It("should sign the items in the background", func() {
+		job := submitted(submit(`{"device_id": "` + deviceID + `", "data": ["first", "second"]}`))
+		Expect(job.Items).To(Equal(2))
+
+		Eventually(jobStatus(job.ID)).Should(Equal(domain.JobStatusSucceeded))
+
+		finished := showJob(job.ID)
+		Expect(finished.Signatures).To(HaveLen(2))
+		Expect(finished.Signatures[0].SignedData).To(Equal("0_first_" + service.ChainGenesis(deviceID)))
+		Expect(finished.Signatures[1].SignedData).To(Equal("1_second_" + finished.Signatures[0].Signature))
+		Expect(finished.StartedAt).NotTo(BeNil())
+		Expect(finished.FinishedAt).NotTo(BeNil())
+	})
+
+	It("should sign the jobs of a device in submission order", func() {
+		var jobIDs []string
+		for i := 0; i < 5; i++ {
+			job := submitted(submit(fmt.Sprintf(`{"device_id": "%s", "data": ["job-%d-a", "job-%d-b"]}`, deviceID, i, i)))
+			jobIDs = append(jobIDs, job.ID)
+		}
+
+		for i, jobID := range jobIDs {
+			Eventually(jobStatus(jobID)).Should(Equal(domain.JobStatusSucceeded))
+			job := showJob(jobID)
+			Expect(job.Signatures[0].SignedData).To(HavePrefix(fmt.Sprintf("%d_job-%d-a_", 2*i, i)))
+			Expect(job.Signatures[1].SignedData).To(HavePrefix(fmt.Sprintf("%d_job-%d-b_", 2*i+1, i)))
+		}
+	})
+
+	It("should record why a job failed", func() {
+		device, err := server.DeviceRepository.GetDevice(deviceID)
+		Expect(err).NotTo(HaveOccurred())
+		suspended := *device
+		suspended.Status = domain.DeviceStatusSuspended
+		Expect(server.DeviceRepository.UpdateDevice(&suspended)).To(Succeed())
+
+		job := submitted(submit(`{"device_id": "` + deviceID + `", "data": ["first"]}`))
+
+		Eventually(jobStatus(job.ID)).Should(Equal(domain.JobStatusFailed))
+		Expect(showJob(job.ID).Error).To(ContainSubstring("only active devices can sign"))
+		Expect(showJob(job.ID).Signatures).To(BeEmpty())
+	})
+
+	It("should notify the callback URL once the job finished, retrying until it is accepted", func() {
+		var attempts atomic.Int32
+		callbacks := make(chan JobResponse, 1)
+		callbackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.Method).To(Equal(http.MethodPost))
+			Expect(r.Header.Get(WebhookEventHeader)).To(Equal(domain.EventJobFinished))
+			if attempts.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			var callback struct {
+				Data JobResponse `json:"data"`
+			}
+			Expect(json.NewDecoder(r.Body).Decode(&callback)).To(Succeed())
+			callbacks <- callback.Data
+		}))
+		defer callbackServer.Close()
+
+		job := submitted(submit(`{"device_id": "` + deviceID + `", "data": ["first"], "callback_url": "` + callbackServer.URL + `"}`))
+
+		var callback JobResponse
+		Eventually(callbacks).Should(Receive(&callback))
+		Expect(attempts.Load()).To(Equal(int32(2)))
+		Expect(callback.ID).To(Equal(job.ID))
+		Expect(callback.Status).To(Equal(domain.JobStatusSucceeded))
+		Expect(callback.Signatures).To(HaveLen(1))
+		Eventually(func() ([]*domain.WebhookDelivery, error) {
+			return server.WebhookRepository.ListDeliveries(persistence.DeliveryQuery{})
+		}).Should(BeEmpty())
+	})
+
+	It("should pick up the unfinished jobs of an earlier run", func() {
+		store, err := persistence.NewFileStore(GinkgoT().TempDir())
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(store.Close)
+		server.Stop()
+		server = &Server{
+			DeviceRepository: store,
+			SignatureRepository: store,
+			UnitOfWork: store,
+			JobRepository: store,
+		}
+		device, err := server.DeviceService().CreateDevice(service.NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+		deviceID = device.ID
+
+		// A job that was running when the process went down, and one still waiting behind it
+		createdAt := time.Now().UTC()
+		Expect(store.CreateJob(&domain.Job{ID: "interrupted", DeviceID: deviceID, Data: []string{"first"}, Status: domain.JobStatusRunning, CreatedAt: createdAt, StartedAt: createdAt})).To(Succeed())
+		Expect(store.CreateJob(&domain.Job{ID: "queued", DeviceID: deviceID, Data: []string{"second"}, Status: domain.JobStatusQueued, CreatedAt: createdAt.Add(time.Millisecond)})).To(Succeed())
+
+		Expect(server.StartJobWorkers(2, 1)).To(Succeed())
+		DeferCleanup(func() { server.Stop() })
+
+		Eventually(jobStatus("queued")).Should(Equal(domain.JobStatusSucceeded))
+		Expect(showJob("interrupted").Status).To(Equal(domain.JobStatusSucceeded))
+		Expect(showJob("interrupted").Signatures[0].SignedData).To(HavePrefix("0_first_"))
+		Expect(showJob("queued").Signatures[0].SignedData).To(HavePrefix("1_second_"))
+	})
+
+	It("should start with more unfinished jobs than the queue holds", func() {
+		server.Stop()
+		server = &Server{
+			DeviceRepository: server.DeviceRepository,
+			SignatureRepository: server.SignatureRepository,
+			UnitOfWork: server.UnitOfWork,
+			JobRepository: server.JobRepository,
+		}
+		createdAt := time.Now().UTC()
+		for i := 0; i < 5; i++ {
+			Expect(server.JobRepository.CreateJob(&domain.Job{ID: fmt.Sprintf("backlog-%d", i), DeviceID: deviceID, Data: []string{fmt.Sprintf("job-%d", i)}, Status: domain.JobStatusQueued, CreatedAt: createdAt.Add(time.Duration(i) * time.Millisecond)})).To(Succeed())
+		}
+
+		// The worker cannot get past the first job
+		deviceMutex := server.DeviceRepository.GetDeviceMutex(deviceID)
+		deviceMutex.Lock()
+		started := make(chan error)
+		go func() {
+			started <- server.StartJobWorkers(1, 1)
+		}()
+		Eventually(started).Should(Receive(BeNil()))
+		DeferCleanup(func() { server.Stop() })
+
+		// New jobs would overtake the backlog
+		w := submit(`{"device_id": "` + deviceID + `", "data": ["later"]}`)
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		deviceMutex.Unlock()
+
+		for i := 0; i < 5; i++ {
+			jobID := fmt.Sprintf("backlog-%d", i)
+			Eventually(jobStatus(jobID)).Should(Equal(domain.JobStatusSucceeded))
+			Expect(showJob(jobID).Signatures[0].SignedData).To(HavePrefix(fmt.Sprintf("%d_job-%d_", i, i)))
+		}
+		Eventually(func() int {
+			return submit(`{"device_id": "` + deviceID + `", "data": ["later"]}`).Code
+		}).Should(Equal(http.StatusAccepted))
+	})
+
+	It("should stop the workers and keep the jobs they did not get to", func() {
+		deviceMutex := server.DeviceRepository.GetDeviceMutex(deviceID)
+		deviceMutex.Lock()
+		running := submitted(submit(`{"device_id": "` + deviceID + `", "data": ["first"]}`))
+		Eventually(jobStatus(running.ID)).Should(Equal(domain.JobStatusRunning))
+		queued := submitted(submit(`{"device_id": "` + deviceID + `", "data": ["second"]}`))
+
+		stopped := make(chan struct{})
+		go func() {
+			server.Stop()
+			close(stopped)
+		}()
+		Consistently(stopped).ShouldNot(BeClosed())
+		deviceMutex.Unlock()
+		Eventually(stopped).Should(BeClosed())
+
+		Expect(showJob(running.ID).Status).To(Equal(domain.JobStatusSucceeded))
+		Expect(showJob(queued.ID).Status).To(Equal(domain.JobStatusQueued))
+		w := submit(`{"device_id": "` + deviceID + `", "data": ["third"]}`)
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("should reject jobs while the worker of the device is busy", func() {
+		server.Stop()
+		server = &Server{
+			DeviceRepository: server.DeviceRepository,
+			SignatureRepository: server.SignatureRepository,
+			UnitOfWork: server.UnitOfWork,
+			JobRepository: server.JobRepository,
+		}
+		Expect(server.StartJobWorkers(1, 1)).To(Succeed())
+
+		deviceMutex := server.DeviceRepository.GetDeviceMutex(deviceID)
+		deviceMutex.Lock()
+		running := submitted(submit(`{"device_id": "` + deviceID + `", "data": ["first"]}`))
+		Eventually(jobStatus(running.ID)).Should(Equal(domain.JobStatusRunning))
+		queued := submitted(submit(`{"device_id": "` + deviceID + `", "data": ["second"]}`))
+
+		w := submit(`{"device_id": "` + deviceID + `", "data": ["third"]}`)
+		deviceMutex.Unlock()
+
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+		Eventually(jobStatus(queued.ID)).Should(Equal(domain.JobStatusSucceeded))
+	})
+
+	DescribeTable("should reject invalid jobs",
+		func(body string, message string) {
+			w := submit(strings.ReplaceAll(body, "<device>", deviceID))
+
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(w.Body.String()).To(ContainSubstring(message))
+		},
+		Entry("missing device", `{"data": ["first"]}`, "DeviceID is required"),
+		Entry("empty batch", `{"device_id": "<device>", "data": []}`, "Data must contain at least 1 items"),
+		Entry("relative callback", `{"device_id": "<device>", "data": ["first"], "callback_url": "/callback"}`, "CallbackURL must be an absolute http or https URL"),
+		Entry("unsupported callback scheme", `{"device_id": "<device>", "data": ["first"], "callback_url": "ftp://example.com"}`, "CallbackURL must be an absolute http or https URL"),
+	)
+
+	It("should answer 503 if the workers are not started", func() {
+		server = &Server{DeviceRepository: server.DeviceRepository}
+
+		w := submit(`{"device_id": "` + deviceID + `", "data": ["first"]}`)
+
+		Expect(w.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+})
+
+var _ = Describe("Signature Stream", func() {
+	type event struct {
+		ID string
+		Signature GetSignatureResponse
+	}
+
+	var (
+		deviceID string
+		server *Server
+		httpServer *httptest.Server
+	)
+
+	sign := func(data string) {
+		req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "`+deviceID+`", "data": "`+data+`"}`))
+		w := httptest.NewRecorder()
+		server.SignTransaction(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	}
+
+	// connect opens the stream and returns its events, the stream is closed after the test
+	connect := func(lastEventID string) <-chan event {
+		req, err := http.NewRequest("GET", httpServer.URL+"/api/v0/devices/"+deviceID+"/signatures/stream", nil)
+		Expect(err).NotTo(HaveOccurred())
+		if lastEventID != "" {
+			req.Header.Set(LastEventIDHeader, lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(resp.Body.Close)
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/event-stream"))
+
+		events := make(chan event, 100)
+		go func() {
+			defer GinkgoRecover()
+			scanner := bufio.NewScanner(resp.Body)
+			var current event
+			for scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case strings.HasPrefix(line, "id: "):
+					current.ID = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "data: "):
+					Expect(json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.Signature)).To(Succeed())
+				case line == "" && current.ID != "":
+					events <- current
+					current = event{}
+				}
+			}
+		}()
+		return events
+	}
+
+	nextEvent := func(events <-chan event) event {
+		var received event
+		Eventually(events).Should(Receive(&received))
+		return received
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+			SignatureBroker: service.NewSignatureBroker(service.DefaultStreamBufferSize),
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/api/v0/devices/{id}/signatures/stream", http.HandlerFunc(server.StreamSignatures))
+		httpServer = httptest.NewServer(mux)
+		DeferCleanup(httpServer.Close)
+
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "Ed25519"}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		deviceID = created.Data.ID
+	})
+
+	It("should push signatures created after connecting", func() {
+		sign("before")
+		events := connect("")
+
+		sign("after")
+
+		received := nextEvent(events)
+		Expect(received.ID).To(Equal("1"))
+		Expect(received.Signature.Data).To(Equal("after"))
+		Expect(received.Signature.DeviceID).To(Equal(deviceID))
+		Consistently(events).ShouldNot(Receive())
+	})
+
+	It("should resume after the Last-Event-ID", func() {
+		sign("first")
+		sign("second")
+		sign("third")
+		events := connect("0")
+
+		Expect(nextEvent(events).Signature.Data).To(Equal("second"))
+		Expect(nextEvent(events).Signature.Data).To(Equal("third"))
+
+		sign("fourth")
+		received := nextEvent(events)
+		Expect(received.ID).To(Equal("3"))
+		Expect(received.Signature.Data).To(Equal("fourth"))
+	})
+
+	It("should replay the whole chain from Last-Event-ID -1", func() {
+		sign("first")
+		events := connect("-1")
+
+		Expect(nextEvent(events).ID).To(Equal("0"))
+	})
+
+	It("should push batch signatures in order", func() {
+		events := connect("")
+
+		req := httptest.NewRequest("POST", "/api/v0/devices/"+deviceID+"/sign-batch", strings.NewReader(`{"data": ["a", "b", "c"]}`))
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+		server.SignBatch(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		Expect(nextEvent(events).ID).To(Equal("0"))
+		Expect(nextEvent(events).ID).To(Equal("1"))
+		Expect(nextEvent(events).ID).To(Equal("2"))
+	})
+
+	It("should catch up from storage after falling behind", func() {
+		server.SignatureBroker = service.NewSignatureBroker(1)
+		events := connect("")
+
+		req := httptest.NewRequest("POST", "/api/v0/devices/"+deviceID+"/sign-batch", strings.NewReader(`{"data": ["a", "b", "c", "d", "e"]}`))
+		req.SetPathValue("id", deviceID)
+		w := httptest.NewRecorder()
+		server.SignBatch(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		for _, id := range []string{"0", "1", "2", "3", "4"} {
+			Expect(nextEvent(events).ID).To(Equal(id))
+		}
+		Consistently(events).ShouldNot(Receive())
+	})
+
+	It("should reject an invalid Last-Event-ID", func() {
+		req, err := http.NewRequest("GET", httpServer.URL+"/api/v0/devices/"+deviceID+"/signatures/stream", nil)
+		Expect(err).NotTo(HaveOccurred())
+		req.Header.Set(LastEventIDHeader, "latest")
+
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
+
+// deletingWebhookRepository deletes a webhook right after the webhooks were listed, like a
+// client deleting it while an event is emitted.
+type deletingWebhookRepository struct {
+	persistence.IWebhookRepository
+	webhookID string
+}
+
+func (d *deletingWebhookRepository) GetAllWebhooks() ([]*domain.Webhook, error) {
+	webhooks, err := d.IWebhookRepository.GetAllWebhooks()
+	if err == nil && d.webhookID != "" {
+		err = d.IWebhookRepository.DeleteWebhook(d.webhookID)
+		d.webhookID = ""
+	}
+	return webhooks, err
+}
+
+var _ = Describe("Webhooks", func() {
+	type delivery struct {
+		Path string
+		Header http.Header
+		Body []byte
+		Event service.WebhookEvent
+	}
+
+	var (
+		server *Server
+		receiver *httptest.Server
+		deliveries chan delivery
+		failing atomic.Bool
+	)
+
+	createWebhook := func(body string) (*httptest.ResponseRecorder, WebhookResponse) {
+		req := httptest.NewRequest("POST", "/api/v0/webhooks", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.Webhooks(w, req)
+
+		var created struct {
+			Data WebhookResponse `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		return w, created.Data
+	}
+
+	createDevice := func() string {
+		req := httptest.NewRequest("POST", "/api/v0/device", strings.NewReader(`{"algorithm": "Ed25519"}`))
+		w := httptest.NewRecorder()
+		server.CreateSignatureDevice(w, req)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		return created.Data.ID
+	}
+
+	sign := func(deviceID string) {
+		req := httptest.NewRequest("POST", "/api/v0/sign-transaction", strings.NewReader(`{"device_id": "`+deviceID+`", "data": "receipt"}`))
+		w := httptest.NewRecorder()
+		server.SignTransaction(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+	}
+
+	nextDelivery := func() delivery {
+		var received delivery
+		Eventually(deliveries).Should(Receive(&received))
+		return received
+	}
+
+	deadLetters := func() []DeliveryResponse {
+		req := httptest.NewRequest("GET", "/api/v0/webhooks/dead-letters", nil)
+		w := httptest.NewRecorder()
+		server.ShowDeadLetters(w, req)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		var response struct {
+			Data []DeliveryResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &response)).To(Succeed())
+		return response.Data
+	}
+
+	BeforeEach(func() {
+		deliveries = make(chan delivery, 100)
+		failing.Store(false)
+		receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			if failing.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			received := delivery{Path: r.URL.Path, Header: r.Header}
+			var err error
+			received.Body, err = io.ReadAll(r.Body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(json.Unmarshal(received.Body, &received.Event)).To(Succeed())
+			deliveries <- received
+		}))
+		DeferCleanup(receiver.Close)
+
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		webhookRepository := persistence.NewWebhookRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, webhookRepository),
+			WebhookRepository: webhookRepository,
+			AllowPrivateEndpoints: true, // The receiver is an httptest server on the loopback interface
+		}
+		server.StartWebhookDispatcher(3, 10*time.Millisecond)
+		DeferCleanup(func() { server.Stop() })
+	})
+
+	It("should deliver device.created with a verifiable signature", func() {
+		w, webhook := createWebhook(`{"url": "` + receiver.URL + `", "secret": "a-shared-secret-value"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		Expect(webhook.Secret).To(Equal("a-shared-secret-value"))
+		Expect(webhook.Events).To(Equal(domain.Events))
+
+		deviceID := createDevice()
+
+		received := nextDelivery()
+		Expect(received.Event.Type).To(Equal(domain.EventDeviceCreated))
+		Expect(received.Event.Data).To(HaveKeyWithValue("id", deviceID))
+		Expect(received.Header.Get(WebhookEventHeader)).To(Equal(domain.EventDeviceCreated))
+		Expect(received.Header.Get(WebhookIDHeader)).To(Equal(received.Event.ID))
+
+		timestamp, signature, found := strings.Cut(strings.TrimPrefix(received.Header.Get(WebhookSignatureHeader), "t="), ",v1=")
+		Expect(found).To(BeTrue())
+		mac := hmac.New(sha256.New, []byte("a-shared-secret-value"))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(received.Body)
+		Expect(signature).To(Equal(hex.EncodeToString(mac.Sum(nil))))
+	})
+
+	It("should only deliver the subscribed events of the subscribed device", func() {
+		deviceID := createDevice()
+		otherDeviceID := createDevice()
+		w, _ := createWebhook(`{"url": "` + receiver.URL + `", "device_id": "` + deviceID + `", "events": ["signature.created"]}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		sign(otherDeviceID)
+		sign(deviceID)
+
+		received := nextDelivery()
+		Expect(received.Event.Type).To(Equal(domain.EventSignatureCreated))
+		Expect(received.Event.Data).To(HaveKeyWithValue("device_id", deviceID))
+		Expect(received.Event.Data).To(HaveKeyWithValue("signature_counter", BeNumerically("==", 0)))
+		Consistently(deliveries).ShouldNot(Receive())
+	})
+
+	It("should deliver device.deactivated when a device is suspended", func() {
+		deviceID := createDevice()
+		w, _ := createWebhook(`{"url": "` + receiver.URL + `", "events": ["device.deactivated"]}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		req := httptest.NewRequest("PATCH", "/api/v0/devices/"+deviceID, strings.NewReader(`{"status": "suspended"}`))
+		req.SetPathValue("id", deviceID)
+		patched := httptest.NewRecorder()
+		server.Device(patched, req)
+		Expect(patched.Code).To(Equal(http.StatusOK))
+
+		received := nextDelivery()
+		Expect(received.Event.Type).To(Equal(domain.EventDeviceDeactivated))
+		Expect(received.Event.Data).To(HaveKeyWithValue("status", "suspended"))
+	})
+
+	It("should still deliver to the other webhooks if one is deleted while an event is emitted", func() {
+		w, _ := createWebhook(`{"url": "` + receiver.URL + `/kept", "events": ["device.created"]}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		w, deleted := createWebhook(`{"url": "` + receiver.URL + `/deleted", "events": ["device.created"]}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		server.WebhookRepository = &deletingWebhookRepository{IWebhookRepository: server.WebhookRepository, webhookID: deleted.ID}
+
+		deviceID := createDevice()
+
+		received := nextDelivery()
+		Expect(received.Path).To(Equal("/kept"))
+		Expect(received.Event.Data).To(HaveKeyWithValue("id", deviceID))
+		Consistently(deliveries).ShouldNot(Receive())
+	})
+
+	It("should dead-letter a delivery after its attempts and deliver it again on retry", func() {
+		failing.Store(true)
+		w, webhook := createWebhook(`{"url": "` + receiver.URL + `", "events": ["device.created"]}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+		createDevice()
+
+		Eventually(deadLetters).Should(HaveLen(1))
+		deadLetter := deadLetters()[0]
+		Expect(deadLetter.WebhookID).To(Equal(webhook.ID))
+		Expect(deadLetter.Attempts).To(Equal(3))
+		Expect(deadLetter.LastError).To(ContainSubstring("503"))
+		Expect(deadLetter.Payload).NotTo(BeEmpty())
+
+		failing.Store(false)
+		req := httptest.NewRequest("POST", "/api/v0/webhooks/dead-letters/"+deadLetter.ID+"/retry", nil)
+		req.SetPathValue("id", deadLetter.ID)
+		retried := httptest.NewRecorder()
+		server.RetryDeadLetter(retried, req)
+		Expect(retried.Code).To(Equal(http.StatusOK))
+
+		received := nextDelivery()
+		Expect(received.Event.ID).To(Equal(deadLetter.EventID))
+		Eventually(deadLetters).Should(BeEmpty())
+	})
+
+	It("should back off exponentially between attempts", func() {
+		dispatcher := &webhookDispatcher{backoff: time.Second}
+
+		Expect(dispatcher.retryDelay(1)).To(Equal(time.Second))
+		Expect(dispatcher.retryDelay(2)).To(Equal(2 * time.Second))
+		Expect(dispatcher.retryDelay(5)).To(Equal(16 * time.Second))
+		Expect(dispatcher.retryDelay(100)).To(Equal(maxWebhookBackoff))
+	})
+
+	It("should stop delivering to a deleted webhook", func() {
+		w, webhook := createWebhook(`{"url": "` + receiver.URL + `"}`)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		req := httptest.NewRequest("DELETE", "/api/v0/webhooks/"+webhook.ID, nil)
+		req.SetPathValue("id", webhook.ID)
+		deleted := httptest.NewRecorder()
+		server.Webhook(deleted, req)
+		Expect(deleted.Code).To(Equal(http.StatusNoContent))
+
+		createDevice()
+		Consistently(deliveries).ShouldNot(Receive())
+	})
+
+	DescribeTable("should reject invalid webhooks",
+		func(body string, message string) {
+			w, _ := createWebhook(strings.ReplaceAll(body, "<receiver>", receiver.URL))
+
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(w.Body.String()).To(ContainSubstring(message))
+		},
+		Entry("missing URL", `{}`, "URL is required"),
+		Entry("relative URL", `{"url": "/hook"}`, "URL must be an absolute http or https URL"),
+		Entry("unknown event", `{"url": "<receiver>", "events": ["device.deleted"]}`, "Events[0] must be one of"),
+		Entry("short secret", `{"url": "<receiver>", "secret": "short"}`, "Secret must be at least 16 characters long"),
+	)
+
+	DescribeTable("should reject webhooks pointing into the internal network",
+		func(url string) {
+			server.AllowPrivateEndpoints = false
+
+			w, _ := createWebhook(`{"url": "` + url + `"}`)
+
+			Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(w.Body.String()).To(ContainSubstring("URL must not point to a loopback, link-local or private address"))
+		},
+		Entry("loopback", "http://127.0.0.1:8080/hook"),
+		Entry("localhost", "http://localhost/hook"),
+		Entry("IPv6 loopback", "http://[::1]/hook"),
+		Entry("metadata service", "http://169.254.169.254/latest/meta-data/"),
+		Entry("private network", "https://10.0.0.7/hook"),
+		Entry("IPv4-mapped private network", "http://[::ffff:192.168.1.1]/hook"),
+		Entry("unspecified", "http://0.0.0.0/hook"),
+	)
+
+	It("should refuse to connect to internal addresses a name resolves to", func() {
+		client := newOutboundClient(time.Second, false)
+
+		// The receiver listens on 127.0.0.1, which is what a hostile DNS answer would point to
+		_, err := client.Get(receiver.URL)
+		Expect(err).To(MatchError(errPrivateAddress))
+		Consistently(deliveries).ShouldNot(Receive())
+	})
+})
+
+var _ = Describe("Problem Responses", func() {
+	var (
+		server *Server
+		handler http.Handler
+	)
+
+	call := func(method string, path string, body string, header http.Header) (*httptest.ResponseRecorder, Problem) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for name, values := range header {
+			for _, value := range values {
+				req.Header.Add(name, value)
+			}
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		var problem Problem
+		if w.Header().Get("Content-Type") == ProblemContentType {
+			Expect(json.Unmarshal(w.Body.Bytes(), &problem)).To(Succeed())
+		}
+		return w, problem
+	}
+
+	createDevice := func() string {
+		w, _ := call("POST", "/api/v0/device", `{"algorithm": "Ed25519"}`, nil)
+		Expect(w.Code).To(Equal(http.StatusCreated))
+
+		var created struct {
+			Data DeviceResponse `json:"data"`
+		}
+		Expect(json.Unmarshal(w.Body.Bytes(), &created)).To(Succeed())
+		return created.Data.ID
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		server = &Server{
+			DeviceRepository: deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork: persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil),
+		}
+		handler = server.Handler()
+	})
+
+	It("should answer an unknown device with 404 and echo the request ID", func() {
+		w, problem := call("POST", "/api/v0/sign-transaction", `{"device_id": "unknown", "data": "receipt"}`, http.Header{
+			RequestIDHeader: {"till-1-request-0001"},
+		})
+
+		Expect(w.Code).To(Equal(http.StatusNotFound))
+		Expect(w.Header().Get("Content-Type")).To(Equal(ProblemContentType))
+		Expect(w.Header().Get(RequestIDHeader)).To(Equal("till-1-request-0001"))
+		Expect(problem).To(Equal(Problem{
+			Type: "about:blank",
+			Title: "Not Found",
+			Status: http.StatusNotFound,
+			Detail: "device with id unknown not found",
+			Code: ErrorCodeNotFound,
+			RequestID: "till-1-request-0001",
+		}))
+	})
+
+	It("should generate a request ID if the client sent none or an invalid one", func() {
+		for _, requestID := range []string{"", "has spaces", strings.Repeat("x", 129)} {
+			w, problem := call("GET", "/api/v0/devices/unknown", "", http.Header{RequestIDHeader: {requestID}})
+
+			Expect(w.Header().Get(RequestIDHeader)).NotTo(Equal(requestID))
+			Expect(problem.RequestID).To(Equal(w.Header().Get(RequestIDHeader)))
+			Expect(problem.RequestID).NotTo(BeEmpty())
+		}
+	})
+
+	It("should point at the fields that failed validation", func() {
+		deviceID := createDevice()
+
+		w, problem := call("POST", "/api/v0/devices/"+deviceID+"/sign-batch", `{"data": ["first", ""]}`, nil)
+
+		Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(problem.Code).To(Equal(ErrorCodeValidationFailed))
+		Expect(problem.Errors).To(Equal([]FieldError{
+			{Pointer: "/data/1", Detail: "Data[1] is required"},
+		}))
+
+		w, problem = call("POST", "/api/v0/sign-transaction", `{}`, nil)
+		Expect(w.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(problem.Errors).To(ConsistOf(
+			FieldError{Pointer: "/device_id", Detail: "DeviceID is required"},
+			FieldError{Pointer: "/data", Detail: "Data is required"},
+		))
+	})
+
+	It("should tell malformed JSON from invalid fields", func() {
+		w, problem := call("POST", "/api/v0/device", `{"algorithm": `, nil)
+
+		Expect(w.Code).To(Equal(http.StatusBadRequest))
+		Expect(problem.Code).To(Equal(ErrorCodeBadRequest))
+		Expect(problem.Errors).To(BeEmpty())
+	})
+
+	It("should give every conflict its own code", func() {
+		deviceID := createDevice()
+
+		header := http.Header{IdempotencyKeyHeader: {"key-1"}}
+		w, _ := call("POST", "/api/v0/sign-transaction", `{"device_id": "`+deviceID+`", "data": "receipt"}`, header)
+		Expect(w.Code).To(Equal(http.StatusOK))
+		w, problem := call("POST", "/api/v0/sign-transaction", `{"device_id": "`+deviceID+`", "data": "other receipt"}`, header)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+		Expect(problem.Code).To(Equal(ErrorCodeIdempotencyKeyReused))
+
+		w, _ = call("PATCH", "/api/v0/devices/"+deviceID, `{"status": "decommissioned"}`, nil)
+		Expect(w.Code).To(Equal(http.StatusOK))
+
+		w, problem = call("POST", "/api/v0/sign-transaction", `{"device_id": "`+deviceID+`", "data": "receipt"}`, nil)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+		Expect(problem.Code).To(Equal(ErrorCodeDeviceNotActive))
+
+		w, problem = call("PATCH", "/api/v0/devices/"+deviceID, `{"label": "renamed"}`, nil)
+		Expect(w.Code).To(Equal(http.StatusConflict))
+		Expect(problem.Code).To(Equal(ErrorCodeDeviceDecommissioned))
+	})
+
+	It("should not leak the text of internal errors", func() {
+		ctrl := gomock.NewController(GinkgoT())
+		mockDeviceRepository := mock_persistence.NewMockIDeviceRepository(ctrl)
+		mockDeviceRepository.EXPECT().GetDevice("device").Return(nil, fmt.Errorf("open /var/lib/signing/devices.db: permission denied"))
+		server.DeviceRepository = mockDeviceRepository
+		handler = server.Handler()
+
+		w, problem := call("GET", "/api/v0/devices/device", "", nil)
+
+		Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		Expect(problem.Code).To(Equal(ErrorCodeInternal))
+		Expect(problem.RequestID).NotTo(BeEmpty())
+		Expect(w.Body.String()).NotTo(ContainSubstring("/var/lib"))
+	})
+
+	It("should write internal errors as problems", func() {
+		w := httptest.NewRecorder()
+		w.Header().Set(RequestIDHeader, "request-1")
+
+		WriteInternalError(w)
+
+		var problem Problem
+		Expect(w.Code).To(Equal(http.StatusInternalServerError))
+		Expect(w.Header().Get("Content-Type")).To(Equal(ProblemContentType))
+		Expect(json.Unmarshal(w.Body.Bytes(), &problem)).To(Succeed())
+		Expect(problem.Code).To(Equal(ErrorCodeInternal))
+		Expect(problem.RequestID).To(Equal("request-1"))
+	})
+
+	It("should keep the method check as a problem", func() {
+		w, problem := call("GET", "/api/v0/sign-transaction", "", nil)
+
+		Expect(w.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(problem.Code).To(Equal(ErrorCodeMethodNotAllowed))
+	})
+})
+
+var _ = Describe("Request Validation", func() {
+	type boundedRequest struct {
+		Items []string `json:"items" validate:"max=1"`
+		Name  string   `json:"name" validate:"min=3"`
+		Count int      `json:"count" validate:"max=5"`
+	}
+
+	It("should describe bounds by the kind of the field", func() {
+		fieldErrors := validateRequest(boundedRequest{Items: []string{"a", "b"}, Name: "ab", Count: 6})
+
+		Expect(fieldErrors).To(ConsistOf(
+			FieldError{Pointer: "/items", Detail: "Items must contain at most 1 items"},
+			FieldError{Pointer: "/name", Detail: "Name must be at least 3 characters long"},
+			FieldError{Pointer: "/count", Detail: "Count must be at most 5"},
+		))
+	})
+})
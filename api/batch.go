@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DefaultMaxBatchItems is the number of data items a batch or job may carry unless Config says otherwise.
+const DefaultMaxBatchItems = 10000
+
+// SignBatchRequest carries the data items of a batch in signing order, at most MaxBatchItems per request.
+type SignBatchRequest struct {
+	Data []string `json:"data" validate:"required,min=1,dive,required"`
+}
+
+type SignBatchResponse struct {
+	Signatures []SignatureResponse `json:"signatures"`
+}
+
+// SignBatch signs an ordered list of data items for one device. The items are signed in order
+// while holding the device lock, each one chained to its predecessor. A batch is all or nothing:
+// if any item fails, no signature of the batch is stored and the counter is left untouched.
+func (s *Server) SignBatch(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	deviceID := request.PathValue("id")
+
+	var req SignBatchRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+		writeInvalidJSON(response)
+		return
+	}
+
+	// Validate the request
+	if validationErrors := validateRequest(req); validationErrors != nil {
+		writeValidationProblem(response, validationErrors)
+		return
+	}
+	if validationErrors := s.validateBatchSize(req.Data); validationErrors != nil {
+		writeValidationProblem(response, validationErrors)
+		return
+	}
+
+	signatureRecords, err := s.SigningService().SignSequence(deviceID, req.Data)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	signatures := make([]SignatureResponse, 0, len(signatureRecords))
+	for _, signatureRecord := range signatureRecords {
+		signatures = append(signatures, SignatureResponse{
+			Signature:  signatureRecord.SignatureValue,
+			SignedData: signatureRecord.SignedData,
+		})
+	}
+
+	WriteAPIResponse(response, http.StatusOK, SignBatchResponse{
+		Signatures: signatures,
+	})
+}
+
+// maxBatchItems returns the configured MaxBatchItems, or DefaultMaxBatchItems if none is configured.
+func (s *Server) maxBatchItems() int {
+	if s.MaxBatchItems <= 0 {
+		return DefaultMaxBatchItems
+	}
+	return s.MaxBatchItems
+}
+
+// validateBatchSize rejects batches and jobs with more data items than the server accepts.
+func (s *Server) validateBatchSize(data []string) []FieldError {
+	if len(data) <= s.maxBatchItems() {
+		return nil
+	}
+	return []FieldError{
+		{Pointer: "/data", Detail: fmt.Sprintf("Data must contain at most %d items", s.maxBatchItems())},
+	}
+}
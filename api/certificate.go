@@ -0,0 +1,221 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto/ca"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
+)
+
+// newCertificateAuthority loads the root of the certificate authority from config.CADir, or
+// returns nil if no directory is configured. The root key is sealed with the master key of
+// the process, which is unset when the device keys are left to the signer daemon.
+func newCertificateAuthority(config Config) (*ca.Authority, error) {
+	if config.CADir == "" {
+		return nil, nil
+	}
+
+	keySealer, err := crypto.LoadKeySealer(config.MasterKey, config.MasterKeyFile, config.PreviousMasterKeys)
+	if err != nil {
+		return nil, err
+	}
+	return ca.LoadOrCreate(config.CADir, keySealer)
+}
+
+// certificateFormat reads the format query parameter of the certificate endpoints, which
+// accept pem (the default) and der.
+func certificateFormat(request *http.Request) (string, bool) {
+	format := request.URL.Query().Get("format")
+	switch format {
+	case "":
+		return crypto.PublicKeyFormatPEM, true
+	case crypto.PublicKeyFormatPEM, crypto.PublicKeyFormatDER:
+		return format, true
+	default:
+		return "", false
+	}
+}
+
+// writeCertificate writes a DER encoded certificate in format, without the data envelope.
+func writeCertificate(response http.ResponseWriter, format string, der []byte) {
+	if format == crypto.PublicKeyFormatDER {
+		response.Header().Set("Content-Type", "application/pkix-cert")
+		response.WriteHeader(http.StatusOK)
+		response.Write(der)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/x-pem-file")
+	response.WriteHeader(http.StatusOK)
+	response.Write(ca.EncodeCertificate(der))
+}
+
+// ShowCACertificate returns the root certificate, the one certificate verifiers have to trust.
+func (s *Server) ShowCACertificate(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	format, ok := certificateFormat(request)
+	if !ok {
+		WriteErrorResponse(response, http.StatusBadRequest, []string{
+			"format must be one of pem or der",
+		})
+		return
+	}
+
+	if s.CertificateAuthority == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"certificate authority is not configured",
+		})
+		return
+	}
+
+	writeCertificate(response, format, s.CertificateAuthority.Certificate().Raw)
+}
+
+// ShowCRL returns the revocation list of the certificate authority, listing the certificates of
+// suspended and decommissioned devices and of rotated keys.
+func (s *Server) ShowCRL(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	format, ok := certificateFormat(request)
+	if !ok {
+		WriteErrorResponse(response, http.StatusBadRequest, []string{
+			"format must be one of pem or der",
+		})
+		return
+	}
+
+	if s.CertificateAuthority == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"certificate authority is not configured",
+		})
+		return
+	}
+
+	crl, err := s.CertificateAuthority.CRL()
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	if format == crypto.PublicKeyFormatDER {
+		response.Header().Set("Content-Type", "application/pkix-crl")
+		response.WriteHeader(http.StatusOK)
+		response.Write(crl)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/x-pem-file")
+	response.WriteHeader(http.StatusOK)
+	response.Write(ca.EncodeCRL(crl))
+}
+
+// ShowDeviceCertificate returns the certificate of the current public key of a device, signed by
+// the root. The certificate is issued along with the key, after a key rotation it certifies the new key.
+func (s *Server) ShowDeviceCertificate(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	format, ok := certificateFormat(request)
+	if !ok {
+		WriteErrorResponse(response, http.StatusBadRequest, []string{
+			"format must be one of pem or der",
+		})
+		return
+	}
+
+	if s.CertificateAuthority == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"certificate authority is not configured",
+		})
+		return
+	}
+
+	device, err := s.DeviceService().GetDevice(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	if !device.IsActive() {
+		writeDeviceNotActive(response, device)
+		return
+	}
+
+	certificate, err := s.deviceCertificate(device)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	writeCertificate(response, format, certificate)
+}
+
+// issueDeviceCertificate certifies the key of a device created, activated or rotated in tx, it is
+// an OnDeviceCreated, OnDeviceActivated and OnKeyRotated hook. The certificate of a rotated key
+// is revoked. The change fails if no certificate could be issued.
+func (s *Server) issueDeviceCertificate(tx persistence.ITransaction, device *domain.Device) error {
+	if s.CertificateAuthority == nil {
+		return nil
+	}
+
+	_, err := s.CertificateAuthority.IssueDeviceCertificate(device)
+	return err
+}
+
+// revokeDeviceCertificate revokes the certificate of a device suspended or decommissioned in tx,
+// it is an OnDeviceDeactivated hook. The device stays active if the revocation fails.
+func (s *Server) revokeDeviceCertificate(tx persistence.ITransaction, device *domain.Device) error {
+	if s.CertificateAuthority == nil {
+		return nil
+	}
+
+	return s.CertificateAuthority.RevokeDeviceCertificate(device.ID)
+}
+
+// deviceCertificate returns the certificate issued for the current key of device. Keys without
+// one, created before certificates were issued along with them or while the certificate
+// authority was disabled, get theirs on the first request, later requests return the same.
+func (s *Server) deviceCertificate(device *domain.Device) ([]byte, error) {
+	certificate, err := s.CertificateAuthority.DeviceCertificate(device)
+	if !errors.Is(err, ca.ErrNoDeviceCertificate) {
+		return certificate, err
+	}
+
+	// Serialized with key rotations and concurrent requests, so a key is certified only once
+	deviceMutex := s.DeviceRepository.GetDeviceMutex(device.ID)
+	deviceMutex.Lock()
+	defer deviceMutex.Unlock()
+
+	device, err = s.DeviceRepository.GetDevice(device.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !device.IsActive() {
+		return nil, &service.DeviceNotActiveError{Device: device}
+	}
+
+	certificate, err = s.CertificateAuthority.DeviceCertificate(device)
+	if !errors.Is(err, ca.ErrNoDeviceCertificate) {
+		return certificate, err
+	}
+	return s.CertificateAuthority.IssueDeviceCertificate(device)
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
+)
+
+// Reasons reported for a broken signature chain.
+const (
+	ChainIssueCounterGap       = "counter_gap"
+	ChainIssueBrokenLink       = "broken_link"
+	ChainIssueInvalidSignature = "invalid_signature"
+	ChainIssueMissingData      = "missing_signed_data"
+	ChainIssueCounterMismatch  = "counter_mismatch"
+	ChainIssueKeyRotation      = "invalid_key_rotation"
+)
+
+type ChainIssue struct {
+	SignatureCounter int    `json:"signature_counter"`
+	SignatureID      string `json:"signature_id,omitempty"`
+	Reason           string `json:"reason"`
+	Message          string `json:"message"`
+}
+
+type ChainVerificationResponse struct {
+	DeviceID           string       `json:"device_id"`
+	Valid              bool         `json:"valid"`
+	VerifiedSignatures int          `json:"verified_signatures"`
+	Issues             []ChainIssue `json:"issues"`
+}
+
+func (s *Server) VerifyChain(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	device, err := s.DeviceService().GetDevice(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	signatures, err := s.SignatureRepository.GetAllSignaturesByDeviceID(device.ID)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	keys, err := newChainKeys(device)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	WriteAPIResponse(response, http.StatusOK, verifyChain(device, signatures, keys))
+}
+
+// verifyChain walks the signatures of a device in counter order and reports every
+// counter gap, every signature not chained to its predecessor and every bad signature.
+// Each signature is verified with the key that was active for its counter, and every key
+// rotation record has to bind the key that followed it.
+func verifyChain(device *domain.Device, signatures []*domain.Signature, keys chainKeys) ChainVerificationResponse {
+	sort.Slice(signatures, func(i, j int) bool {
+		return signatures[i].SignatureCounter < signatures[j].SignatureCounter
+	})
+
+	result := ChainVerificationResponse{
+		DeviceID: device.ID,
+		Issues:   []ChainIssue{},
+	}
+
+	expectedCounter := 0
+	lastSignature := service.ChainGenesis(device.ID)
+
+	for _, signature := range signatures {
+		report := func(reason string, format string, args ...interface{}) {
+			result.Issues = append(result.Issues, ChainIssue{
+				SignatureCounter: signature.SignatureCounter,
+				SignatureID:      signature.ID,
+				Reason:           reason,
+				Message:          fmt.Sprintf(format, args...),
+			})
+		}
+
+		if signature.SignatureCounter != expectedCounter {
+			report(ChainIssueCounterGap, "expected signature counter %d, got %d", expectedCounter, signature.SignatureCounter)
+		}
+
+		key := keys.forCounter(signature.SignatureCounter)
+		if next, rotated := keys.successor(key); rotated && signature.SignatureCounter == key.lastCounter &&
+			signature.SignedData != service.BuildSignedData(signature.SignatureCounter, service.RotationData(next.publicKey), lastSignature) {
+			report(ChainIssueKeyRotation, "rotation record does not bind the key that followed it")
+		}
+
+		switch {
+		case signature.SignedData == "":
+			report(ChainIssueMissingData, "signed data was not recorded for this signature")
+		case signature.SignedData != service.BuildSignedData(signature.SignatureCounter, chainedData(signature, lastSignature), lastSignature):
+			report(ChainIssueBrokenLink, "signed data is not chained to the previous signature")
+		default:
+			if err := verifySignatureValue(key.verifier, signature.SignedData, signature.SignatureValue); err != nil {
+				report(ChainIssueInvalidSignature, "%s", err.Error())
+			} else {
+				result.VerifiedSignatures++
+			}
+		}
+
+		expectedCounter = signature.SignatureCounter + 1
+		lastSignature = signature.SignatureValue
+	}
+
+	if device.SignatureCounter != expectedCounter {
+		result.Issues = append(result.Issues, ChainIssue{
+			SignatureCounter: device.SignatureCounter,
+			Reason:           ChainIssueCounterMismatch,
+			Message:          fmt.Sprintf("device counter is %d but the chain ends before %d", device.SignatureCounter, expectedCounter),
+		})
+	}
+
+	result.Valid = len(result.Issues) == 0
+	return result
+}
+
+// chainedData returns the data a signature was built over. Signatures stored before the raw data
+// was recorded only have their signed data, the data is read from between the counter and the
+// link to lastSignature then. An empty result does not rebuild the signed data.
+func chainedData(signature *domain.Signature, lastSignature string) string {
+	if signature.Data != "" {
+		return signature.Data
+	}
+
+	data, found := strings.CutPrefix(signature.SignedData, fmt.Sprintf("%d_", signature.SignatureCounter))
+	if !found {
+		return ""
+	}
+	data, found = strings.CutSuffix(data, "_"+lastSignature)
+	if !found {
+		return ""
+	}
+	return data
+}
+
+// verifySignatureValue checks a base64 encoded signature against the signed data.
+func verifySignatureValue(verifier crypto.Verifier, signedData string, signatureValue string) error {
+	signature, err := base64.StdEncoding.DecodeString(signatureValue)
+	if err != nil {
+		return fmt.Errorf("signature is not valid base64: %w", err)
+	}
+
+	return verifier.Verify([]byte(signedData), signature)
+}
+
+// newVerifier returns a verifier for the current key of a device.
+func newVerifier(device *domain.Device) (crypto.Verifier, error) {
+	return newPublicKeyVerifier(device, device.PublicKey)
+}
+
+// newPublicKeyVerifier returns a verifier for publicKey, which is a current or retired key of device.
+func newPublicKeyVerifier(device *domain.Device, publicKey string) (crypto.Verifier, error) {
+	algorithm, err := crypto.LookupAlgorithm(device.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return algorithm.NewVerifier([]byte(publicKey), device.KeyParameters)
+}
+
+// chainKey is a public key of a device together with the range of counters it signed.
+type chainKey struct {
+	publicKey    string
+	firstCounter int
+	lastCounter  int // -1 for the current key
+	verifier     crypto.Verifier
+}
+
+// chainKeys holds every key of a device, ordered by the counters they signed.
+type chainKeys []chainKey
+
+func newChainKeys(device *domain.Device) (chainKeys, error) {
+	keys := make(chainKeys, 0, len(device.KeyHistory)+1)
+	for _, retiredKey := range device.KeyHistory {
+		verifier, err := newPublicKeyVerifier(device, retiredKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, chainKey{
+			publicKey:    retiredKey.PublicKey,
+			firstCounter: retiredKey.FirstCounter,
+			lastCounter:  retiredKey.LastCounter,
+			verifier:     verifier,
+		})
+	}
+
+	verifier, err := newVerifier(device)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, chainKey{
+		publicKey:    device.PublicKey,
+		firstCounter: device.CurrentKeyFirstCounter(),
+		lastCounter:  -1,
+		verifier:     verifier,
+	}), nil
+}
+
+// forCounter returns the key that was active when counter was signed.
+func (k chainKeys) forCounter(counter int) chainKey {
+	for _, key := range k {
+		if key.lastCounter == -1 || counter <= key.lastCounter {
+			return key
+		}
+	}
+	return k[len(k)-1]
+}
+
+// successor returns the key that replaced key, if it was rotated.
+func (k chainKeys) successor(key chainKey) (chainKey, bool) {
+	for i := range k[:len(k)-1] {
+		if k[i].firstCounter == key.firstCounter {
+			return k[i+1], true
+		}
+	}
+	return chainKey{}, false
+}
@@ -2,16 +2,27 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
 	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
-	"github.com/google/uuid"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
 )
 
 type CreateDeviceRequest struct {
-    Algorithm string `json:"algorithm" validate:"required,oneof=RSA ECC"`
+    Algorithm string `json:"algorithm" validate:"required,algorithm"`
     Label     string `json:"label"`
+    KeySize   int    `json:"key_size"`
+    Curve     string `json:"curve"`
+    Hash      string `json:"hash"`
+    Padding   string `json:"padding"`
+    Metadata  map[string]string `json:"metadata,omitempty"`
+    // PrivateKey imports an existing key instead of generating one, either a PEM string
+    // (PKCS#1, PKCS#8 or SEC1) or a JWK object
+    PrivateKey json.RawMessage `json:"private_key,omitempty"`
 }
 
 type DeviceResponse struct {
@@ -20,6 +31,21 @@ type DeviceResponse struct {
     PublicKey        string `json:"public_key"`
     SignatureCounter int    `json:"signature_counter"`
     Label           string `json:"label"`
+    KeySize          int    `json:"key_size,omitempty"`
+    Curve            string `json:"curve,omitempty"`
+    Hash             string `json:"hash,omitempty"`
+    Padding          string `json:"padding,omitempty"`
+    KeyHistory       []RetiredKeyResponse `json:"key_history,omitempty"`
+    Status           string `json:"status"`
+    Metadata         map[string]string `json:"metadata,omitempty"`
+    CreatedAt        time.Time `json:"created_at"`
+}
+
+type RetiredKeyResponse struct {
+    PublicKey    string    `json:"public_key"`
+    FirstCounter int       `json:"first_counter"`
+    LastCounter  int       `json:"last_counter"`
+    RotatedAt    time.Time `json:"rotated_at"`
 }
 
 func (s *Server) CreateSignatureDevice(response http.ResponseWriter, request *http.Request) {
@@ -32,93 +58,86 @@ func (s *Server) CreateSignatureDevice(response http.ResponseWriter, request *ht
 
 	var req CreateDeviceRequest
 	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
-		WriteErrorResponse(response, http.StatusBadRequest, []string{
-			"Invalid JSON format",
-		})
+		writeInvalidJSON(response)
 		return
 	}
 
 	// Validate the request
 	if validationErrors := validateRequest(req); validationErrors != nil {
-		WriteErrorResponse(response, http.StatusBadRequest, validationErrors)
+		writeValidationProblem(response, validationErrors)
 		return
 	}
 
-	device := domain.Device{
-		ID: uuid.New().String(),
+	newDevice := service.NewDevice{
 		Algorithm: req.Algorithm,
-		SignatureCounter: 0,
 		Label: req.Label,
+		KeyParameters: domain.KeyParameters{
+			KeySize: req.KeySize,
+			Curve: req.Curve,
+			Hash: req.Hash,
+			Padding: req.Padding,
+		},
+		Metadata: req.Metadata,
 	}
-
-	switch req.Algorithm {
-		case "RSA":
-			rsa := crypto.RSAGenerator{}
-			keyPair, err := rsa.Generate()
-			if err != nil {
-				WriteErrorResponse(response, http.StatusInternalServerError, []string{
-					err.Error(),
-				})
-				return
-			}
-
-			rsaMarshaler := crypto.NewRSAMarshaler()
-			public, private, err := rsaMarshaler.Marshal(*keyPair)
-			if err != nil {
-				WriteErrorResponse(response, http.StatusInternalServerError, []string{
-					err.Error(),
-				})
-				return
-			}
-
-			device.PublicKey = string(public)
-			device.PrivateKey = string(private)
-		case "ECC":
-			ecc := crypto.ECCGenerator{}
-			keyPair, err := ecc.Generate()
-			if err != nil {
-				WriteErrorResponse(response, http.StatusInternalServerError, []string{
-					err.Error(),
-				})
-				return
-			}
-
-			eccMarshaler := crypto.NewECCMarshaler()
-			public, private, err := eccMarshaler.Encode(*keyPair)
-			if err != nil {
-				WriteErrorResponse(response, http.StatusInternalServerError, []string{
-					err.Error(),
-				})
-				return
-			}
-
-			device.PublicKey = string(public)
-			device.PrivateKey = string(private)
-		default:
-			WriteErrorResponse(response, http.StatusBadRequest, []string{
-				"Invalid algorithm",
+	if len(req.PrivateKey) > 0 {
+		privateKey, err := importedPrivateKey(req.PrivateKey)
+		if err != nil {
+			writeValidationProblem(response, []FieldError{
+				{Pointer: "/private_key", Detail: err.Error()},
 			})
 			return
 		}
+		newDevice.PrivateKey = privateKey
+	}
 
-	err := s.DeviceRepository.CreateDevice(&device)
+	device, err := s.DeviceService().CreateDevice(newDevice)
 	if err != nil {
-		WriteErrorResponse(response, http.StatusInternalServerError, []string{
-			err.Error(),
-		})
+		writeError(response, err)
 		return
 	}
 
-	WriteAPIResponse(response, http.StatusCreated, wrapDeviceResponse(&device))
+	WriteAPIResponse(response, http.StatusCreated, wrapDeviceResponse(device))
+}
+
+// importedPrivateKey returns the key to import as given, a PEM string is unquoted and a JWK object kept as JSON.
+func importedPrivateKey(raw json.RawMessage) ([]byte, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		return []byte(encoded), nil
+	}
+
+	var jwk map[string]interface{}
+	if err := json.Unmarshal(raw, &jwk); err != nil {
+		return nil, errors.New("private_key must be a PEM string or a JWK object")
+	}
+	return raw, nil
 }
 
 func wrapDeviceResponse(device *domain.Device) DeviceResponse {
+	var keyHistory []RetiredKeyResponse
+	for _, retiredKey := range device.KeyHistory {
+		keyHistory = append(keyHistory, RetiredKeyResponse{
+			PublicKey: crypto.StandardPublicKeyPEM(retiredKey.PublicKey),
+			FirstCounter: retiredKey.FirstCounter,
+			LastCounter: retiredKey.LastCounter,
+			RotatedAt: retiredKey.RotatedAt,
+		})
+	}
+
 	return DeviceResponse{
 		ID: device.ID,
 		Algorithm: device.Algorithm,
-		PublicKey: device.PublicKey,
+		PublicKey: crypto.StandardPublicKeyPEM(device.PublicKey),
 		SignatureCounter: device.SignatureCounter,
 		Label: device.Label,
+		KeySize: device.KeyParameters.KeySize,
+		Curve: device.KeyParameters.Curve,
+		Hash: device.KeyParameters.Hash,
+		Padding: device.KeyParameters.Padding,
+		KeyHistory: keyHistory,
+		Status: string(device.CurrentStatus()),
+		Metadata: device.Metadata,
+		CreatedAt: device.CreatedAt,
 	}
 }
 
@@ -130,17 +149,53 @@ func (s *Server) ShowAllDevices(response http.ResponseWriter, request *http.Requ
 		return
 	}
 	
-	devices, err := s.DeviceRepository.GetAllDevices()
+	parameters, err := parsePageParameters(request.URL.Query())
 	if err != nil {
-		WriteErrorResponse(response, http.StatusInternalServerError, []string{
+		WriteErrorResponse(response, http.StatusBadRequest, []string{
 			err.Error(),
 		})
 		return
 	}
 
-	deviceResponses := deviceListResponse(devices)
+	query := persistence.DeviceQuery{
+		Algorithm:   request.URL.Query().Get("algorithm"),
+		LabelPrefix: request.URL.Query().Get("label_prefix"),
+		Limit:       parameters.queryLimit(),
+		Descending:  parameters.descending,
+	}
+	if status := request.URL.Query().Get("status"); status != "" {
+		query.Status, err = domain.ParseDeviceStatus(status)
+		if err != nil {
+			WriteErrorResponse(response, http.StatusBadRequest, []string{
+				err.Error(),
+			})
+			return
+		}
+	}
+	if parameters.cursor != nil {
+		query.After, err = parameters.cursor.DeviceCursor()
+		if err != nil {
+			WriteErrorResponse(response, http.StatusBadRequest, []string{
+				err.Error(),
+			})
+			return
+		}
+	}
+
+	devices, err := s.DeviceService().ListDevices(query)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	var nextCursor string
+	if parameters.nextPage(len(devices)) {
+		devices = devices[:parameters.limit]
+		last := devices[len(devices)-1]
+		nextCursor = persistence.EncodeDevicePageCursor(last)
+	}
 
-	WriteAPIResponse(response, http.StatusOK, deviceResponses)
+	WritePageResponse(response, http.StatusOK, deviceListResponse(devices), nextCursor)
 }
 
 func deviceListResponse(devices []*domain.Device) []DeviceResponse {
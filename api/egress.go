@@ -0,0 +1,81 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateAddress is returned when a callback or webhook would reach an address that is not
+// publicly routable, e.g. the loopback interface or a cloud metadata service.
+var errPrivateAddress = errors.New("address is not publicly routable")
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, netip does not treat it as private.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddress reports whether address may be reached by outbound requests.
+func isPublicAddress(address netip.Addr) bool {
+	address = address.Unmap()
+	return address.IsGlobalUnicast() &&
+		!address.IsPrivate() &&
+		!sharedAddressSpace.Contains(address)
+}
+
+// newOutboundClient returns the client calling the URLs registered by API clients. Unless
+// allowPrivate is set it refuses to connect to addresses that are not publicly routable. The
+// check runs on the address that is actually dialed, so neither DNS answers nor redirects can
+// lead the request back into the internal network. Proxies from the environment are ignored
+// for the same reason.
+func newOutboundClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network string, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddress(addrPort.Addr()) {
+				return fmt.Errorf("%s: %w", addrPort.Addr(), errPrivateAddress)
+			}
+			return nil
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: webhookConcurrency,
+		},
+	}
+}
+
+// validateHTTPURL checks that the value of field is an absolute http or https URL. Unless
+// allowPrivate is set, hosts that are obviously internal are refused right away, names that
+// resolve to internal addresses are refused by the outbound client when it dials them.
+func validateHTTPURL(field string, value string, allowPrivate bool) error {
+	parsed, err := url.Parse(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("%s must be an absolute http or https URL", field)
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%s must not point to a loopback, link-local or private address", field)
+	}
+	if address, err := netip.ParseAddr(host); err == nil && !isPublicAddress(address) {
+		return fmt.Errorf("%s must not point to a loopback, link-local or private address", field)
+	}
+	return nil
+}
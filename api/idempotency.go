@@ -0,0 +1,13 @@
+package api
+
+import "github.com/fiskaly/coding-challenges/signing-service-challenge/service"
+
+const (
+	// IdempotencyKeyHeader lets clients retry a sign request without signing twice.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks a response that returns a signature created by an earlier request.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyRetention is how long an idempotency key is honored unless Config says otherwise.
+	DefaultIdempotencyRetention = service.DefaultIdempotencyRetention
+)
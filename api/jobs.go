@@ -0,0 +1,365 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultJobWorkers is the number of workers signing jobs unless Config says otherwise.
+	DefaultJobWorkers = 4
+	// DefaultJobQueueSize is the number of jobs waiting per worker unless Config says otherwise.
+	DefaultJobQueueSize = 1000
+	// DefaultJobRetention is how long finished jobs can be polled unless Config says otherwise.
+	DefaultJobRetention = 24 * time.Hour
+)
+
+var (
+	errJobQueueFull    = errors.New("job queue is full, try again later")
+	errJobQueueStopped = errors.New("job workers are stopped")
+)
+
+type SubmitJobRequest struct {
+	DeviceID string `json:"device_id" validate:"required"`
+	Data []string `json:"data" validate:"required,min=1,dive,required"`
+	CallbackURL string `json:"callback_url"`
+}
+
+type JobResponse struct {
+	ID string `json:"id"`
+	DeviceID string `json:"device_id"`
+	Status domain.JobStatus `json:"status"`
+	Items int `json:"items"`
+	Signatures []SignatureResponse `json:"signatures,omitempty"`
+	Error string `json:"error,omitempty"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func wrapJobResponse(job *domain.Job) JobResponse {
+	jobResponse := JobResponse{
+		ID: job.ID,
+		DeviceID: job.DeviceID,
+		Status: job.Status,
+		Items: len(job.Data),
+		Error: job.Error,
+		CallbackURL: job.CallbackURL,
+		CreatedAt: job.CreatedAt,
+	}
+	if !job.StartedAt.IsZero() {
+		jobResponse.StartedAt = &job.StartedAt
+	}
+	if !job.FinishedAt.IsZero() {
+		jobResponse.FinishedAt = &job.FinishedAt
+	}
+	for _, signature := range job.Signatures {
+		jobResponse.Signatures = append(jobResponse.Signatures, SignatureResponse{
+			Signature:  signature.SignatureValue,
+			SignedData: signature.SignedData,
+		})
+	}
+	return jobResponse
+}
+
+// jobQueue hands jobs to a fixed set of workers. All jobs of a device go to the same worker,
+// which runs them one after another, so a device signs its jobs in the order they were submitted.
+type jobQueue struct {
+	mutex sync.Mutex // Held while a job is submitted, so a free slot cannot be taken in between
+	repository persistence.IJobRepository
+	workers []chan string
+	backlog []int // Jobs of each worker still to be queued again after a restart, new jobs wait for them
+	stopped chan struct{} // Closed once the workers are told to stop
+	running sync.WaitGroup
+	stopOnce sync.Once
+}
+
+// submit stores the job and queues it, or returns errJobQueueFull if its worker is too far behind.
+func (q *jobQueue) submit(job *domain.Job) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	select {
+	case <-q.stopped:
+		return errJobQueueStopped
+	default:
+	}
+
+	i := q.worker(job.DeviceID)
+	worker := q.workers[i]
+	if q.backlog[i] > 0 || len(worker) == cap(worker) {
+		return errJobQueueFull
+	}
+
+	if err := q.repository.CreateJob(job); err != nil {
+		return err
+	}
+	worker <- job.ID
+	return nil
+}
+
+// worker returns the index of the worker running the jobs of the device.
+func (q *jobQueue) worker(deviceID string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(deviceID))
+	return int(hash.Sum32() % uint32(len(q.workers)))
+}
+
+// requeue feeds the backlog of a worker into its queue as the worker drains it, in the order the
+// jobs were submitted. It gives up once the workers are told to stop, the jobs stay queued in
+// the repository.
+func (q *jobQueue) requeue(i int, jobIDs []string) {
+	worker := q.workers[i]
+	for _, jobID := range jobIDs {
+		select {
+		case <-q.stopped:
+			return
+		case worker <- jobID:
+		}
+
+		q.mutex.Lock()
+		q.backlog[i]--
+		q.mutex.Unlock()
+	}
+}
+
+// stop tells the workers to stop and waits for the jobs they are running. Jobs still waiting
+// in the queues stay queued in the repository.
+func (q *jobQueue) stop() {
+	q.stopOnce.Do(func() {
+		q.mutex.Lock()
+		close(q.stopped)
+		q.mutex.Unlock()
+	})
+	q.running.Wait()
+}
+
+// StartJobWorkers starts the workers processing the signing jobs, each with room for queueSize
+// waiting jobs. Jobs that are still queued or running in the JobRepository, e.g. because the
+// process was restarted, are queued again in the background, and new jobs of a worker are
+// refused until its backlog is queued. Until it is called, or without a JobRepository, the job
+// endpoints answer 503 Service Unavailable.
+func (s *Server) StartJobWorkers(workers int, queueSize int) error {
+	if workers <= 0 {
+		workers = DefaultJobWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultJobQueueSize
+	}
+	if s.JobRepository == nil {
+		return nil
+	}
+
+	unfinishedJobs, err := s.JobRepository.ListUnfinishedJobs()
+	if err != nil {
+		return err
+	}
+
+	queue := &jobQueue{
+		repository: s.JobRepository,
+		stopped: make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		worker := make(chan string, queueSize)
+		queue.workers = append(queue.workers, worker)
+		queue.running.Add(1)
+		go func() {
+			defer queue.running.Done()
+			for {
+				// A stop request wins over waiting jobs
+				select {
+				case <-queue.stopped:
+					return
+				default:
+				}
+
+				select {
+				case <-queue.stopped:
+					return
+				case jobID := <-worker:
+					s.runJob(jobID)
+				}
+			}
+		}()
+	}
+
+	// The backlog can be larger than the queues, so it is fed in as the workers drain them.
+	// Every device keeps the order of its jobs, new ones are refused until it is queued.
+	backlogs := make([][]string, workers)
+	for _, job := range unfinishedJobs {
+		i := queue.worker(job.DeviceID)
+		backlogs[i] = append(backlogs[i], job.ID)
+	}
+	queue.backlog = make([]int, workers)
+	for i, jobIDs := range backlogs {
+		if len(jobIDs) == 0 {
+			continue
+		}
+		queue.backlog[i] = len(jobIDs)
+		queue.running.Add(1)
+		go func() {
+			defer queue.running.Done()
+			queue.requeue(i, jobIDs)
+		}()
+	}
+
+	s.jobs = queue
+	return nil
+}
+
+// jobRetention returns the configured retention of finished jobs, or the default.
+func (s *Server) jobRetention() time.Duration {
+	if s.JobRetention <= 0 {
+		return DefaultJobRetention
+	}
+	return s.JobRetention
+}
+
+// runJob signs the items of a job and records the outcome, like a batch a job is all or nothing.
+func (s *Server) runJob(jobID string) {
+	if _, err := s.SigningService().RunJob(jobID); err != nil {
+		log.Printf("job %s: %v", jobID, err)
+	}
+}
+
+// jobFinished puts the callback of a finished job into the outbox, in the same unit of work as
+// the outcome of the job. The job is posted in the shape GET /api/v0/jobs/{id} returns it.
+func (s *Server) jobFinished(tx persistence.ITransaction, job *domain.Job) error {
+	if job.CallbackURL == "" || s.WebhookRepository == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(Response{Data: wrapJobResponse(job)})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	err = tx.CreateDeliveries([]*domain.WebhookDelivery{{
+		ID: uuid.New().String(),
+		URL: job.CallbackURL,
+		EventID: uuid.New().String(),
+		Event: domain.EventJobFinished,
+		Payload: string(payload),
+		Status: domain.DeliveryStatusPending,
+		NextAttemptAt: now,
+		CreatedAt: now,
+	}})
+	if err != nil {
+		return err
+	}
+	if s.webhooks != nil {
+		tx.AfterCommit(s.webhooks.notify)
+	}
+	return nil
+}
+
+// SubmitJob queues the signing of a list of data items and answers 202 Accepted right away.
+// The job is processed in the background and can be polled with GET /api/v0/jobs/{id}.
+func (s *Server) SubmitJob(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	var req SubmitJobRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+		writeInvalidJSON(response)
+		return
+	}
+
+	// Validate the request
+	if validationErrors := validateRequest(req); validationErrors != nil {
+		writeValidationProblem(response, validationErrors)
+		return
+	}
+	if validationErrors := s.validateBatchSize(req.Data); validationErrors != nil {
+		writeValidationProblem(response, validationErrors)
+		return
+	}
+	if req.CallbackURL != "" {
+		if err := validateHTTPURL("CallbackURL", req.CallbackURL, s.AllowPrivateEndpoints); err != nil {
+			writeValidationProblem(response, []FieldError{
+				{Pointer: "/callback_url", Detail: err.Error()},
+			})
+			return
+		}
+	}
+
+	if s.jobs == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"background jobs are not enabled",
+		})
+		return
+	}
+
+	// Fail early for unknown devices, whether the device may sign is checked when the job runs
+	if _, err := s.DeviceService().GetDevice(req.DeviceID); err != nil {
+		writeError(response, err)
+		return
+	}
+
+	if _, err := s.JobRepository.DeleteJobsFinishedBefore(time.Now().Add(-s.jobRetention())); err != nil {
+		writeError(response, err)
+		return
+	}
+
+	job := &domain.Job{
+		ID: uuid.New().String(),
+		DeviceID: req.DeviceID,
+		Data: req.Data,
+		CallbackURL: req.CallbackURL,
+		Status: domain.JobStatusQueued,
+		CreatedAt: time.Now().UTC(),
+	}
+	err := s.jobs.submit(job)
+	if errors.Is(err, errJobQueueFull) || errors.Is(err, errJobQueueStopped) {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	response.Header().Set("Location", "/api/v0/jobs/"+job.ID)
+	WriteAPIResponse(response, http.StatusAccepted, wrapJobResponse(job))
+}
+
+func (s *Server) ShowJob(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	if s.jobs == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"background jobs are not enabled",
+		})
+		return
+	}
+
+	job, err := s.JobRepository.GetJob(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	WriteAPIResponse(response, http.StatusOK, wrapJobResponse(job))
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+)
+
+// keyRewrapper is implemented by key providers whose references change when the master key is rotated.
+type keyRewrapper interface {
+	NeedsRewrap(reference string) bool
+	Rewrap(reference string) (string, error)
+}
+
+// compactor is implemented by storages that keep overwritten records on disk until they are compacted.
+type compactor interface {
+	Compact() error
+}
+
+// newKeyProvider sets up the provider holding the device private keys. The local provider
+// seals keys with the configured master key, the remote one leaves that to the signer daemon.
+// Without a master key the local provider refuses to put private keys into a persistent
+// storage in the clear, unless that was explicitly allowed.
+func newKeyProvider(config Config) (crypto.KeyProvider, error) {
+	switch config.KeyProvider {
+	case "", KeyProviderLocal:
+		keySealer, err := crypto.LoadKeySealer(config.MasterKey, config.MasterKeyFile, config.PreviousMasterKeys)
+		if err != nil {
+			return nil, err
+		}
+		if keySealer == nil && config.Storage != "" && config.Storage != StorageMemory {
+			if !config.AllowPlaintextKeys {
+				return nil, fmt.Errorf("%s storage would keep device private keys unencrypted, set a master key or allow plaintext keys", config.Storage)
+			}
+			log.Printf("WARNING: no master key is set, device private keys are stored unencrypted in the %s storage", config.Storage)
+		}
+		return crypto.NewLocalKeyProvider(keySealer), nil
+	case KeyProviderRemote:
+		return crypto.DialKeyProvider("unix", config.SignerSocket)
+	default:
+		return nil, fmt.Errorf("unsupported key provider: %s", config.KeyProvider)
+	}
+}
+
+// RewrapDeviceKeys wraps the data key of every device with the current master key after a
+// rotation, and seals keys that were stored before encryption was enabled. The private keys
+// themselves do not change. Once keys were updated the storage is compacted, so the records
+// holding them unsealed or under the old master key are gone from disk too. It returns the
+// number of devices that were updated.
+func (s *Server) RewrapDeviceKeys() (int, error) {
+	rewrapper, ok := s.KeyProvider.(keyRewrapper)
+	if !ok {
+		return 0, nil
+	}
+
+	devices, err := s.DeviceRepository.GetAllDevices()
+	if err != nil {
+		return 0, err
+	}
+
+	rewrapped := 0
+	for _, device := range devices {
+		if !rewrapper.NeedsRewrap(device.KeyReference) {
+			continue
+		}
+
+		if err := s.rewrapDeviceKey(rewrapper, device.ID); err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
+	}
+
+	if storage, ok := s.DeviceRepository.(compactor); ok && rewrapped > 0 {
+		if err := storage.Compact(); err != nil {
+			return rewrapped, fmt.Errorf("compacting storage after rewrapping device keys: %w", err)
+		}
+	}
+
+	return rewrapped, nil
+}
+
+func (s *Server) rewrapDeviceKey(rewrapper keyRewrapper, deviceID string) error {
+	deviceMutex := s.DeviceRepository.GetDeviceMutex(deviceID)
+	deviceMutex.Lock()
+	defer deviceMutex.Unlock()
+
+	device, err := s.DeviceRepository.GetDevice(deviceID)
+	if err != nil {
+		return err
+	}
+
+	keyReference, err := rewrapper.Rewrap(device.KeyReference)
+	if err != nil {
+		return err
+	}
+
+	updatedDevice := *device
+	updatedDevice.KeyReference = keyReference
+	return s.DeviceRepository.UpdateDevice(&updatedDevice)
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
+)
+
+// UpdateDeviceRequest changes the fields that are set. Metadata is merged into the existing
+// metadata of the device, a null value removes the key.
+type UpdateDeviceRequest struct {
+	Label    *string            `json:"label"`
+	Metadata map[string]*string `json:"metadata"`
+	Status   *string            `json:"status"`
+}
+
+// writeDeviceNotActive rejects an operation that needs the device to sign.
+func writeDeviceNotActive(response http.ResponseWriter, device *domain.Device) {
+	writeError(response, &service.DeviceNotActiveError{Device: device})
+}
+
+// Device serves a single device, GET returns it and PATCH updates it.
+func (s *Server) Device(response http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		s.ShowDevice(response, request)
+	case http.MethodPatch:
+		s.UpdateDevice(response, request)
+	default:
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+	}
+}
+
+func (s *Server) ShowDevice(response http.ResponseWriter, request *http.Request) {
+	device, err := s.DeviceService().GetDevice(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	WriteAPIResponse(response, http.StatusOK, wrapDeviceResponse(device))
+}
+
+// UpdateDevice changes the label, metadata or status of a device. Suspended devices can be
+// activated again, decommissioned devices are archived and can no longer be changed.
+func (s *Server) UpdateDevice(response http.ResponseWriter, request *http.Request) {
+	var req UpdateDeviceRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+		writeInvalidJSON(response)
+		return
+	}
+
+	update := service.DeviceUpdate{
+		Label: req.Label,
+		Metadata: req.Metadata,
+	}
+	if req.Status != nil {
+		status := domain.DeviceStatus(*req.Status)
+		update.Status = &status
+	}
+
+	device, err := s.DeviceService().UpdateDevice(request.PathValue("id"), update)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	WriteAPIResponse(response, http.StatusOK, wrapDeviceResponse(device))
+}
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+)
+
+// Page sizes of the list endpoints, a limit query parameter picks one up to MaxPageLimit. A list
+// requested without limit and cursor is returned in full, like before the lists were paged.
+const (
+	DefaultPageLimit = 100
+	MaxPageLimit     = 1000
+)
+
+// PageResponse is the response container of the list endpoints. NextCursor is empty on the last page.
+type PageResponse struct {
+	Data       interface{} `json:"data"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// pageParameters are the paging query parameters shared by the list endpoints.
+type pageParameters struct {
+	limit      int // Zero for an unpaged list
+	descending bool
+	cursor     *persistence.PageCursor
+}
+
+// queryLimit is the limit of the storage query of a page, one more than the page size tells
+// whether there is a next page. Zero queries everything.
+func (p pageParameters) queryLimit() int {
+	if p.limit == 0 {
+		return 0
+	}
+	return p.limit + 1
+}
+
+// nextPage reports whether entries, as returned by a query limited by queryLimit, go beyond the page.
+func (p pageParameters) nextPage(entries int) bool {
+	return p.limit > 0 && entries > p.limit
+}
+
+// WritePageResponse writes a page of a list endpoint with the cursor of the next page.
+func WritePageResponse(w http.ResponseWriter, code int, data interface{}, nextCursor string) {
+	response := PageResponse{
+		Data:       data,
+		NextCursor: nextCursor,
+	}
+
+	bytes, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		WriteInternalError(w)
+		return
+	}
+
+	w.WriteHeader(code)
+	w.Write(bytes)
+}
+
+// parsePageParameters reads limit, order (asc or desc) and cursor. Without limit and cursor the
+// list is not paged, so clients written before paging existed still get every entry.
+func parsePageParameters(query url.Values) (pageParameters, error) {
+	parameters := pageParameters{}
+	if query.Has("limit") || query.Has("cursor") {
+		parameters.limit = DefaultPageLimit
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > MaxPageLimit {
+			return parameters, fmt.Errorf("limit must be a number between 1 and %d", MaxPageLimit)
+		}
+		parameters.limit = parsed
+	}
+
+	switch query.Get("order") {
+	case "", "asc":
+	case "desc":
+		parameters.descending = true
+	default:
+		return parameters, errors.New("order must be asc or desc")
+	}
+
+	cursor, err := persistence.DecodePageCursor(query.Get("cursor"))
+	if err != nil {
+		return parameters, err
+	}
+	parameters.cursor = cursor
+
+	return parameters, nil
+}
+
+// parseCounter reads an optional non-negative integer query parameter.
+func parseCounter(query url.Values, name string) (*int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	counter, err := strconv.Atoi(value)
+	if err != nil || counter < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative number", name)
+	}
+	return &counter, nil
+}
+
+// parseTime reads an optional RFC 3339 query parameter, the zero time stands for an unset one.
+func parseTime(query url.Values, name string) (time.Time, error) {
+	value := query.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return parsed, nil
+}
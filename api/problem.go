@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
+	"github.com/google/uuid"
+)
+
+// ProblemContentType is the media type of error responses, see RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// RequestIDHeader carries the ID of a request. A valid ID sent by the client is kept, otherwise
+// one is generated. Either way it is returned in this header and in every problem response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength is the longest request ID accepted from a client.
+const maxRequestIDLength = 128
+
+// internalErrorDetail replaces the message of unexpected errors, which is only logged.
+const internalErrorDetail = "the request could not be processed, the server log has the details under the request ID"
+
+// Error codes of problem responses. They are stable, clients should decide on them rather than on
+// the detail message, which may change.
+const (
+	ErrorCodeBadRequest           = "bad_request"            // 400, e.g. malformed JSON or query parameters
+	ErrorCodeValidationFailed     = "validation_failed"      // 422, the body is well-formed but its fields are not
+	ErrorCodeNotFound             = "not_found"              // 404, the device or other record does not exist
+	ErrorCodeMethodNotAllowed     = "method_not_allowed"     // 405
+	ErrorCodeConflict             = "conflict"               // 409, the request contradicts the state of a record
+	ErrorCodeAlreadyExists        = "already_exists"         // 409, a record with the same ID exists
+	ErrorCodeDeviceNotActive      = "device_not_active"      // 409, the device is suspended or decommissioned
+	ErrorCodeDeviceDecommissioned = "device_decommissioned"  // 409, decommissioned devices can no longer be changed
+	ErrorCodeIdempotencyKeyReused = "idempotency_key_reused" // 409, the Idempotency-Key belongs to different data
+	ErrorCodeUnavailable          = "unavailable"            // 503, the feature is disabled or busy, retry later
+	ErrorCodeInternal             = "internal_error"         // 500
+)
+
+// Problem is the error response body, an RFC 7807 problem details object. Type is always
+// about:blank, Code tells the problem apart.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a validation failure of a single request field. Pointer is a JSON Pointer
+// (RFC 6901) into the request body.
+type FieldError struct {
+	Pointer string `json:"pointer"`
+	Detail  string `json:"detail"`
+}
+
+// WriteProblem writes problem as an application/problem+json response. The title, type and
+// request ID are filled in if they are empty.
+func WriteProblem(w http.ResponseWriter, problem Problem) {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+	if problem.RequestID == "" {
+		problem.RequestID = w.Header().Get(RequestIDHeader)
+	}
+
+	bytes, err := json.Marshal(problem)
+	if err != nil {
+		WriteInternalError(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	w.Write(bytes)
+}
+
+// writeValidationProblem rejects a request body whose fields failed validation.
+func writeValidationProblem(w http.ResponseWriter, fieldErrors []FieldError) {
+	details := make([]string, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		details = append(details, fieldError.Detail)
+	}
+
+	WriteProblem(w, Problem{
+		Status: http.StatusUnprocessableEntity,
+		Detail: strings.Join(details, "; "),
+		Code:   ErrorCodeValidationFailed,
+		Errors: fieldErrors,
+	})
+}
+
+// writeInvalidJSON rejects a request body that could not be decoded.
+func writeInvalidJSON(w http.ResponseWriter) {
+	WriteProblem(w, Problem{
+		Status: http.StatusBadRequest,
+		Detail: "Invalid JSON format",
+		Code:   ErrorCodeBadRequest,
+	})
+}
+
+// writeError translates an error of the services or repositories into a problem response: unknown
+// records are 404, devices that may not sign and other conflicts are 409, rejected input is 422.
+// Any other error is a 500.
+func writeError(w http.ResponseWriter, err error) {
+	var invalid *service.InvalidArgumentError
+	problem := Problem{Detail: err.Error()}
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		problem.Status, problem.Code = http.StatusNotFound, ErrorCodeNotFound
+	case errors.Is(err, service.ErrDeviceNotActive):
+		problem.Status, problem.Code = http.StatusConflict, ErrorCodeDeviceNotActive
+	case errors.Is(err, domain.ErrDeviceDecommissioned):
+		problem.Status, problem.Code = http.StatusConflict, ErrorCodeDeviceDecommissioned
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		problem.Status, problem.Code = http.StatusConflict, ErrorCodeIdempotencyKeyReused
+	case errors.Is(err, persistence.ErrAlreadyExists):
+		problem.Status, problem.Code = http.StatusConflict, ErrorCodeAlreadyExists
+	case errors.Is(err, service.ErrConflict):
+		problem.Status, problem.Code = http.StatusConflict, ErrorCodeConflict
+	case errors.As(err, &invalid):
+		problem.Status, problem.Code = http.StatusUnprocessableEntity, ErrorCodeValidationFailed
+	default:
+		// The error may carry storage or key provider internals, clients only get the request ID
+		log.Printf("request %s: %v", w.Header().Get(RequestIDHeader), err)
+		problem.Status, problem.Code = http.StatusInternalServerError, ErrorCodeInternal
+		problem.Detail = internalErrorDetail
+	}
+
+	WriteProblem(w, problem)
+}
+
+// errorCode returns the error code of a problem that is only known by its status.
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrorCodeBadRequest
+	case http.StatusNotFound:
+		return ErrorCodeNotFound
+	case http.StatusMethodNotAllowed:
+		return ErrorCodeMethodNotAllowed
+	case http.StatusConflict:
+		return ErrorCodeConflict
+	case http.StatusUnprocessableEntity:
+		return ErrorCodeValidationFailed
+	case http.StatusServiceUnavailable:
+		return ErrorCodeUnavailable
+	default:
+		return ErrorCodeInternal
+	}
+}
+
+// withRequestID makes sure every request has an ID and returns it in the RequestIDHeader.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		requestID := request.Header.Get(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+
+		response.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(response, request)
+	})
+}
+
+// validRequestID reports whether a request ID sent by a client can be echoed: printable ASCII
+// of a sensible length, so it cannot break the headers or the logs it ends up in.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, character := range requestID {
+		if character < 0x21 || character > 0x7e {
+			return false
+		}
+	}
+	return true
+}
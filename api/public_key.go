@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+)
+
+// JWKSResponse is a JWK Set (RFC 7517) and is served without the usual data envelope.
+type JWKSResponse struct {
+	Keys []crypto.JWK `json:"keys"`
+}
+
+// devicePublicJWK describes the current public key of a device as a JWK, with the device ID as kid.
+func devicePublicJWK(deviceID string, publicKey string) (crypto.JWK, error) {
+	parsed, err := crypto.ParsePublicKey([]byte(publicKey))
+	if err != nil {
+		return crypto.JWK{}, err
+	}
+
+	jwk, err := crypto.NewPublicJWK(parsed)
+	if err != nil {
+		return crypto.JWK{}, err
+	}
+	jwk.Kid = deviceID
+	jwk.Use = "sig"
+	return jwk, nil
+}
+
+// ShowPublicKey exports the current public key of a device in the format named by the format
+// query parameter: pem (the default), jwk or der. The key is written as is, not wrapped in a
+// data envelope, so standard tools can consume the response directly.
+func (s *Server) ShowPublicKey(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	format := request.URL.Query().Get("format")
+	if format == "" {
+		format = crypto.PublicKeyFormatPEM
+	}
+	if format != crypto.PublicKeyFormatPEM && format != crypto.PublicKeyFormatJWK && format != crypto.PublicKeyFormatDER {
+		WriteErrorResponse(response, http.StatusBadRequest, []string{
+			"format must be one of pem, jwk or der",
+		})
+		return
+	}
+
+	device, err := s.DeviceService().GetDevice(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	publicKey, err := crypto.ParsePublicKey([]byte(device.PublicKey))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	var contentType string
+	var body []byte
+	switch format {
+	case crypto.PublicKeyFormatPEM:
+		contentType = "application/x-pem-file"
+		body, err = crypto.MarshalPublicKeyPEM(publicKey)
+	case crypto.PublicKeyFormatDER:
+		contentType = "application/octet-stream"
+		body, err = crypto.MarshalPublicKeyDER(publicKey)
+	case crypto.PublicKeyFormatJWK:
+		var jwk crypto.JWK
+		contentType = "application/jwk+json"
+		jwk, err = devicePublicJWK(device.ID, device.PublicKey)
+		if err == nil {
+			body, err = json.Marshal(jwk)
+		}
+	}
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	response.Header().Set("Content-Type", contentType)
+	response.WriteHeader(http.StatusOK)
+	response.Write(body)
+}
+
+// ShowJWKS publishes the current public key of every active device as a JWK Set, keyed by device ID.
+func (s *Server) ShowJWKS(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	devices, err := s.DeviceRepository.GetAllDevices()
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	jwks := JWKSResponse{Keys: []crypto.JWK{}}
+	for _, device := range devices {
+		if !device.IsActive() {
+			continue
+		}
+
+		jwk, err := devicePublicJWK(device.ID, device.PublicKey)
+		if err != nil {
+			writeError(response, err)
+			return
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	body, err := json.Marshal(jwks)
+	if err != nil {
+		WriteInternalError(response)
+		return
+	}
+
+	response.Header().Set("Content-Type", "application/jwk-set+json")
+	response.WriteHeader(http.StatusOK)
+	response.Write(body)
+}
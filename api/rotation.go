@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+)
+
+type RotateKeyResponse struct {
+	Device            DeviceResponse    `json:"device"`
+	RotationSignature SignatureResponse `json:"rotation_signature"`
+}
+
+// RotateKey gives a device a new key pair. The old key signs a rotation record binding the
+// new public key as the next link of the chain, and is kept in the key history of the device.
+func (s *Server) RotateKey(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	device, rotationRecord, err := s.SigningService().RotateKey(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	WriteAPIResponse(response, http.StatusOK, RotateKeyResponse{
+		Device: wrapDeviceResponse(device),
+		RotationSignature: SignatureResponse{
+			Signature:  rotationRecord.SignatureValue,
+			SignedData: rotationRecord.SignedData,
+		},
+	})
+}
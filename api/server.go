@@ -2,9 +2,17 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto/ca"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
 	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	sqlpersistence "github.com/fiskaly/coding-challenges/signing-service-challenge/persistence/sql"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
 )
 
 // Response is the generic API response container.
@@ -12,36 +20,225 @@ type Response struct {
 	Data interface{} `json:"data"`
 }
 
-// ErrorResponse is the generic error API response container.
-type ErrorResponse struct {
-	Errors []string `json:"errors"`
+// Storage backends that can be selected through Config.Storage.
+const (
+	StorageMemory = "memory"
+	StorageFile   = "file"
+	StorageSQL    = "sql"
+)
+
+// Key providers that can be selected through Config.KeyProvider.
+const (
+	KeyProviderLocal  = "local"
+	KeyProviderRemote = "remote"
+)
+
+// Config holds the parameters used to set up a Server.
+type Config struct {
+	ListenAddress string
+	Storage       string // One of the Storage* constants, defaults to StorageMemory
+	DataDir       string // Directory holding the journal of the file storage
+	SQLDriver     string // database/sql driver name used by the SQL storage
+	SQLDataSource string // Data source name passed to the SQL driver
+
+	MasterKey          string   // Base64 encoded 32 byte key wrapping the device keys, takes precedence over MasterKeyFile
+	MasterKeyFile      string   // File holding the base64 encoded master key
+	PreviousMasterKeys []string // Base64 encoded master keys that were rotated out
+	AllowPlaintextKeys bool     // Lets the file and sql storage keep device private keys unencrypted when no master key is set
+
+	KeyProvider  string // One of the KeyProvider* constants, defaults to KeyProviderLocal
+	SignerSocket string // Unix socket of the signer daemon used by KeyProviderRemote
+
+	CADir string // Directory holding the root key and certificate of the certificate authority, empty disables it
+
+	IdempotencyRetention time.Duration // How long an Idempotency-Key is honored, defaults to DefaultIdempotencyRetention
+	MaxBatchItems        int           // Data items a batch or job may carry, defaults to DefaultMaxBatchItems
+
+	JobWorkers   int           // Workers processing signing jobs, defaults to DefaultJobWorkers
+	JobQueueSize int           // Jobs waiting per worker, defaults to DefaultJobQueueSize
+	JobRetention time.Duration // How long finished jobs can be polled, defaults to DefaultJobRetention
+
+	WebhookMaxAttempts int           // Attempts of a webhook delivery before it is dead-lettered, defaults to DefaultWebhookMaxAttempts
+	WebhookBackoff     time.Duration // Delay before the first retry of a webhook delivery, defaults to DefaultWebhookBackoff
+
+	AllowPrivateEndpoints bool // Lets job callbacks and webhooks reach loopback, link-local and private addresses
 }
 
 // Server manages HTTP requests and dispatches them to the appropriate services.
 type Server struct {
 	listenAddress string
 
-	DeviceRepository persistence.IDeviceRepository
-	SignatureRepository persistence.ISignatureRepository
+	DeviceRepository     persistence.IDeviceRepository
+	SignatureRepository  persistence.ISignatureRepository
+	UnitOfWork           persistence.IUnitOfWork
+	KeyProvider          crypto.KeyProvider         // Holds the device private keys, nil keeps them unencrypted in the process
+	CertificateAuthority *ca.Authority              // Certifies the device public keys, nil disables the certificate endpoints
+	IdempotencyRetention time.Duration              // Zero means DefaultIdempotencyRetention
+	SignatureBroker      *service.SignatureBroker   // Feeds the signature streams, nil disables them
+	JobRepository        persistence.IJobRepository // Keeps the signing jobs, nil disables them
+	JobRetention         time.Duration              // Zero means DefaultJobRetention
+	MaxBatchItems        int                        // Zero means DefaultMaxBatchItems
+
+	WebhookRepository persistence.IWebhookRepository // Outbox of the webhook deliveries, nil disables webhooks
+
+	AllowPrivateEndpoints bool // Lets job callbacks and webhooks reach loopback, link-local and private addresses
+
+	jobs     *jobQueue          // Nil until StartJobWorkers was called
+	webhooks *webhookDispatcher // Nil until StartWebhookDispatcher was called
 }
 
 // NewServer is a factory to instantiate a new Server.
-func NewServer(listenAddress string) *Server {
+func NewServer(config Config) (*Server, error) {
+	server := &Server{
+		listenAddress:         config.ListenAddress,
+		IdempotencyRetention:  config.IdempotencyRetention,
+		JobRetention:          config.JobRetention,
+		MaxBatchItems:         config.MaxBatchItems,
+		AllowPrivateEndpoints: config.AllowPrivateEndpoints,
+		SignatureBroker:       service.NewSignatureBroker(service.DefaultStreamBufferSize),
+		// TODO: add services / further dependencies here ...
+	}
 
 	//Setup persistence layer
-	deviceRepository := persistence.NewDeviceRepository()
-	signatureRepository := persistence.NewSignatureRepository()
+	switch config.Storage {
+	case "", StorageMemory:
+		server.DeviceRepository = persistence.NewDeviceRepository()
+		server.SignatureRepository = persistence.NewSignatureRepository()
+		server.JobRepository = persistence.NewJobRepository()
+		server.WebhookRepository = persistence.NewWebhookRepository()
+		server.UnitOfWork = persistence.NewUnitOfWork(server.DeviceRepository, server.SignatureRepository, server.JobRepository, server.WebhookRepository)
+	case StorageFile:
+		store, err := persistence.NewFileStore(config.DataDir)
+		if err != nil {
+			return nil, err
+		}
+		server.DeviceRepository = store
+		server.SignatureRepository = store
+		server.UnitOfWork = store
+		server.JobRepository = store
+		server.WebhookRepository = store
+	case StorageSQL:
+		store, err := sqlpersistence.Open(config.SQLDriver, config.SQLDataSource)
+		if err != nil {
+			return nil, err
+		}
+		server.DeviceRepository = store
+		server.SignatureRepository = store
+		server.UnitOfWork = store
+		server.JobRepository = store
+		server.WebhookRepository = store
+	default:
+		return nil, fmt.Errorf("unsupported storage: %s", config.Storage)
+	}
 
-	return &Server{
-		listenAddress: listenAddress,
-		DeviceRepository: deviceRepository,
-		SignatureRepository: signatureRepository,
-		// TODO: add services / further dependencies here ...
+	keyProvider, err := newKeyProvider(config)
+	if err != nil {
+		return nil, err
+	}
+	server.KeyProvider = keyProvider
+
+	certificateAuthority, err := newCertificateAuthority(config)
+	if err != nil {
+		return nil, err
+	}
+	server.CertificateAuthority = certificateAuthority
+
+	// Move keys sealed under a rotated master key over to the current one
+	if _, err := server.RewrapDeviceKeys(); err != nil {
+		return nil, err
+	}
+
+	if err := server.StartJobWorkers(config.JobWorkers, config.JobQueueSize); err != nil {
+		return nil, err
+	}
+	server.StartWebhookDispatcher(config.WebhookMaxAttempts, config.WebhookBackoff)
+
+	return server, nil
+}
+
+// Stop stops the job workers and the webhook dispatcher, waiting for the jobs and deliveries
+// they are working on. Queued jobs and pending deliveries stay in the storage, with the file
+// or sql storage the next start picks them up.
+func (s *Server) Stop() {
+	if s.jobs != nil {
+		s.jobs.stop()
+	}
+	if s.webhooks != nil {
+		s.webhooks.stop()
+	}
+}
+
+// DeviceService returns the transport-independent service behind the device endpoints, sharing
+// the storage and keys of the Server. Its hooks announce the device changes to the webhooks and
+// keep the device certificates in line with their keys and statuses, for the HTTP API and for the gRPC API serving the same service.
+func (s *Server) DeviceService() *service.DeviceService {
+	events := s.eventOutbox()
+	return &service.DeviceService{
+		DeviceRepository:    s.DeviceRepository,
+		UnitOfWork:          s.UnitOfWork,
+		KeyProvider:         s.KeyProvider,
+		OnDeviceCreated:     deviceHooks(events.DeviceCreated, s.issueDeviceCertificate),
+		OnDeviceActivated:   s.issueDeviceCertificate,
+		OnDeviceDeactivated: deviceHooks(events.DeviceDeactivated, s.revokeDeviceCertificate),
 	}
 }
 
-// Run registers all HandlerFuncs for the existing HTTP routes and starts the Server.
+// SigningService returns the transport-independent service behind the signing endpoints, sharing
+// the storage, keys and signature streams of the Server. Its hooks announce the signatures to the
+// webhooks, for the HTTP API and for the gRPC API serving the same service.
+func (s *Server) SigningService() *service.SigningService {
+	events := s.eventOutbox()
+	return &service.SigningService{
+		DeviceRepository:     s.DeviceRepository,
+		SignatureRepository:  s.SignatureRepository,
+		UnitOfWork:           s.UnitOfWork,
+		KeyProvider:          s.KeyProvider,
+		IdempotencyRetention: s.IdempotencyRetention,
+		Broker:               s.SignatureBroker,
+		JobRepository:        s.JobRepository,
+		OnSignatures:         events.SignaturesCreated,
+		OnJobFinished:        s.jobFinished,
+		OnKeyRotated:         s.issueDeviceCertificate,
+	}
+}
+
+// deviceHooks chains device hooks, they run in order until one fails.
+func deviceHooks(hooks ...func(tx persistence.ITransaction, device *domain.Device) error) func(tx persistence.ITransaction, device *domain.Device) error {
+	return func(tx persistence.ITransaction, device *domain.Device) error {
+		for _, hook := range hooks {
+			if err := hook(tx, device); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// eventOutbox puts the events of the services into the webhook outbox, with the records in the
+// shape the HTTP API returns them.
+func (s *Server) eventOutbox() *service.EventOutbox {
+	events := &service.EventOutbox{
+		WebhookRepository: s.WebhookRepository,
+		DeviceData: func(device *domain.Device) interface{} {
+			return wrapDeviceResponse(device)
+		},
+		SignatureData: func(signature *domain.Signature) interface{} {
+			return wrapSignatureResponse(signature)
+		},
+	}
+	if s.webhooks != nil {
+		events.Notify = s.webhooks.notify
+	}
+	return events
+}
+
+// Run starts the Server on its listen address.
 func (s *Server) Run() error {
+	return http.ListenAndServe(s.listenAddress, s.Handler())
+}
+
+// Handler registers all HandlerFuncs for the existing HTTP routes. Every request gets a request ID.
+func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 
 	mux.Handle("/api/v0/health", http.HandlerFunc(s.Health))
@@ -49,39 +246,58 @@ func (s *Server) Run() error {
 	mux.Handle("/api/v0/sign-transaction", http.HandlerFunc(s.SignTransaction))
 	mux.Handle("/api/v0/signatures", http.HandlerFunc(s.ShowAllSignaturesByDevice))
 	mux.Handle("/api/v0/devices", http.HandlerFunc(s.ShowAllDevices))
+	mux.Handle("/api/v0/devices/{id}", http.HandlerFunc(s.Device))
+	mux.Handle("/api/v0/devices/{id}/signatures/stream", http.HandlerFunc(s.StreamSignatures))
+	mux.Handle("/api/v0/devices/{id}/verify-chain", http.HandlerFunc(s.VerifyChain))
+	mux.Handle("/api/v0/devices/{id}/rotate-key", http.HandlerFunc(s.RotateKey))
+	mux.Handle("/api/v0/devices/{id}/sign-batch", http.HandlerFunc(s.SignBatch))
+	mux.Handle("/api/v0/devices/{id}/public-key", http.HandlerFunc(s.ShowPublicKey))
+	mux.Handle("/api/v0/devices/{id}/certificate", http.HandlerFunc(s.ShowDeviceCertificate))
+	mux.Handle("/api/v0/ca/certificate", http.HandlerFunc(s.ShowCACertificate))
+	mux.Handle("/api/v0/ca/crl", http.HandlerFunc(s.ShowCRL))
+	mux.Handle("/api/v0/jobs", http.HandlerFunc(s.SubmitJob))
+	mux.Handle("/api/v0/jobs/{id}", http.HandlerFunc(s.ShowJob))
+	mux.Handle("/api/v0/webhooks", http.HandlerFunc(s.Webhooks))
+	mux.Handle("/api/v0/webhooks/{id}", http.HandlerFunc(s.Webhook))
+	mux.Handle("/api/v0/webhooks/dead-letters", http.HandlerFunc(s.ShowDeadLetters))
+	mux.Handle("/api/v0/webhooks/dead-letters/{id}/retry", http.HandlerFunc(s.RetryDeadLetter))
+	mux.Handle("/api/v0/verify", http.HandlerFunc(s.VerifySignature))
+	mux.Handle("/api/v0/algorithms", http.HandlerFunc(s.ShowAllAlgorithms))
+	mux.Handle("/.well-known/jwks.json", http.HandlerFunc(s.ShowJWKS))
 	// TODO: register further HandlerFuncs here ...
 
-	return http.ListenAndServe(s.listenAddress, mux)
+	return withRequestID(mux)
 }
 
-// WriteInternalError writes a default internal error message as an HTTP response.
+// WriteInternalError writes a default internal error problem as an HTTP response. It does not
+// go through WriteProblem, which falls back to it if a problem cannot be encoded.
 func WriteInternalError(w http.ResponseWriter) {
+	bytes, _ := json.Marshal(Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(http.StatusInternalServerError),
+		Status:    http.StatusInternalServerError,
+		Code:      ErrorCodeInternal,
+		RequestID: w.Header().Get(RequestIDHeader),
+	})
+
+	w.Header().Set("Content-Type", ProblemContentType)
 	w.WriteHeader(http.StatusInternalServerError)
-	w.Write([]byte(http.StatusText(http.StatusInternalServerError)))
+	w.Write(bytes)
 }
 
 // WriteErrorResponse takes an HTTP status code and a slice of errors
-// and writes those as an HTTP error response in a structured format.
+// and writes those as a problem response with the default error code of the status.
 func WriteErrorResponse(w http.ResponseWriter, code int, errors []string) {
-	w.WriteHeader(code)
-
-	errorResponse := ErrorResponse{
-		Errors: errors,
-	}
-
-	bytes, err := json.Marshal(errorResponse)
-	if err != nil {
-		WriteInternalError(w)
-	}
-
-	w.Write(bytes)
+	WriteProblem(w, Problem{
+		Status: code,
+		Detail: strings.Join(errors, "; "),
+		Code:   errorCode(code),
+	})
 }
 
 // WriteAPIResponse takes an HTTP status code and a generic data struct
 // and writes those as an HTTP response in a structured format.
 func WriteAPIResponse(w http.ResponseWriter, code int, data interface{}) {
-	w.WriteHeader(code)
-
 	response := Response{
 		Data: data,
 	}
@@ -89,7 +305,9 @@ func WriteAPIResponse(w http.ResponseWriter, code int, data interface{}) {
 	bytes, err := json.MarshalIndent(response, "", "  ")
 	if err != nil {
 		WriteInternalError(w)
+		return
 	}
 
+	w.WriteHeader(code)
 	w.Write(bytes)
 }
@@ -1,14 +1,14 @@
 package api
 
 import (
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
-	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
 	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
-	"github.com/google/uuid"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
 )
 
 type SignTransactionRequest struct {
@@ -31,63 +31,39 @@ func (s *Server) SignTransaction(response http.ResponseWriter, request *http.Req
 
 	var req SignTransactionRequest
 	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
-		WriteErrorResponse(response, http.StatusBadRequest, []string{
-			"Invalid JSON format",
-		})
+		writeInvalidJSON(response)
 		return
 	}
 
 	// Validate the request
 	if validationErrors := validateRequest(req); validationErrors != nil {
-		WriteErrorResponse(response, http.StatusBadRequest, validationErrors)
-		return
-	}
-
-	device, err := s.DeviceRepository.GetDevice(req.DeviceID)
-	if err != nil {
-		WriteErrorResponse(response, http.StatusInternalServerError, []string{
-			err.Error(),
-		})
+		writeValidationProblem(response, validationErrors)
 		return
 	}
 
-	//For locking per device to avoid race conditions when incrementing the signature counter
-	deviceMutex := s.DeviceRepository.GetDeviceMutex(req.DeviceID)
-	deviceMutex.Lock()
-	defer deviceMutex.Unlock()
-
-	signatureResponse, signatureCounter, err := s.signData(device, req.Data)
-	if err != nil {
-		WriteErrorResponse(response, http.StatusInternalServerError, []string{
-			err.Error(),
+	idempotencyKey := request.Header.Get(IdempotencyKeyHeader)
+	if len(idempotencyKey) > service.MaxIdempotencyKeyLength {
+		WriteErrorResponse(response, http.StatusBadRequest, []string{
+			fmt.Sprintf("%s must not be longer than %d characters", IdempotencyKeyHeader, service.MaxIdempotencyKeyLength),
 		})
 		return
 	}
 
-	// Save the signature to the repository
-	signatureRecord := &domain.Signature{
-		ID:               uuid.New().String(),
-		DeviceID:         device.ID,
-		SignatureCounter: signatureCounter,
-		SignatureValue:   signatureResponse.Signature,
-	}
-	err = s.SignatureRepository.CreateSignature(signatureRecord)
+	signatureRecord, replayed, err := s.SigningService().Sign(req.DeviceID, req.Data, idempotencyKey)
 	if err != nil {
-		WriteErrorResponse(response, http.StatusInternalServerError, []string{
-			err.Error(),
-		})
+		writeError(response, err)
 		return
 	}
 
-	err = s.DeviceRepository.IncrementSignatureCounter(device.ID)
-	if err != nil {
-		WriteErrorResponse(response, http.StatusInternalServerError, []string{
-			err.Error(),
-		})
-		return
+	// A retry is answered with the original signature, even if the device was suspended since
+	if replayed {
+		response.Header().Set(IdempotentReplayedHeader, "true")
 	}
 
-	WriteAPIResponse(response, http.StatusOK, signatureResponse)
+	WriteAPIResponse(response, http.StatusOK, SignatureResponse{
+		Signature:  signatureRecord.SignatureValue,
+		SignedData: signatureRecord.SignedData,
+	})
 }
 
 func (s *Server) ShowAllSignaturesByDevice(response http.ResponseWriter, request *http.Request) {
@@ -106,70 +82,55 @@ func (s *Server) ShowAllSignaturesByDevice(response http.ResponseWriter, request
 		return
 	}
 	
-	signatures, err := s.SignatureRepository.GetAllSignaturesByDeviceID(deviceID)
+	parameters, err := parsePageParameters(request.URL.Query())
 	if err != nil {
-		WriteErrorResponse(response, http.StatusInternalServerError, []string{
+		WriteErrorResponse(response, http.StatusBadRequest, []string{
 			err.Error(),
 		})
 		return
 	}
 
-	WriteAPIResponse(response, http.StatusOK, wrapSignatureListResponse(signatures))
-}
-
-func (s *Server) signData(device *domain.Device, data string) (SignatureResponse, int, error) {
-	var signer crypto.Signer
-	var err error
-
-	// Create the appropriate signer based on algorithm
-	switch device.Algorithm {
-	case "RSA":
-		marshaler := crypto.NewRSAMarshaler()
-		keyPair, err := marshaler.Unmarshal([]byte(device.PrivateKey))
-		if err != nil {
-			return SignatureResponse{}, 0, err
-		}
-		signer = crypto.NewRSASigner(keyPair)
-	case "ECC":
-		marshaler := crypto.NewECCMarshaler()
-		keyPair, err := marshaler.Decode([]byte(device.PrivateKey))
-		if err != nil {
-			return SignatureResponse{}, 0, err
-		}
-		signer = crypto.NewECCSigner(keyPair)
-	default:
-		return SignatureResponse{}, 0, fmt.Errorf("unsupported algorithm: %s", device.Algorithm)
-	}
-
-	// Build the raw string format
-	var rawStringFormat string
-	var signatureCounter int
-
-	if device.SignatureCounter == 0 {
-		signatureCounter = 0
-		rawStringFormat = fmt.Sprintf("0_%s_%s", data, base64.StdEncoding.EncodeToString([]byte(device.ID)))
-	} else {
-		latestSignature, err := s.SignatureRepository.GetLatestSignature(device.ID)
-		if err != nil {
-			return SignatureResponse{}, 0, err
+	query := persistence.SignatureQuery{
+		DeviceID:   deviceID,
+		Limit:      parameters.queryLimit(),
+		Descending: parameters.descending,
+	}
+	var filterErrors []string
+	if query.FromCounter, err = parseCounter(request.URL.Query(), "from_counter"); err != nil {
+		filterErrors = append(filterErrors, err.Error())
+	}
+	if query.ToCounter, err = parseCounter(request.URL.Query(), "to_counter"); err != nil {
+		filterErrors = append(filterErrors, err.Error())
+	}
+	if query.CreatedFrom, err = parseTime(request.URL.Query(), "from"); err != nil {
+		filterErrors = append(filterErrors, err.Error())
+	}
+	if query.CreatedTo, err = parseTime(request.URL.Query(), "to"); err != nil {
+		filterErrors = append(filterErrors, err.Error())
+	}
+	if parameters.cursor != nil {
+		if query.AfterCounter, err = parameters.cursor.SignatureCounter(); err != nil {
+			filterErrors = append(filterErrors, err.Error())
 		}
-		signatureCounter = latestSignature.SignatureCounter + 1
-		rawStringFormat = fmt.Sprintf("%d_%s_%s", latestSignature.SignatureCounter+1, data, latestSignature.SignatureValue)
+	}
+	if filterErrors != nil {
+		WriteErrorResponse(response, http.StatusBadRequest, filterErrors)
+		return
 	}
 
-	// Sign the data
-	signature, err := signer.Sign([]byte(rawStringFormat))
+	signatures, err := s.SigningService().ListSignatures(query)
 	if err != nil {
-		return SignatureResponse{}, 0, err
+		writeError(response, err)
+		return
 	}
 
-	// Create the response
-	signatureResponse := SignatureResponse{
-		Signature:  base64.StdEncoding.EncodeToString(signature),
-		SignedData: rawStringFormat,
+	var nextCursor string
+	if parameters.nextPage(len(signatures)) {
+		signatures = signatures[:parameters.limit]
+		nextCursor = persistence.EncodeSignaturePageCursor(signatures[len(signatures)-1])
 	}
 
-	return signatureResponse, signatureCounter, nil
+	WritePageResponse(response, http.StatusOK, wrapSignatureListResponse(signatures), nextCursor)
 }
 
 type GetSignatureResponse struct {
@@ -177,17 +138,31 @@ type GetSignatureResponse struct {
 	DeviceID string `json:"device_id"`
 	SignatureCounter int `json:"signature_counter"`
 	SignatureValue string `json:"signature_value"`
+	SignedData string `json:"signed_data"`
+	Data string `json:"data"`
+	Algorithm string `json:"algorithm"`
+	PreviousSignatureID string `json:"previous_signature_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 func wrapSignatureListResponse(signatures []*domain.Signature) []GetSignatureResponse {
 	signatureResponses := make([]GetSignatureResponse, 0, len(signatures))
 	for _, signature := range signatures {
-		signatureResponses = append(signatureResponses, GetSignatureResponse{
-			ID: signature.ID,
-			DeviceID: signature.DeviceID,
-			SignatureCounter: signature.SignatureCounter,
-			SignatureValue: signature.SignatureValue,
-		})
+		signatureResponses = append(signatureResponses, wrapSignatureResponse(signature))
 	}
 	return signatureResponses
-}
\ No newline at end of file
+}
+
+func wrapSignatureResponse(signature *domain.Signature) GetSignatureResponse {
+	return GetSignatureResponse{
+		ID: signature.ID,
+		DeviceID: signature.DeviceID,
+		SignatureCounter: signature.SignatureCounter,
+		SignatureValue: signature.SignatureValue,
+		SignedData: signature.SignedData,
+		Data: signature.Data,
+		Algorithm: signature.Algorithm,
+		PreviousSignatureID: signature.PreviousSignatureID,
+		CreatedAt: signature.CreatedAt,
+	}
+}
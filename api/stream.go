@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+const (
+	// LastEventIDHeader carries the counter of the last signature a stream client received.
+	LastEventIDHeader = "Last-Event-ID"
+
+	streamHeartbeatInterval = 15 * time.Second
+)
+
+// StreamSignatures pushes the signatures of a device as Server-Sent Events while they are created.
+// The event ID is the signature counter, a client sending Last-Event-ID first receives the stored
+// signatures after that counter. Without it the stream starts with the next signature.
+func (s *Server) StreamSignatures(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	if s.SignatureBroker == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"signature streams are not enabled",
+		})
+		return
+	}
+
+	flusher, ok := response.(http.Flusher)
+	if !ok {
+		WriteErrorResponse(response, http.StatusInternalServerError, []string{
+			"streaming is not supported",
+		})
+		return
+	}
+
+	device, err := s.DeviceService().GetDevice(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	// The counter of the last signature the client has, -1 if it has none
+	lastCounter := device.SignatureCounter - 1
+	if lastEventID := request.Header.Get(LastEventIDHeader); lastEventID != "" {
+		lastCounter, err = strconv.Atoi(lastEventID)
+		if err != nil || lastCounter < -1 {
+			WriteErrorResponse(response, http.StatusBadRequest, []string{
+				fmt.Sprintf("%s must be a signature counter", LastEventIDHeader),
+			})
+			return
+		}
+	}
+
+	response.Header().Set("Content-Type", "text/event-stream")
+	response.Header().Set("Cache-Control", "no-cache")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Events and heartbeats are written from different goroutines
+	var writeMutex sync.Mutex
+	stopHeartbeat := make(chan struct{})
+	heartbeatStopped := make(chan struct{})
+	go func() {
+		defer close(heartbeatStopped)
+		heartbeat := time.NewTicker(streamHeartbeatInterval)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-stopHeartbeat:
+				return
+			case <-heartbeat.C:
+				writeMutex.Lock()
+				if _, err := fmt.Fprint(response, ": keep-alive\n\n"); err == nil {
+					flusher.Flush()
+				}
+				writeMutex.Unlock()
+			}
+		}
+	}()
+	defer func() {
+		close(stopHeartbeat)
+		<-heartbeatStopped
+	}()
+
+	// Ends when the client disconnects, the response is under way so errors cannot be reported
+	s.SigningService().WatchSignatures(request.Context(), device.ID, lastCounter, func(signature *domain.Signature) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+
+		if err := writeSignatureEvent(response, signature); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
+}
+
+func writeSignatureEvent(response http.ResponseWriter, signature *domain.Signature) error {
+	data, err := json.Marshal(wrapSignatureListResponse([]*domain.Signature{signature})[0])
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(response, "id: %d\nevent: signature\ndata: %s\n\n", signature.SignatureCounter, data)
+	return err
+}
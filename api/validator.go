@@ -2,7 +2,10 @@ package api
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -10,23 +13,79 @@ var validate *validator.Validate
 
 func init() {
 	validate = validator.New(validator.WithRequiredStructEnabled())
+
+	// Namespaces use the JSON names, they become the pointers of the validation failures
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// The algorithm tag accepts every algorithm registered in the crypto package
+	validate.RegisterValidation("algorithm", func(field validator.FieldLevel) bool {
+		_, err := crypto.LookupAlgorithm(field.Field().String())
+		return err == nil
+	})
 }
 
-// validateRequest validates a struct and returns formatted error messages
-func validateRequest(s interface{}) []string {
+// validateRequest validates a struct and returns a formatted error message for every field
+// that failed, together with a JSON Pointer to the field in the request body
+func validateRequest(s interface{}) []FieldError {
 	if err := validate.Struct(s); err != nil {
-		var validationErrors []string
+		var validationErrors []FieldError
 		for _, err := range err.(validator.ValidationErrors) {
+			var message string
 			switch err.Tag() {
 			case "required":
-				validationErrors = append(validationErrors, fmt.Sprintf("%s is required", err.Field()))
+				message = fmt.Sprintf("%s is required", err.StructField())
 			case "oneof":
-				validationErrors = append(validationErrors, fmt.Sprintf("%s must be one of: %s", err.Field(), err.Param()))
+				message = fmt.Sprintf("%s must be one of: %s", err.StructField(), err.Param())
+			case "min":
+				message = boundMessage(err, "at least")
+			case "max":
+				message = boundMessage(err, "at most")
+			case "algorithm":
+				message = fmt.Sprintf("%s must be one of: %s", err.StructField(), strings.Join(crypto.Algorithms(), " "))
 			default:
-				validationErrors = append(validationErrors, fmt.Sprintf("%s is invalid", err.Field()))
+				message = fmt.Sprintf("%s is invalid", err.StructField())
 			}
+			validationErrors = append(validationErrors, FieldError{
+				Pointer: jsonPointer(err.Namespace()),
+				Detail:  message,
+			})
 		}
 		return validationErrors
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// boundMessage describes a failed min or max tag, which bounds the length of slices, maps and
+// strings and the value of numbers.
+func boundMessage(err validator.FieldError, bound string) string {
+	switch err.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return fmt.Sprintf("%s must contain %s %s items", err.StructField(), bound, err.Param())
+	case reflect.String:
+		return fmt.Sprintf("%s must be %s %s characters long", err.StructField(), bound, err.Param())
+	default:
+		return fmt.Sprintf("%s must be %s %s", err.StructField(), bound, err.Param())
+	}
+}
+
+// jsonPointer turns the namespace of a validated field, e.g. SignBatchRequest.data[1], into a
+// JSON Pointer to the field in the request body, e.g. /data/1.
+func jsonPointer(namespace string) string {
+	_, path, _ := strings.Cut(namespace, ".")
+
+	var pointer strings.Builder
+	for _, segment := range strings.FieldsFunc(path, func(character rune) bool {
+		return character == '.' || character == '[' || character == ']'
+	}) {
+		segment = strings.ReplaceAll(segment, "~", "~0")
+		segment = strings.ReplaceAll(segment, "/", "~1")
+		pointer.WriteString("/" + segment)
+	}
+	return pointer.String()
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+)
+
+type VerifySignatureRequest struct {
+	DeviceID   string `json:"device_id" validate:"required"`
+	SignedData string `json:"signed_data" validate:"required"`
+	Signature  string `json:"signature" validate:"required"`
+}
+
+type VerifySignatureResponse struct {
+	Valid  bool   `json:"valid"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// VerifySignature checks a base64 encoded signature over signed_data against the public key of a device.
+func (s *Server) VerifySignature(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	var req VerifySignatureRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+		writeInvalidJSON(response)
+		return
+	}
+
+	// Validate the request
+	if validationErrors := validateRequest(req); validationErrors != nil {
+		writeValidationProblem(response, validationErrors)
+		return
+	}
+
+	device, err := s.DeviceService().GetDevice(req.DeviceID)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	// Pick the key that was active for the counter the signed data starts with
+	keys, err := newChainKeys(device)
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	result := VerifySignatureResponse{Valid: true}
+	verifier := keys.forCounter(device.SignatureCounter).verifier
+	if counter, err := strconv.Atoi(strings.SplitN(req.SignedData, "_", 2)[0]); err == nil {
+		verifier = keys.forCounter(counter).verifier
+	}
+
+	if err := verifySignatureValue(verifier, req.SignedData, req.Signature); err != nil {
+		result.Valid = false
+		result.Reason = err.Error()
+		if errors.Is(err, crypto.ErrInvalidSignature) {
+			result.Reason = "signature does not match the signed data for this device"
+		}
+	}
+
+	WriteAPIResponse(response, http.StatusOK, result)
+}
@@ -0,0 +1,506 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/google/uuid"
+)
+
+const (
+	// WebhookSignatureHeader carries "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">" keyed with the webhook secret.
+	WebhookSignatureHeader = "Webhook-Signature"
+	// WebhookEventHeader names the event of a delivery.
+	WebhookEventHeader = "Webhook-Event"
+	// WebhookIDHeader carries the event ID, it stays the same across retries.
+	WebhookIDHeader = "Webhook-ID"
+
+	// DefaultWebhookMaxAttempts is how often a delivery is attempted before it is dead-lettered unless Config says otherwise.
+	DefaultWebhookMaxAttempts = 10
+	// DefaultWebhookBackoff is the delay before the first retry unless Config says otherwise, it doubles with every attempt.
+	DefaultWebhookBackoff = 5 * time.Second
+
+	maxWebhookBackoff      = time.Hour
+	webhookPollInterval    = time.Second
+	webhookBatchSize       = 100
+	webhookConcurrency     = 4
+	webhookTimeout         = 10 * time.Second
+	minWebhookSecretLength = 16
+)
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" validate:"required"`
+	DeviceID string `json:"device_id"`
+	Events []string `json:"events" validate:"dive,oneof=device.created device.deactivated signature.created"`
+	Secret string `json:"secret"`
+}
+
+type WebhookResponse struct {
+	ID string `json:"id"`
+	URL string `json:"url"`
+	DeviceID string `json:"device_id,omitempty"`
+	Events []string `json:"events"`
+	Secret string `json:"secret,omitempty"` // Only returned when the webhook is created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeliveryResponse struct {
+	ID string `json:"id"`
+	WebhookID string `json:"webhook_id,omitempty"`
+	URL string `json:"url,omitempty"` // Callback URL of a job callback
+	EventID string `json:"event_id"`
+	Event string `json:"event"`
+	Status domain.DeliveryStatus `json:"status"`
+	Attempts int `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt time.Time `json:"created_at"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func wrapWebhookResponse(webhook *domain.Webhook) WebhookResponse {
+	events := webhook.Events
+	if len(events) == 0 {
+		events = domain.Events
+	}
+	return WebhookResponse{
+		ID: webhook.ID,
+		URL: webhook.URL,
+		DeviceID: webhook.DeviceID,
+		Events: events,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
+
+func wrapDeliveryResponse(delivery *domain.WebhookDelivery) DeliveryResponse {
+	return DeliveryResponse{
+		ID: delivery.ID,
+		WebhookID: delivery.WebhookID,
+		URL: delivery.URL,
+		EventID: delivery.EventID,
+		Event: delivery.Event,
+		Status: delivery.Status,
+		Attempts: delivery.Attempts,
+		LastError: delivery.LastError,
+		NextAttemptAt: delivery.NextAttemptAt,
+		CreatedAt: delivery.CreatedAt,
+		Payload: json.RawMessage(delivery.Payload),
+	}
+}
+
+// webhookDispatcher works through the outbox. Deliveries that fail are retried with
+// exponential backoff until they run out of attempts, then they are dead-lettered.
+type webhookDispatcher struct {
+	repository persistence.IWebhookRepository
+	maxAttempts int
+	backoff time.Duration
+	wake chan struct{}
+	client *http.Client // Posts the deliveries to the webhooks and callback URLs
+	stopped chan struct{} // Closed once the dispatcher is told to stop
+	done chan struct{} // Closed once the dispatcher stopped
+	stopOnce sync.Once
+}
+
+// StartWebhookDispatcher starts delivering the outbox. Until it is called events are
+// collected in the outbox but not delivered.
+func (s *Server) StartWebhookDispatcher(maxAttempts int, backoff time.Duration) {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultWebhookMaxAttempts
+	}
+	if backoff <= 0 {
+		backoff = DefaultWebhookBackoff
+	}
+	if s.WebhookRepository == nil {
+		s.WebhookRepository = persistence.NewWebhookRepository()
+	}
+
+	dispatcher := &webhookDispatcher{
+		repository: s.WebhookRepository,
+		maxAttempts: maxAttempts,
+		backoff: backoff,
+		wake: make(chan struct{}, 1),
+		client: newOutboundClient(webhookTimeout, s.AllowPrivateEndpoints),
+		stopped: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go dispatcher.run()
+	s.webhooks = dispatcher
+}
+
+// notify wakes the dispatcher up, e.g. because new deliveries are due.
+func (d *webhookDispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stop tells the dispatcher to stop and waits for the attempts in flight. Pending deliveries
+// stay in the outbox.
+func (d *webhookDispatcher) stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopped)
+	})
+	<-d.done
+}
+
+func (d *webhookDispatcher) run() {
+	defer close(d.done)
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.dispatchDue()
+		select {
+		case <-d.stopped:
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// dispatchDue attempts every delivery that is due, a few at a time.
+func (d *webhookDispatcher) dispatchDue() {
+	for {
+		deliveries, err := d.repository.ListDeliveries(persistence.DeliveryQuery{
+			Status: domain.DeliveryStatusPending,
+			DueBy: time.Now(),
+			Limit: webhookBatchSize,
+		})
+		if err != nil {
+			log.Printf("webhooks: %v", err)
+			return
+		}
+
+		var wait sync.WaitGroup
+		slots := make(chan struct{}, webhookConcurrency)
+		for _, delivery := range deliveries {
+			wait.Add(1)
+			slots <- struct{}{}
+			go func() {
+				defer wait.Done()
+				d.attempt(delivery)
+				<-slots
+			}()
+		}
+		wait.Wait()
+
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+		select {
+		case <-d.stopped:
+			return
+		default:
+		}
+	}
+}
+
+// attempt posts a delivery to its webhook, or a job callback to its URL, and records the outcome.
+func (d *webhookDispatcher) attempt(delivery *domain.WebhookDelivery) {
+	var err error
+	if delivery.IsCallback() {
+		err = d.post(delivery.URL, "", delivery)
+	} else {
+		webhook, getErr := d.repository.GetWebhook(delivery.WebhookID)
+		if getErr != nil {
+			// The webhook was deleted together with its deliveries meanwhile
+			return
+		}
+		err = d.post(webhook.URL, webhook.Secret, delivery)
+	}
+	if err == nil {
+		if err := d.repository.DeleteDelivery(delivery.ID); err != nil {
+			log.Printf("webhooks: delivery %s: %v", delivery.ID, err)
+		}
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.maxAttempts {
+		delivery.Status = domain.DeliveryStatusDead
+	} else {
+		delay := d.retryDelay(delivery.Attempts)
+		delivery.NextAttemptAt = time.Now().UTC().Add(delay)
+		time.AfterFunc(delay, d.notify)
+	}
+	if err := d.repository.UpdateDelivery(delivery); err != nil {
+		log.Printf("webhooks: delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// retryDelay returns how long to wait after the given number of failed attempts.
+func (d *webhookDispatcher) retryDelay(attempts int) time.Duration {
+	delay := d.backoff
+	for i := 1; i < attempts && delay < maxWebhookBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxWebhookBackoff)
+}
+
+// post posts the payload of a delivery to url, any status but 2xx is a failure. Deliveries
+// are signed with secret, job callbacks have none.
+func (d *webhookDispatcher) post(url string, secret string, delivery *domain.WebhookDelivery) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(WebhookEventHeader, delivery.Event)
+	request.Header.Set(WebhookIDHeader, delivery.EventID)
+	if secret != "" {
+		request.Header.Set(WebhookSignatureHeader, signWebhookPayload(secret, time.Now(), []byte(delivery.Payload)))
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("%s answered %s", url, response.Status)
+	}
+	return nil
+}
+
+// signWebhookPayload builds the WebhookSignatureHeader value. The timestamp is part of the
+// signed content, so receivers can reject replays of old deliveries.
+func signWebhookPayload(secret string, timestamp time.Time, payload []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(payload)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Webhooks serves the webhook registry, GET lists the webhooks and POST registers one.
+func (s *Server) Webhooks(response http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		s.ShowAllWebhooks(response, request)
+	case http.MethodPost:
+		s.CreateWebhook(response, request)
+	default:
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+	}
+}
+
+func (s *Server) CreateWebhook(response http.ResponseWriter, request *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(request.Body).Decode(&req); err != nil {
+		writeInvalidJSON(response)
+		return
+	}
+
+	// Validate the request
+	if validationErrors := validateRequest(req); validationErrors != nil {
+		writeValidationProblem(response, validationErrors)
+		return
+	}
+	if err := validateHTTPURL("URL", req.URL, s.AllowPrivateEndpoints); err != nil {
+		writeValidationProblem(response, []FieldError{
+			{Pointer: "/url", Detail: err.Error()},
+		})
+		return
+	}
+	if req.Secret != "" && len(req.Secret) < minWebhookSecretLength {
+		writeValidationProblem(response, []FieldError{
+			{Pointer: "/secret", Detail: fmt.Sprintf("Secret must be at least %d characters long", minWebhookSecretLength)},
+		})
+		return
+	}
+
+	if s.WebhookRepository == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"webhooks are not enabled",
+		})
+		return
+	}
+
+	if req.DeviceID != "" {
+		if _, err := s.DeviceService().GetDevice(req.DeviceID); err != nil {
+			writeError(response, err)
+			return
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		secret, err = newWebhookSecret()
+		if err != nil {
+			writeError(response, err)
+			return
+		}
+	}
+
+	webhook := &domain.Webhook{
+		ID: uuid.New().String(),
+		URL: req.URL,
+		Secret: secret,
+		DeviceID: req.DeviceID,
+		Events: req.Events,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := s.WebhookRepository.CreateWebhook(webhook); err != nil {
+		writeError(response, err)
+		return
+	}
+
+	webhookResponse := wrapWebhookResponse(webhook)
+	webhookResponse.Secret = webhook.Secret
+	WriteAPIResponse(response, http.StatusCreated, webhookResponse)
+}
+
+func (s *Server) ShowAllWebhooks(response http.ResponseWriter, request *http.Request) {
+	if s.WebhookRepository == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"webhooks are not enabled",
+		})
+		return
+	}
+
+	webhooks, err := s.WebhookRepository.GetAllWebhooks()
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	webhookResponses := make([]WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		webhookResponses = append(webhookResponses, wrapWebhookResponse(webhook))
+	}
+	WriteAPIResponse(response, http.StatusOK, webhookResponses)
+}
+
+// Webhook serves a single webhook, GET returns it and DELETE removes it with its pending and dead deliveries.
+func (s *Server) Webhook(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet && request.Method != http.MethodDelete {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	if s.WebhookRepository == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"webhooks are not enabled",
+		})
+		return
+	}
+
+	webhook, err := s.WebhookRepository.GetWebhook(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	if request.Method == http.MethodDelete {
+		if err := s.WebhookRepository.DeleteWebhook(webhook.ID); err != nil {
+			writeError(response, err)
+			return
+		}
+		response.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	WriteAPIResponse(response, http.StatusOK, wrapWebhookResponse(webhook))
+}
+
+// ShowDeadLetters lists the deliveries that ran out of attempts, oldest first.
+func (s *Server) ShowDeadLetters(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodGet {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	if s.WebhookRepository == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"webhooks are not enabled",
+		})
+		return
+	}
+
+	deliveries, err := s.WebhookRepository.ListDeliveries(persistence.DeliveryQuery{
+		Status: domain.DeliveryStatusDead,
+	})
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+
+	deliveryResponses := make([]DeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryResponses = append(deliveryResponses, wrapDeliveryResponse(delivery))
+	}
+	WriteAPIResponse(response, http.StatusOK, deliveryResponses)
+}
+
+// RetryDeadLetter puts a dead-lettered delivery back into the outbox with a fresh set of attempts.
+func (s *Server) RetryDeadLetter(response http.ResponseWriter, request *http.Request) {
+	if request.Method != http.MethodPost {
+		WriteErrorResponse(response, http.StatusMethodNotAllowed, []string{
+			http.StatusText(http.StatusMethodNotAllowed),
+		})
+		return
+	}
+
+	if s.WebhookRepository == nil {
+		WriteErrorResponse(response, http.StatusServiceUnavailable, []string{
+			"webhooks are not enabled",
+		})
+		return
+	}
+
+	delivery, err := s.WebhookRepository.GetDelivery(request.PathValue("id"))
+	if err != nil {
+		writeError(response, err)
+		return
+	}
+	if delivery.Status != domain.DeliveryStatusDead {
+		WriteErrorResponse(response, http.StatusConflict, []string{
+			fmt.Sprintf("delivery %s is still %s", delivery.ID, delivery.Status),
+		})
+		return
+	}
+
+	delivery.Status = domain.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now().UTC()
+	if err := s.WebhookRepository.UpdateDelivery(delivery); err != nil {
+		writeError(response, err)
+		return
+	}
+	if s.webhooks != nil {
+		s.webhooks.notify()
+	}
+
+	WriteAPIResponse(response, http.StatusOK, wrapDeliveryResponse(delivery))
+}
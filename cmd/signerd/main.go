@@ -0,0 +1,95 @@
+// Command signerd holds the device private keys outside of the HTTP-facing process.
+//
+// It serves a crypto.KeyService on a unix socket, the API server talks to it when it is
+// started with KEY_PROVIDER=remote. Keys are stored one file per key in SIGNER_KEY_DIR and
+// sealed with the master key. Without a master key it refuses to start, unless
+// ALLOW_PLAINTEXT_KEYS is true.
+package main
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+)
+
+func main() {
+	socket := getEnv("SIGNER_SOCKET", "signer.sock")
+	keyDir := getEnv("SIGNER_KEY_DIR", "keys")
+
+	var previousMasterKeys []string
+	if previous := getEnv("PREVIOUS_MASTER_KEYS", ""); previous != "" {
+		previousMasterKeys = strings.Split(previous, ",")
+	}
+
+	keySealer, err := crypto.LoadKeySealer(getEnv("MASTER_KEY", ""), getEnv("MASTER_KEY_FILE", ""), previousMasterKeys)
+	if err != nil {
+		log.Fatal("Could not load master key: ", err)
+	}
+	if keySealer == nil {
+		allowPlaintextKeys, err := strconv.ParseBool(getEnv("ALLOW_PLAINTEXT_KEYS", "false"))
+		if err != nil {
+			log.Fatal("Invalid ALLOW_PLAINTEXT_KEYS: ", err)
+		}
+		if !allowPlaintextKeys {
+			log.Fatal("No master key is set, keys would be stored unencrypted in ", keyDir)
+		}
+		log.Print("WARNING: no master key is set, keys are stored unencrypted in ", keyDir)
+	}
+
+	provider, err := crypto.NewFileKeyProvider(keyDir, crypto.NewLocalKeyProvider(keySealer))
+	if err != nil {
+		log.Fatal("Could not open key directory: ", err)
+	}
+
+	// Move keys sealed under a rotated master key over to the current one
+	if _, err := provider.RewrapKeys(); err != nil {
+		log.Fatal("Could not rewrap keys: ", err)
+	}
+
+	listener, err := listen(socket)
+	if err != nil {
+		log.Fatal("Could not listen on ", socket, ": ", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	log.Print("Serving keys on ", socket)
+	if err := crypto.ServeKeyProvider(listener, provider); err != nil {
+		log.Fatal("Could not serve keys: ", err)
+	}
+}
+
+// listen creates the unix socket at path, only its owner may connect to it.
+func listen(path string) (net.Listener, error) {
+	// A socket left behind by a crashed daemon would make the listen fail
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	// The socket gets its mode when it is created, restricting it afterwards would leave a
+	// window in which anyone can connect
+	mask := syscall.Umask(0o177)
+	defer syscall.Umask(mask)
+
+	return net.Listen("unix", path)
+}
+
+// getEnv returns the value of the environment variable key or fallback if it is unset.
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSignerdSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Signerd Suite")
+}
+
+var _ = Describe("Signer Daemon", func() {
+	var socket string
+
+	BeforeEach(func() {
+		// Socket paths are limited in length, the test temp dir can be too deep
+		socketDir, err := os.MkdirTemp("", "signerd")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, socketDir)
+		socket = filepath.Join(socketDir, "signer.sock")
+	})
+
+	It("should create a socket only its owner can connect to", func() {
+		listener, err := listen(socket)
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+
+		info, err := os.Stat(socket)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode() & os.ModeSocket).NotTo(BeZero())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o600)))
+	})
+
+	It("should replace a socket left behind by a crashed daemon", func() {
+		stale, err := net.Listen("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		// Keep the socket file around, like a daemon that was killed
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		Expect(stale.Close()).To(Succeed())
+
+		listener, err := listen(socket)
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+	})
+
+	It("should serve the keys of its key directory", func() {
+		keyDir := GinkgoT().TempDir()
+		provider, err := crypto.NewFileKeyProvider(keyDir, crypto.NewLocalKeyProvider(nil))
+		Expect(err).NotTo(HaveOccurred())
+
+		listener, err := listen(socket)
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		go crypto.ServeKeyProvider(listener, provider)
+
+		remote, err := crypto.DialKeyProvider("unix", socket)
+		Expect(err).NotTo(HaveOccurred())
+		defer remote.Close()
+
+		spec := crypto.KeySpec{Algorithm: "Ed25519"}
+		reference, err := remote.CreateKey(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(filepath.Join(keyDir, reference+".pem")).To(BeAnExistingFile())
+		_, err = remote.SignDigest(spec, reference, []byte("test-data"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,308 @@
+package crypto
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+// RSAAlgorithm signs with RSA-PSS or RSA PKCS#1 v1.5.
+type RSAAlgorithm struct{}
+
+func (RSAAlgorithm) Name() string {
+	return "RSA"
+}
+
+func (RSAAlgorithm) ResolveParameters(parameters domain.KeyParameters) (domain.KeyParameters, error) {
+	if parameters.Curve != "" {
+		return parameters, errors.New("curve is not supported for RSA")
+	}
+
+	switch parameters.KeySize {
+	case 0:
+		parameters.KeySize = DefaultRSAKeySize
+	case 2048, 3072, 4096:
+	default:
+		return parameters, fmt.Errorf("unsupported key size: %d", parameters.KeySize)
+	}
+
+	return resolveRSASignatureParameters(parameters)
+}
+
+// resolveRSASignatureParameters validates hash and padding of an RSA key and fills in their defaults.
+func resolveRSASignatureParameters(parameters domain.KeyParameters) (domain.KeyParameters, error) {
+	if parameters.Hash == "" {
+		parameters.Hash = "SHA-256"
+	}
+	if _, err := ParseHash(parameters.Hash); err != nil {
+		return parameters, err
+	}
+
+	switch RSAPadding(parameters.Padding) {
+	case "":
+		parameters.Padding = string(RSAPaddingPSS)
+	case RSAPaddingPSS, RSAPaddingPKCS1v15:
+	default:
+		return parameters, fmt.Errorf("unsupported padding: %s", parameters.Padding)
+	}
+
+	return parameters, nil
+}
+
+func (RSAAlgorithm) Generate(parameters domain.KeyParameters) ([]byte, []byte, error) {
+	generator := RSAGenerator{Bits: parameters.KeySize}
+	keyPair, err := generator.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	marshaler := NewRSAMarshaler()
+	return marshaler.Marshal(*keyPair)
+}
+
+func (RSAAlgorithm) Import(privateKey []byte, parameters domain.KeyParameters) (domain.KeyParameters, []byte, error) {
+	if parameters.Curve != "" {
+		return parameters, nil, errors.New("curve is not supported for RSA")
+	}
+
+	marshaler := NewRSAMarshaler()
+	keyPair, err := marshaler.Parse(privateKey)
+	if err != nil {
+		return parameters, nil, err
+	}
+
+	bits := keyPair.Private.N.BitLen()
+	if bits < MinRSAKeySize {
+		return parameters, nil, fmt.Errorf("RSA key has %d bits, at least %d are required", bits, MinRSAKeySize)
+	}
+	if parameters.KeySize != 0 && parameters.KeySize != bits {
+		return parameters, nil, fmt.Errorf("key size %d does not match the %d bit key", parameters.KeySize, bits)
+	}
+	parameters.KeySize = bits
+
+	parameters, err = resolveRSASignatureParameters(parameters)
+	if err != nil {
+		return parameters, nil, err
+	}
+
+	_, encoded, err := marshaler.Marshal(*keyPair)
+	return parameters, encoded, err
+}
+
+func (RSAAlgorithm) Digest(message []byte, parameters domain.KeyParameters) ([]byte, error) {
+	options, err := signatureOptions(parameters)
+	if err != nil {
+		return nil, err
+	}
+	return digest(options.rsaHash(), message), nil
+}
+
+func (RSAAlgorithm) NewSigner(privateKey []byte, parameters domain.KeyParameters) (KeyPairSigner, error) {
+	options, err := signatureOptions(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	marshaler := NewRSAMarshaler()
+	keyPair, err := marshaler.Unmarshal(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewRSASignerWithOptions(keyPair, options), nil
+}
+
+func (RSAAlgorithm) NewVerifier(publicKey []byte, parameters domain.KeyParameters) (Verifier, error) {
+	options, err := signatureOptions(parameters)
+	if err != nil {
+		return nil, err
+	}
+	return NewRSAVerifierFromPEM(publicKey, options)
+}
+
+// ECCAlgorithm signs with ECDSA.
+type ECCAlgorithm struct{}
+
+func (ECCAlgorithm) Name() string {
+	return "ECC"
+}
+
+func (ECCAlgorithm) ResolveParameters(parameters domain.KeyParameters) (domain.KeyParameters, error) {
+	if parameters.KeySize != 0 {
+		return parameters, errors.New("key size is not supported for ECC")
+	}
+	if parameters.Padding != "" {
+		return parameters, errors.New("padding is not supported for ECC")
+	}
+
+	if parameters.Curve == "" {
+		parameters.Curve = "P-384"
+	}
+	if _, err := ParseCurve(parameters.Curve); err != nil {
+		return parameters, err
+	}
+
+	if parameters.Hash == "" {
+		// Match the hash strength to the curve
+		parameters.Hash = map[string]string{
+			"P-256": "SHA-256",
+			"P-384": "SHA-384",
+			"P-521": "SHA-512",
+		}[parameters.Curve]
+	}
+	if _, err := ParseHash(parameters.Hash); err != nil {
+		return parameters, err
+	}
+
+	return parameters, nil
+}
+
+func (ECCAlgorithm) Generate(parameters domain.KeyParameters) ([]byte, []byte, error) {
+	curve, err := ParseCurve(parameters.Curve)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generator := ECCGenerator{Curve: curve}
+	keyPair, err := generator.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	marshaler := NewECCMarshaler()
+	return marshaler.Encode(*keyPair)
+}
+
+func (ECCAlgorithm) Import(privateKey []byte, parameters domain.KeyParameters) (domain.KeyParameters, []byte, error) {
+	marshaler := NewECCMarshaler()
+	keyPair, err := marshaler.Parse(privateKey)
+	if err != nil {
+		return parameters, nil, err
+	}
+
+	// Only the curves offered for generated keys are accepted, which rules out P-224
+	curve := keyPair.Private.Curve.Params().Name
+	if _, err := ParseCurve(curve); err != nil {
+		return parameters, nil, err
+	}
+	if parameters.Curve != "" && parameters.Curve != curve {
+		return parameters, nil, fmt.Errorf("curve %s does not match the %s key", parameters.Curve, curve)
+	}
+	parameters.Curve = curve
+
+	parameters, err = ECCAlgorithm{}.ResolveParameters(parameters)
+	if err != nil {
+		return parameters, nil, err
+	}
+
+	_, encoded, err := marshaler.Encode(*keyPair)
+	return parameters, encoded, err
+}
+
+func (ECCAlgorithm) Digest(message []byte, parameters domain.KeyParameters) ([]byte, error) {
+	options, err := signatureOptions(parameters)
+	if err != nil {
+		return nil, err
+	}
+	if options.Hash == 0 {
+		return message, nil
+	}
+	return digest(options.Hash, message), nil
+}
+
+func (ECCAlgorithm) NewSigner(privateKey []byte, parameters domain.KeyParameters) (KeyPairSigner, error) {
+	options, err := signatureOptions(parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	marshaler := NewECCMarshaler()
+	keyPair, err := marshaler.Decode(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewECCSignerWithOptions(keyPair, options), nil
+}
+
+func (ECCAlgorithm) NewVerifier(publicKey []byte, parameters domain.KeyParameters) (Verifier, error) {
+	options, err := signatureOptions(parameters)
+	if err != nil {
+		return nil, err
+	}
+	return NewECCVerifierFromPEM(publicKey, options)
+}
+
+// Ed25519Algorithm signs with pure Ed25519.
+type Ed25519Algorithm struct{}
+
+func (Ed25519Algorithm) Name() string {
+	return "Ed25519"
+}
+
+func (Ed25519Algorithm) ResolveParameters(parameters domain.KeyParameters) (domain.KeyParameters, error) {
+	if parameters != (domain.KeyParameters{}) {
+		return parameters, errors.New("Ed25519 does not support key parameters")
+	}
+	return parameters, nil
+}
+
+func (Ed25519Algorithm) Generate(parameters domain.KeyParameters) ([]byte, []byte, error) {
+	generator := Ed25519Generator{}
+	keyPair, err := generator.Generate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	marshaler := NewEd25519Marshaler()
+	return marshaler.Encode(*keyPair)
+}
+
+func (Ed25519Algorithm) Import(privateKey []byte, parameters domain.KeyParameters) (domain.KeyParameters, []byte, error) {
+	parameters, err := Ed25519Algorithm{}.ResolveParameters(parameters)
+	if err != nil {
+		return parameters, nil, err
+	}
+
+	marshaler := NewEd25519Marshaler()
+	keyPair, err := marshaler.Parse(privateKey)
+	if err != nil {
+		return parameters, nil, err
+	}
+
+	_, encoded, err := marshaler.Encode(*keyPair)
+	return parameters, encoded, err
+}
+
+func (Ed25519Algorithm) Digest(message []byte, parameters domain.KeyParameters) ([]byte, error) {
+	return message, nil
+}
+
+func (Ed25519Algorithm) NewSigner(privateKey []byte, parameters domain.KeyParameters) (KeyPairSigner, error) {
+	marshaler := NewEd25519Marshaler()
+	keyPair, err := marshaler.Decode(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewEd25519Signer(keyPair), nil
+}
+
+func (Ed25519Algorithm) NewVerifier(publicKey []byte, parameters domain.KeyParameters) (Verifier, error) {
+	return NewEd25519VerifierFromPEM(publicKey)
+}
+
+// signatureOptions translates the key parameters of a device into SignatureOptions.
+func signatureOptions(parameters domain.KeyParameters) (SignatureOptions, error) {
+	options := SignatureOptions{
+		Padding: RSAPadding(parameters.Padding),
+	}
+
+	if parameters.Hash != "" {
+		hash, err := ParseHash(parameters.Hash)
+		if err != nil {
+			return SignatureOptions{}, err
+		}
+		options.Hash = hash
+	}
+
+	return options, nil
+}
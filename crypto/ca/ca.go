@@ -0,0 +1,532 @@
+// Package ca is the certificate authority of the service. It certifies the public keys of
+// devices under a single root, so third parties can verify any device by trusting that root.
+package ca
+
+import (
+	gocrypto "crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/google/uuid"
+)
+
+const (
+	rootKeyFile         = "root.key"
+	rootCertificateFile = "root.crt"
+	// issuedDir holds a copy of every issued certificate, named by its hex serial number.
+	issuedDir = "issued"
+	// devicesDir holds the current certificate of every device, named by the device ID.
+	devicesDir = "devices"
+	// revocationsFile lists the revoked certificates that have not expired yet.
+	revocationsFile = "revoked.json"
+
+	rootValidity   = 10 * 365 * 24 * time.Hour
+	deviceValidity = 365 * 24 * time.Hour
+	// crlValidity is how long verifiers may rely on a revocation list, it is renewed halfway through.
+	crlValidity = 24 * time.Hour
+	// clockSkew backdates certificates so verifiers with a slow clock accept them right away.
+	clockSkew = 5 * time.Minute
+)
+
+var (
+	// oidDescription is the X.520 description attribute, which holds the device label.
+	oidDescription = asn1.ObjectIdentifier{2, 5, 4, 13}
+	// oidSubjectDirectoryAttributes is the RFC 5280 extension carrying the device attributes.
+	oidSubjectDirectoryAttributes = asn1.ObjectIdentifier{2, 5, 29, 9}
+)
+
+// attribute is an X.501 Attribute as used by the subject directory attributes extension.
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []string `asn1:"set"`
+}
+
+// ErrMasterKeyRequired is returned by LoadOrCreate without a KeySealer, the root key is never
+// written or kept in the clear.
+var ErrMasterKeyRequired = errors.New("the certificate authority needs a master key to seal its root key")
+
+// ErrNoDeviceCertificate is returned by DeviceCertificate when no certificate was issued for the
+// current key of a device.
+var ErrNoDeviceCertificate = errors.New("no certificate was issued for the current key of the device")
+
+// Authority holds the root key and self-signed root certificate of the service.
+type Authority struct {
+	mutex       sync.Mutex // Serializes the changes of the device certificates and revocations
+	dir         string
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	revocations []revocation
+
+	crl        []byte // DER encoded revocation list, nil until requested or after a revocation
+	crlCreated time.Time
+}
+
+// revocation records a revoked certificate in the revocations file.
+type revocation struct {
+	SerialNumber string    `json:"serial_number"` // Hex, like the names in the issued directory
+	RevokedAt    time.Time `json:"revoked_at"`
+	ExpiresAt    time.Time `json:"expires_at"` // The certificate is dropped from the list once it expired
+}
+
+// LoadOrCreate reads the root key and certificate from dir, and creates both on first start.
+// The root key is sealed by sealer, a root key written in the clear by an earlier version is
+// sealed when it is loaded.
+func LoadOrCreate(dir string, sealer *crypto.KeySealer) (*Authority, error) {
+	if sealer == nil {
+		return nil, ErrMasterKeyRequired
+	}
+	for _, subdir := range []string{issuedDir, devicesDir} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0o700); err != nil {
+			return nil, err
+		}
+	}
+
+	keyPath := filepath.Join(dir, rootKeyFile)
+	certificatePath := filepath.Join(dir, rootCertificateFile)
+
+	encodedKey, err := os.ReadFile(keyPath)
+	if errors.Is(err, os.ErrNotExist) {
+		authority, err := create(dir, sealer)
+		if err != nil {
+			return nil, err
+		}
+		return authority, authority.readRevocations()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	encodedCertificate, err := os.ReadFile(certificatePath)
+	if err != nil {
+		return nil, err
+	}
+
+	authority, err := parse(encodedKey, encodedCertificate, sealer)
+	if err != nil {
+		return nil, err
+	}
+	authority.dir = dir
+
+	if sealer.NeedsRewrap(encodedKey) {
+		encodedKey, err = sealer.Rewrap(encodedKey)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeFile(keyPath, encodedKey); err != nil {
+			return nil, err
+		}
+	}
+
+	return authority, authority.readRevocations()
+}
+
+func create(dir string, sealer *crypto.KeySealer) (*Authority, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: "Signing Service Root CA"},
+		NotBefore:             now.Add(-clockSkew),
+		NotAfter:              now.Add(rootValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	encodedKey, err := sealer.Seal(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		return nil, err
+	}
+
+	// The certificate goes first, a key without its certificate would be replaced on the next start
+	if err := writeFile(filepath.Join(dir, rootCertificateFile), EncodeCertificate(der)); err != nil {
+		return nil, err
+	}
+	if err := writeFile(filepath.Join(dir, rootKeyFile), encodedKey); err != nil {
+		return nil, err
+	}
+
+	return &Authority{
+		dir:         dir,
+		certificate: certificate,
+		key:         key,
+	}, nil
+}
+
+func parse(encodedKey []byte, encodedCertificate []byte, sealer *crypto.KeySealer) (*Authority, error) {
+	if crypto.IsSealed(encodedKey) {
+		var err error
+		encodedKey, err = sealer.Open(encodedKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	keyBlock, _ := pem.Decode(encodedKey)
+	if keyBlock == nil {
+		return nil, errors.New("no PEM encoded root key found")
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsedKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("root key is not an ECDSA key")
+	}
+
+	certificateBlock, _ := pem.Decode(encodedCertificate)
+	if certificateBlock == nil || certificateBlock.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded root certificate found")
+	}
+	certificate, err := x509.ParseCertificate(certificateBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if !key.PublicKey.Equal(certificate.PublicKey) {
+		return nil, errors.New("root certificate does not belong to the root key")
+	}
+
+	return &Authority{
+		certificate: certificate,
+		key:         key,
+	}, nil
+}
+
+// Certificate returns the root certificate.
+func (a *Authority) Certificate() *x509.Certificate {
+	return a.certificate
+}
+
+// IssueDeviceCertificate certifies the current public key of a device and returns the DER
+// encoded certificate. The device ID is the common name of the subject and, for UUIDs, a
+// urn:uuid subject alternative name. The label is the description attribute of the subject
+// and of the subject directory attributes extension. A copy of the certificate is stored in
+// the issued directory before it is returned, so every certificate out there can be accounted
+// for, and it becomes the certificate DeviceCertificate returns for the device. The certificate
+// it replaces, e.g. the one of a key that was rotated, is revoked.
+func (a *Authority) IssueDeviceCertificate(device *domain.Device) ([]byte, error) {
+	devicePath, err := a.devicePath(device.ID)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := crypto.ParsePublicKey([]byte(device.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+
+	serialNumber, err := newSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{CommonName: device.ID},
+		NotBefore:             now.Add(-clockSkew),
+		NotAfter:              now.Add(deviceValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+
+	if _, err := uuid.Parse(device.ID); err == nil {
+		template.URIs = []*url.URL{{Scheme: "urn", Opaque: "uuid:" + device.ID}}
+	}
+
+	if device.Label != "" {
+		template.Subject.ExtraNames = []pkix.AttributeTypeAndValue{{Type: oidDescription, Value: device.Label}}
+
+		attributes, err := asn1.Marshal([]attribute{{Type: oidDescription, Values: []string{device.Label}}})
+		if err != nil {
+			return nil, err
+		}
+		template.ExtraExtensions = []pkix.Extension{{Id: oidSubjectDirectoryAttributes, Value: attributes}}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, a.certificate, publicKey, a.key)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if err := writeFile(a.issuedPath(serialNumber), EncodeCertificate(der)); err != nil {
+		return nil, fmt.Errorf("could not store the issued certificate: %w", err)
+	}
+	if err := a.revoke(devicePath); err != nil {
+		return nil, err
+	}
+	if err := writeFile(devicePath, EncodeCertificate(der)); err != nil {
+		return nil, fmt.Errorf("could not store the issued certificate: %w", err)
+	}
+	return der, nil
+}
+
+// DeviceCertificate returns the DER encoded certificate last issued for device, as long as it
+// certifies the current public key of the device. Otherwise it returns ErrNoDeviceCertificate.
+func (a *Authority) DeviceCertificate(device *domain.Device) ([]byte, error) {
+	devicePath, err := a.devicePath(device.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	certificate, err := readCertificate(devicePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNoDeviceCertificate
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A crash right after a revocation can leave the revoked certificate behind
+	if a.isRevoked(certificate.SerialNumber) {
+		return nil, ErrNoDeviceCertificate
+	}
+
+	publicKey, err := crypto.ParsePublicKey([]byte(device.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	if certified, ok := certificate.PublicKey.(interface{ Equal(gocrypto.PublicKey) bool }); !ok || !certified.Equal(publicKey) {
+		return nil, ErrNoDeviceCertificate
+	}
+	return certificate.Raw, nil
+}
+
+// RevokeDeviceCertificate revokes the current certificate of a device, e.g. when the device is
+// suspended or decommissioned. A device without a certificate is left alone.
+func (a *Authority) RevokeDeviceCertificate(deviceID string) error {
+	devicePath, err := a.devicePath(deviceID)
+	if err != nil {
+		return err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.revoke(devicePath)
+}
+
+// revoke adds the certificate stored at devicePath to the revocations and removes the file.
+// The revocation is on disk before the file goes, callers hold a.mutex.
+func (a *Authority) revoke(devicePath string) error {
+	certificate, err := readCertificate(devicePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !a.isRevoked(certificate.SerialNumber) {
+		now := time.Now().UTC()
+		revocations := []revocation{{
+			SerialNumber: certificate.SerialNumber.Text(16),
+			RevokedAt:    now,
+			ExpiresAt:    certificate.NotAfter,
+		}}
+		// Expired certificates are no longer listed
+		for _, revoked := range a.revocations {
+			if revoked.ExpiresAt.After(now) {
+				revocations = append(revocations, revoked)
+			}
+		}
+
+		content, err := json.MarshalIndent(revocations, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFile(filepath.Join(a.dir, revocationsFile), content); err != nil {
+			return fmt.Errorf("could not store the revocation: %w", err)
+		}
+		a.revocations = revocations
+		a.crl = nil
+	}
+
+	if err := os.Remove(devicePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (a *Authority) isRevoked(serialNumber *big.Int) bool {
+	for _, revoked := range a.revocations {
+		if revoked.SerialNumber == serialNumber.Text(16) {
+			return true
+		}
+	}
+	return false
+}
+
+// readRevocations loads the revocations file, which does not exist before the first revocation.
+func (a *Authority) readRevocations() error {
+	content, err := os.ReadFile(filepath.Join(a.dir, revocationsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, &a.revocations)
+}
+
+// CRL returns the DER encoded revocation list of the certificates revoked by the authority and
+// not yet expired, signed by the root. A new list is created after every revocation and once the
+// current one is halfway to its next update.
+func (a *Authority) CRL() ([]byte, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	if a.crl != nil && now.Before(a.crlCreated.Add(crlValidity/2)) {
+		return a.crl, nil
+	}
+
+	entries := make([]x509.RevocationListEntry, 0, len(a.revocations))
+	for _, revoked := range a.revocations {
+		if !revoked.ExpiresAt.After(now) {
+			continue
+		}
+		serialNumber, ok := new(big.Int).SetString(revoked.SerialNumber, 16)
+		if !ok {
+			return nil, fmt.Errorf("invalid serial number %q in the revocations", revoked.SerialNumber)
+		}
+		entries = append(entries, x509.RevocationListEntry{
+			SerialNumber:   serialNumber,
+			RevocationTime: revoked.RevokedAt,
+		})
+	}
+
+	// The time makes the CRL number grow with every list, also across restarts
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		Number:                    big.NewInt(now.UnixNano()),
+		ThisUpdate:                now.Add(-clockSkew),
+		NextUpdate:                now.Add(crlValidity),
+	}, a.certificate, a.key)
+	if err != nil {
+		return nil, err
+	}
+
+	a.crl = crl
+	a.crlCreated = now
+	return crl, nil
+}
+
+// IssuedCertificate returns the stored certificate with the given serial number in PEM.
+func (a *Authority) IssuedCertificate(serialNumber *big.Int) ([]byte, error) {
+	return os.ReadFile(a.issuedPath(serialNumber))
+}
+
+func (a *Authority) issuedPath(serialNumber *big.Int) string {
+	return filepath.Join(a.dir, issuedDir, serialNumber.Text(16)+".crt")
+}
+
+// devicePath maps a device ID to the file of its current certificate, IDs that would leave the
+// devices directory are refused.
+func (a *Authority) devicePath(deviceID string) (string, error) {
+	if deviceID == "" || deviceID == "." || deviceID == ".." || strings.ContainsAny(deviceID, `/\`) {
+		return "", fmt.Errorf("invalid device id %q", deviceID)
+	}
+	return filepath.Join(a.dir, devicesDir, deviceID+".crt"), nil
+}
+
+// readCertificate reads a PEM encoded certificate from path.
+func readCertificate(path string) (*x509.Certificate, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no PEM encoded certificate found in %s", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// EncodeCertificate wraps a DER encoded certificate in a PEM "CERTIFICATE" block.
+func EncodeCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// EncodeCRL wraps a DER encoded revocation list in a PEM "X509 CRL" block.
+func EncodeCRL(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+// newSerialNumber returns a random positive 128 bit serial number.
+func newSerialNumber() (*big.Int, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("could not generate serial number: %w", err)
+	}
+	return serialNumber.Add(serialNumber, big.NewInt(1)), nil
+}
+
+// writeFile replaces path atomically, a crash leaves either the old or the new content behind.
+func writeFile(path string, content []byte) error {
+	temporary, err := os.CreateTemp(filepath.Dir(path), ".ca-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+
+	if _, err := temporary.Write(content); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Sync(); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary.Name(), path)
+}
@@ -0,0 +1,201 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestCASuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Certificate Authority Suite")
+}
+
+var _ = Describe("Authority", func() {
+	var (
+		dir       string
+		sealer    *crypto.KeySealer
+		authority *Authority
+	)
+
+	newDevice := func() *domain.Device {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		publicKey, err := crypto.MarshalPublicKeyPEM(&key.PublicKey)
+		Expect(err).NotTo(HaveOccurred())
+		return &domain.Device{ID: uuid.New().String(), Algorithm: "ECC", PublicKey: string(publicKey), Label: "till-1"}
+	}
+
+	parseCertificate := func(der []byte) *x509.Certificate {
+		certificate, err := x509.ParseCertificate(der)
+		Expect(err).NotTo(HaveOccurred())
+		return certificate
+	}
+
+	revokedSerialNumbers := func() []string {
+		der, err := authority.CRL()
+		Expect(err).NotTo(HaveOccurred())
+		crl, err := x509.ParseRevocationList(der)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(crl.CheckSignatureFrom(authority.Certificate())).To(Succeed())
+
+		serialNumbers := make([]string, 0, len(crl.RevokedCertificateEntries))
+		for _, entry := range crl.RevokedCertificateEntries {
+			serialNumbers = append(serialNumbers, entry.SerialNumber.Text(16))
+		}
+		return serialNumbers
+	}
+
+	BeforeEach(func() {
+		masterKey, err := crypto.NewMasterKey(bytes.Repeat([]byte{7}, 32))
+		Expect(err).NotTo(HaveOccurred())
+		sealer = crypto.NewKeySealer(masterKey)
+
+		dir = GinkgoT().TempDir()
+		authority, err = LoadOrCreate(dir, sealer)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("When it is loaded", func() {
+		It("should keep the root across restarts", func() {
+			reloaded, err := LoadOrCreate(dir, sealer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(reloaded.Certificate().Raw).To(Equal(authority.Certificate().Raw))
+			Expect(authority.Certificate().IsCA).To(BeTrue())
+			Expect(authority.Certificate().CheckSignatureFrom(authority.Certificate())).To(Succeed())
+		})
+
+		It("should seal the root key and refuse to run without a master key", func() {
+			rootKey, err := os.ReadFile(filepath.Join(dir, rootKeyFile))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crypto.IsSealed(rootKey)).To(BeTrue())
+
+			_, err = LoadOrCreate(dir, nil)
+			Expect(err).To(MatchError(ErrMasterKeyRequired))
+			_, err = LoadOrCreate(GinkgoT().TempDir(), nil)
+			Expect(err).To(MatchError(ErrMasterKeyRequired))
+		})
+
+		It("should seal a root key written in the clear by an earlier version", func() {
+			keyDER, err := x509.MarshalPKCS8PrivateKey(authority.key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(writeFile(filepath.Join(dir, rootKeyFile), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))).To(Succeed())
+
+			reloaded, err := LoadOrCreate(dir, sealer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reloaded.Certificate().Raw).To(Equal(authority.Certificate().Raw))
+
+			rootKey, err := os.ReadFile(filepath.Join(dir, rootKeyFile))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crypto.IsSealed(rootKey)).To(BeTrue())
+		})
+	})
+
+	Context("When a device certificate is issued", func() {
+		It("should certify the device key under the root and keep the certificate", func() {
+			device := newDevice()
+			der, err := authority.IssueDeviceCertificate(device)
+			Expect(err).NotTo(HaveOccurred())
+
+			certificate := parseCertificate(der)
+			roots := x509.NewCertPool()
+			roots.AddCert(authority.Certificate())
+			_, err = certificate.Verify(x509.VerifyOptions{Roots: roots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate.Subject.CommonName).To(Equal(device.ID))
+			Expect(certificate.Subject.Names).To(ContainElement(HaveField("Value", "till-1")))
+			Expect(certificate.URIs[0].String()).To(Equal("urn:uuid:" + device.ID))
+
+			issued, err := authority.IssuedCertificate(certificate.SerialNumber)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(issued).To(Equal(EncodeCertificate(der)))
+			Expect(authority.DeviceCertificate(device)).To(Equal(der))
+		})
+
+		It("should not return a certificate of another key of the device", func() {
+			device := newDevice()
+			_, err := authority.DeviceCertificate(device)
+			Expect(err).To(MatchError(ErrNoDeviceCertificate))
+
+			_, err = authority.IssueDeviceCertificate(device)
+			Expect(err).NotTo(HaveOccurred())
+			rotated := *device
+			rotated.PublicKey = newDevice().PublicKey
+			_, err = authority.DeviceCertificate(&rotated)
+			Expect(err).To(MatchError(ErrNoDeviceCertificate))
+		})
+
+		It("should refuse device IDs that leave the devices directory", func() {
+			device := newDevice()
+			device.ID = "../root"
+			_, err := authority.IssueDeviceCertificate(device)
+			Expect(err).To(MatchError(ContainSubstring("invalid device id")))
+			Expect(authority.RevokeDeviceCertificate("../root")).To(MatchError(ContainSubstring("invalid device id")))
+		})
+	})
+
+	Context("When a device certificate is revoked", func() {
+		It("should list it in the revocation list, also after a restart", func() {
+			device := newDevice()
+			der, err := authority.IssueDeviceCertificate(device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(revokedSerialNumbers()).To(BeEmpty())
+
+			Expect(authority.RevokeDeviceCertificate(device.ID)).To(Succeed())
+			_, err = authority.DeviceCertificate(device)
+			Expect(err).To(MatchError(ErrNoDeviceCertificate))
+			serialNumber := parseCertificate(der).SerialNumber.Text(16)
+			Expect(revokedSerialNumbers()).To(Equal([]string{serialNumber}))
+
+			// Revoking a device without a certificate changes nothing
+			Expect(authority.RevokeDeviceCertificate(device.ID)).To(Succeed())
+
+			authority, err = LoadOrCreate(dir, sealer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(revokedSerialNumbers()).To(Equal([]string{serialNumber}))
+		})
+
+		It("should revoke the certificate a new one replaces", func() {
+			device := newDevice()
+			previous, err := authority.IssueDeviceCertificate(device)
+			Expect(err).NotTo(HaveOccurred())
+
+			device.PublicKey = newDevice().PublicKey
+			_, err = authority.IssueDeviceCertificate(device)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(revokedSerialNumbers()).To(Equal([]string{parseCertificate(previous).SerialNumber.Text(16)}))
+		})
+
+		It("should not serve a revoked certificate left behind by a crash", func() {
+			device := newDevice()
+			der, err := authority.IssueDeviceCertificate(device)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(authority.RevokeDeviceCertificate(device.ID)).To(Succeed())
+			Expect(writeFile(filepath.Join(dir, devicesDir, device.ID+".crt"), EncodeCertificate(der))).To(Succeed())
+
+			_, err = authority.DeviceCertificate(device)
+			Expect(err).To(MatchError(ErrNoDeviceCertificate))
+		})
+
+		It("should drop expired certificates from the revocation list", func() {
+			authority.revocations = []revocation{{SerialNumber: "1", RevokedAt: time.Now().Add(-2 * time.Hour), ExpiresAt: time.Now().Add(-time.Hour)}}
+
+			Expect(revokedSerialNumbers()).To(BeEmpty())
+		})
+	})
+})
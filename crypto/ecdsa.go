@@ -4,6 +4,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 )
 
 // ECCKeyPair is a DTO that holds ECC private and public keys.
@@ -28,20 +29,15 @@ func (m ECCMarshaler) Encode(keyPair ECCKeyPair) ([]byte, []byte, error) {
 		return nil, nil, err
 	}
 
-	publicKeyBytes, err := x509.MarshalPKIXPublicKey(keyPair.Public)
-	if err != nil {
-		return nil, nil, err
-	}
-
 	encodedPrivate := pem.EncodeToMemory(&pem.Block{
-		Type:  "PRIVATE_KEY",
+		Type:  "EC PRIVATE KEY",
 		Bytes: privateKeyBytes,
 	})
 
-	encodedPublic := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC_KEY",
-		Bytes: publicKeyBytes,
-	})
+	encodedPublic, err := MarshalPublicKeyPEM(keyPair.Public)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	return encodedPublic, encodedPrivate, nil
 }
@@ -49,6 +45,10 @@ func (m ECCMarshaler) Encode(keyPair ECCKeyPair) ([]byte, []byte, error) {
 // Decode assembles an ECCKeyPair from an encoded private key.
 func (m ECCMarshaler) Decode(privateKeyBytes []byte) (*ECCKeyPair, error) {
 	block, _ := pem.Decode(privateKeyBytes)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+
 	privateKey, err := x509.ParseECPrivateKey(block.Bytes)
 	if err != nil {
 		return nil, err
@@ -59,3 +59,35 @@ func (m ECCMarshaler) Decode(privateKeyBytes []byte) (*ECCKeyPair, error) {
 		Public:  &privateKey.PublicKey,
 	}, nil
 }
+
+// DecodePublic assembles an ecdsa.PublicKey from an encoded public key.
+func (m ECCMarshaler) DecodePublic(publicKeyBytes []byte) (*ecdsa.PublicKey, error) {
+	publicKey, err := ParsePublicKey(publicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	eccPublicKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an ECC key")
+	}
+	return eccPublicKey, nil
+}
+
+// Parse assembles an ECCKeyPair from an externally generated private key in SEC1, PKCS#8 or JWK encoding.
+func (m ECCMarshaler) Parse(privateKeyBytes []byte) (*ECCKeyPair, error) {
+	privateKey, err := parsePrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	eccPrivateKey, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an ECC key")
+	}
+
+	return &ECCKeyPair{
+		Private: eccPrivateKey,
+		Public:  &eccPrivateKey.PublicKey,
+	}, nil
+}
@@ -0,0 +1,258 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	sealedKeyBlockType   = "SEALED PRIVATE KEY"
+	masterKeyIDHeader    = "Master-Key-Id"
+	wrappedDataKeyHeader = "Data-Key"
+	masterKeySize        = 32
+	dataKeySize          = 32
+)
+
+// ErrKeyNotSealed is returned when a KeySealer is asked to open a private key that was stored
+// before a master key was configured. Such keys have to be sealed with Rewrap first.
+var ErrKeyNotSealed = errors.New("private key is not sealed")
+
+// MasterKey wraps the data keys that encrypt device private keys.
+type MasterKey struct {
+	ID  string // Derived from the key, so rotated keys can be told apart
+	key []byte
+}
+
+// NewMasterKey creates a MasterKey from 32 bytes of key material.
+func NewMasterKey(key []byte) (*MasterKey, error) {
+	if len(key) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", masterKeySize, len(key))
+	}
+
+	fingerprint := sha256.Sum256(key)
+	return &MasterKey{
+		ID:  hex.EncodeToString(fingerprint[:8]),
+		key: bytes.Clone(key),
+	}, nil
+}
+
+// ParseMasterKey creates a MasterKey from its base64 encoding, as kept in an environment variable.
+func ParseMasterKey(encoded string) (*MasterKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("master key is not valid base64: %w", err)
+	}
+	return NewMasterKey(key)
+}
+
+// ReadMasterKeyFile reads a base64 encoded MasterKey from a file.
+func ReadMasterKeyFile(path string) (*MasterKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMasterKey(string(content))
+}
+
+// LoadKeySealer creates a KeySealer from base64 encoded master keys, masterKey takes precedence
+// over masterKeyFile. It returns nil if neither is set, meaning private keys are not encrypted.
+func LoadKeySealer(masterKey string, masterKeyFile string, previousMasterKeys []string) (*KeySealer, error) {
+	var current *MasterKey
+	var err error
+
+	switch {
+	case masterKey != "":
+		current, err = ParseMasterKey(masterKey)
+	case masterKeyFile != "":
+		current, err = ReadMasterKeyFile(masterKeyFile)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make([]*MasterKey, 0, len(previousMasterKeys))
+	for _, encoded := range previousMasterKeys {
+		masterKey, err := ParseMasterKey(encoded)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, masterKey)
+	}
+
+	return NewKeySealer(current, previous...), nil
+}
+
+// KeySealer applies envelope encryption to private keys. Every key is encrypted with its
+// own data key, which is wrapped by the current master key. Previous master keys are only
+// used to open keys sealed before a rotation.
+type KeySealer struct {
+	current    *MasterKey
+	masterKeys map[string]*MasterKey
+}
+
+// NewKeySealer creates a KeySealer sealing with current and opening with current and previous.
+func NewKeySealer(current *MasterKey, previous ...*MasterKey) *KeySealer {
+	masterKeys := map[string]*MasterKey{current.ID: current}
+	for _, masterKey := range previous {
+		masterKeys[masterKey.ID] = masterKey
+	}
+
+	return &KeySealer{
+		current:    current,
+		masterKeys: masterKeys,
+	}
+}
+
+// IsSealed reports whether key was produced by Seal.
+func IsSealed(key []byte) bool {
+	block, _ := pem.Decode(key)
+	return block != nil && block.Type == sealedKeyBlockType
+}
+
+// Seal encrypts an encoded private key under a fresh data key.
+func (k *KeySealer) Seal(privateKey []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	ciphertext, err := encrypt(dataKey, privateKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.encode(dataKey, ciphertext)
+}
+
+// Open decrypts a key sealed by Seal. Keys that were never sealed are refused with
+// ErrKeyNotSealed, otherwise a key written to the storage in the clear would be used silently.
+func (k *KeySealer) Open(sealedKey []byte) ([]byte, error) {
+	if !IsSealed(sealedKey) {
+		return nil, ErrKeyNotSealed
+	}
+
+	dataKey, ciphertext, err := k.decode(sealedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypt(dataKey, ciphertext, nil)
+}
+
+// Rewrap wraps the data key of a sealed key with the current master key. The encrypted
+// private key itself stays untouched. Keys that were never sealed get sealed, this is the
+// one-time migration of keys stored before a master key was configured.
+func (k *KeySealer) Rewrap(sealedKey []byte) ([]byte, error) {
+	if !IsSealed(sealedKey) {
+		return k.Seal(sealedKey)
+	}
+
+	dataKey, ciphertext, err := k.decode(sealedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return k.encode(dataKey, ciphertext)
+}
+
+// NeedsRewrap reports whether key is not yet sealed under the current master key.
+func (k *KeySealer) NeedsRewrap(key []byte) bool {
+	block, _ := pem.Decode(key)
+	return block == nil || block.Type != sealedKeyBlockType || block.Headers[masterKeyIDHeader] != k.current.ID
+}
+
+func (k *KeySealer) encode(dataKey []byte, ciphertext []byte) ([]byte, error) {
+	wrappedDataKey, err := encrypt(k.current.key, dataKey, []byte(k.current.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type: sealedKeyBlockType,
+		Headers: map[string]string{
+			masterKeyIDHeader:    k.current.ID,
+			wrappedDataKeyHeader: base64.StdEncoding.EncodeToString(wrappedDataKey),
+		},
+		Bytes: ciphertext,
+	}), nil
+}
+
+func (k *KeySealer) decode(sealedKey []byte) ([]byte, []byte, error) {
+	block, _ := pem.Decode(sealedKey)
+	if block == nil || block.Type != sealedKeyBlockType {
+		return nil, nil, errors.New("no sealed private key found")
+	}
+
+	masterKeyID := block.Headers[masterKeyIDHeader]
+	masterKey, exists := k.masterKeys[masterKeyID]
+	if !exists {
+		return nil, nil, fmt.Errorf("master key %s is not available", masterKeyID)
+	}
+
+	wrappedDataKey, err := base64.StdEncoding.DecodeString(block.Headers[wrappedDataKeyHeader])
+	if err != nil {
+		return nil, nil, fmt.Errorf("wrapped data key is not valid base64: %w", err)
+	}
+
+	dataKey, err := decrypt(masterKey.key, wrappedDataKey, []byte(masterKeyID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return dataKey, block.Bytes, nil
+}
+
+// encrypt seals plaintext with AES-GCM and prepends the random nonce.
+func encrypt(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// decrypt opens a ciphertext produced by encrypt.
+func decrypt(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, errors.New("decryption failed")
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
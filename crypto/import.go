@@ -0,0 +1,40 @@
+package crypto
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// MinRSAKeySize is the smallest RSA modulus accepted for imported keys.
+const MinRSAKeySize = 2048
+
+// parsePrivateKey decodes an externally generated private key. It accepts PEM encoded
+// PKCS#1, PKCS#8 and SEC1 keys as well as JWK, and returns an *rsa.PrivateKey,
+// *ecdsa.PrivateKey or ed25519.PrivateKey.
+func parsePrivateKey(encoded []byte) (any, error) {
+	if isJWK(encoded) {
+		return parseJWKPrivateKey(encoded)
+	}
+
+	block, _ := pem.Decode(encoded)
+	if block == nil {
+		return nil, errors.New("private key is neither PEM nor JWK encoded")
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" || block.Headers["Proc-Type"] != "" {
+		return nil, errors.New("encrypted private keys are not supported")
+	}
+
+	// The PEM label is not trusted, every format is tried on the DER content
+	if privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+	if privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+	if privateKey, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+	return nil, fmt.Errorf("%s is not a PKCS#1, PKCS#8 or SEC1 private key", block.Type)
+}
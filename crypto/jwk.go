@@ -0,0 +1,161 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// JWK is a JSON Web Key (RFC 7517) holding an RSA, EC or Ed25519 key.
+// Private fields are empty for public keys.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA
+	N  string `json:"n,omitempty"`
+	E  string `json:"e,omitempty"`
+	D  string `json:"d,omitempty"` // Also the private scalar of EC and the seed of Ed25519
+	P  string `json:"p,omitempty"`
+	Q  string `json:"q,omitempty"`
+	DP string `json:"dp,omitempty"`
+	DQ string `json:"dq,omitempty"`
+	QI string `json:"qi,omitempty"`
+
+	// EC and Ed25519
+	X string `json:"x,omitempty"`
+	Y string `json:"y,omitempty"`
+}
+
+// isJWK reports whether encoded looks like a JSON object rather than PEM.
+func isJWK(encoded []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(encoded), []byte("{"))
+}
+
+// parseJWKPrivateKey decodes a private JWK into an *rsa.PrivateKey, *ecdsa.PrivateKey or ed25519.PrivateKey.
+func parseJWKPrivateKey(encoded []byte) (any, error) {
+	var jwk JWK
+	if err := json.Unmarshal(encoded, &jwk); err != nil {
+		return nil, fmt.Errorf("invalid JWK: %w", err)
+	}
+	if jwk.D == "" {
+		return nil, errors.New("JWK does not contain a private key")
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		return jwk.rsaPrivateKey()
+	case "EC":
+		return jwk.ecdsaPrivateKey()
+	case "OKP":
+		return jwk.ed25519PrivateKey()
+	default:
+		return nil, fmt.Errorf("unsupported JWK key type: %s", jwk.Kty)
+	}
+}
+
+func (j JWK) rsaPrivateKey() (*rsa.PrivateKey, error) {
+	var n, e, d, p, q big.Int
+	for _, field := range []struct {
+		name  string
+		value string
+		into  *big.Int
+	}{{"n", j.N, &n}, {"e", j.E, &e}, {"d", j.D, &d}, {"p", j.P, &p}, {"q", j.Q, &q}} {
+		if err := decodeJWKInt(field.name, field.value, field.into); err != nil {
+			return nil, err
+		}
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("JWK exponent is too large")
+	}
+
+	privateKey := &rsa.PrivateKey{
+		PublicKey: rsa.PublicKey{N: &n, E: int(e.Int64())},
+		D:         &d,
+		Primes:    []*big.Int{&p, &q},
+	}
+	if err := privateKey.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid RSA JWK: %w", err)
+	}
+	privateKey.Precompute()
+	return privateKey, nil
+}
+
+func (j JWK) ecdsaPrivateKey() (*ecdsa.PrivateKey, error) {
+	curve, err := ParseCurve(j.Crv)
+	if err != nil {
+		return nil, err
+	}
+
+	var x, y, d big.Int
+	for _, field := range []struct {
+		name  string
+		value string
+		into  *big.Int
+	}{{"x", j.X, &x}, {"y", j.Y, &y}, {"d", j.D, &d}} {
+		if err := decodeJWKInt(field.name, field.value, field.into); err != nil {
+			return nil, err
+		}
+	}
+
+	// Let crypto/ecdh check the scalar and derive the public point it belongs to
+	ecdhCurve := map[string]ecdh.Curve{"P-256": ecdh.P256(), "P-384": ecdh.P384(), "P-521": ecdh.P521()}[j.Crv]
+	size := (curve.Params().BitSize + 7) / 8
+	if len(d.Bytes()) > size {
+		return nil, errors.New("invalid EC JWK: private scalar too large")
+	}
+	ecdhKey, err := ecdhCurve.NewPrivateKey(d.FillBytes(make([]byte, size)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid EC JWK: %w", err)
+	}
+
+	point := ecdhKey.PublicKey().Bytes()
+	if new(big.Int).SetBytes(point[1:1+size]).Cmp(&x) != 0 || new(big.Int).SetBytes(point[1+size:]).Cmp(&y) != 0 {
+		return nil, errors.New("invalid EC JWK: public point does not match the private key")
+	}
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve, X: &x, Y: &y},
+		D:         &d,
+	}, nil
+}
+
+func (j JWK) ed25519PrivateKey() (ed25519.PrivateKey, error) {
+	if j.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported JWK curve: %s", j.Crv)
+	}
+
+	seed, err := base64.RawURLEncoding.DecodeString(j.D)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, errors.New("invalid Ed25519 JWK: d is not a 32 byte seed")
+	}
+
+	privateKey := ed25519.NewKeyFromSeed(seed)
+	if j.X != "" && j.X != base64.RawURLEncoding.EncodeToString(privateKey.Public().(ed25519.PublicKey)) {
+		return nil, errors.New("invalid Ed25519 JWK: public key does not match the private key")
+	}
+	return privateKey, nil
+}
+
+func decodeJWKInt(name string, value string, into *big.Int) error {
+	if value == "" {
+		return fmt.Errorf("JWK is missing %s", name)
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return fmt.Errorf("JWK %s is not base64url encoded: %w", name, err)
+	}
+	into.SetBytes(decoded)
+	return nil
+}
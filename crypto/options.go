@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	_ "crypto/sha256" // Registers SHA-256 for crypto.Hash.New
+	_ "crypto/sha512" // Registers SHA-384 and SHA-512 for crypto.Hash.New
+	"fmt"
+)
+
+// RSAPadding selects the RSA signature scheme.
+type RSAPadding string
+
+const (
+	RSAPaddingPSS      RSAPadding = "PSS"
+	RSAPaddingPKCS1v15 RSAPadding = "PKCS1v15"
+)
+
+// SignatureOptions configure how data is hashed and padded before it is signed or verified.
+type SignatureOptions struct {
+	// Hash is applied to the data before signing. For RSA the zero value means SHA-256,
+	// for ECC it means the data is signed as is, which is how devices created before
+	// hashes were configurable sign.
+	Hash crypto.Hash
+	// Padding is only used by RSA, the zero value means RSAPaddingPSS.
+	Padding RSAPadding
+}
+
+// ParseHash maps a hash name like "SHA-256" to a crypto.Hash.
+func ParseHash(name string) (crypto.Hash, error) {
+	switch name {
+	case "SHA-256":
+		return crypto.SHA256, nil
+	case "SHA-384":
+		return crypto.SHA384, nil
+	case "SHA-512":
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("unsupported hash: %s", name)
+	}
+}
+
+// ParseCurve maps a curve name like "P-256" to an elliptic.Curve.
+func ParseCurve(name string) (elliptic.Curve, error) {
+	switch name {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	case "P-521":
+		return elliptic.P521(), nil
+	default:
+		return nil, fmt.Errorf("unsupported curve: %s", name)
+	}
+}
+
+// digest hashes data with hash.
+func digest(hash crypto.Hash, data []byte) []byte {
+	hasher := hash.New()
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+func (o SignatureOptions) rsaHash() crypto.Hash {
+	if o.Hash == 0 {
+		return crypto.SHA256
+	}
+	return o.Hash
+}
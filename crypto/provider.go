@@ -0,0 +1,287 @@
+package crypto
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/google/uuid"
+)
+
+// KeySpec names the algorithm and parameters of a key. It is stored on the device next to
+// the key reference, so a KeyProvider does not have to remember it.
+type KeySpec struct {
+	Algorithm  string
+	Parameters domain.KeyParameters
+}
+
+// KeyProvider owns private keys and signs with them. Callers only ever hold a reference
+// to a key, which is opaque to them.
+type KeyProvider interface {
+	// CreateKey generates a key pair and returns the reference to it.
+	CreateKey(spec KeySpec) (string, error)
+	// ImportKey takes over a private key as encoded by Algorithm.Import and returns the reference to it.
+	ImportKey(spec KeySpec, privateKey []byte) (string, error)
+	// PublicKey returns the encoded public key of the referenced key.
+	PublicKey(spec KeySpec, reference string) ([]byte, error)
+	// SignDigest signs a digest computed by Algorithm.Digest with the referenced key.
+	SignDigest(spec KeySpec, reference string, digest []byte) ([]byte, error)
+	// DeleteKey destroys the referenced key, e.g. a key created for a device change that failed.
+	// Deleting a key that does not exist is not an error.
+	DeleteKey(reference string) error
+}
+
+// LocalKeyProvider keeps keys inside the process. The reference is the encoded private key
+// itself, sealed if a KeySealer is set, so the provider has no state of its own.
+type LocalKeyProvider struct {
+	sealer *KeySealer
+}
+
+// NewLocalKeyProvider creates a LocalKeyProvider, private keys are left unencrypted if sealer is nil.
+func NewLocalKeyProvider(sealer *KeySealer) *LocalKeyProvider {
+	return &LocalKeyProvider{sealer: sealer}
+}
+
+func (l *LocalKeyProvider) CreateKey(spec KeySpec) (string, error) {
+	algorithm, err := LookupAlgorithm(spec.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	_, privateKey, err := algorithm.Generate(spec.Parameters)
+	if err != nil {
+		return "", err
+	}
+
+	return l.ImportKey(spec, privateKey)
+}
+
+func (l *LocalKeyProvider) ImportKey(spec KeySpec, privateKey []byte) (string, error) {
+	if l.sealer == nil {
+		return string(privateKey), nil
+	}
+
+	sealedKey, err := l.sealer.Seal(privateKey)
+	if err != nil {
+		return "", err
+	}
+	return string(sealedKey), nil
+}
+
+func (l *LocalKeyProvider) PublicKey(spec KeySpec, reference string) ([]byte, error) {
+	signer, err := l.signer(spec, reference)
+	if err != nil {
+		return nil, err
+	}
+	return signer.PublicKey()
+}
+
+func (l *LocalKeyProvider) SignDigest(spec KeySpec, reference string, digest []byte) ([]byte, error) {
+	signer, err := l.signer(spec, reference)
+	if err != nil {
+		return nil, err
+	}
+	return signer.SignDigest(digest)
+}
+
+// NeedsRewrap reports whether the referenced key is not sealed under the current master key.
+func (l *LocalKeyProvider) NeedsRewrap(reference string) bool {
+	return l.sealer != nil && l.sealer.NeedsRewrap([]byte(reference))
+}
+
+// Rewrap returns the reference of the same key sealed under the current master key.
+func (l *LocalKeyProvider) Rewrap(reference string) (string, error) {
+	if l.sealer == nil {
+		return reference, nil
+	}
+
+	privateKey, err := l.sealer.Rewrap([]byte(reference))
+	if err != nil {
+		return "", err
+	}
+	return string(privateKey), nil
+}
+
+// DeleteKey has nothing to do, the key only exists in its reference.
+func (l *LocalKeyProvider) DeleteKey(reference string) error {
+	return nil
+}
+
+func (l *LocalKeyProvider) signer(spec KeySpec, reference string) (KeyPairSigner, error) {
+	algorithm, err := LookupAlgorithm(spec.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey := []byte(reference)
+	if l.sealer != nil {
+		privateKey, err = l.sealer.Open(privateKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return algorithm.NewSigner(privateKey, spec.Parameters)
+}
+
+// FileKeyProvider stores the keys of a LocalKeyProvider in a directory, one file per key.
+// The reference handed out is the random name of the file, so it reveals nothing about the key.
+type FileKeyProvider struct {
+	dir   string
+	local *LocalKeyProvider
+}
+
+// NewFileKeyProvider creates a FileKeyProvider keeping its keys in dir.
+func NewFileKeyProvider(dir string, local *LocalKeyProvider) (*FileKeyProvider, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	return &FileKeyProvider{
+		dir:   dir,
+		local: local,
+	}, nil
+}
+
+func (f *FileKeyProvider) CreateKey(spec KeySpec) (string, error) {
+	localReference, err := f.local.CreateKey(spec)
+	if err != nil {
+		return "", err
+	}
+	return f.store(localReference)
+}
+
+func (f *FileKeyProvider) ImportKey(spec KeySpec, privateKey []byte) (string, error) {
+	localReference, err := f.local.ImportKey(spec, privateKey)
+	if err != nil {
+		return "", err
+	}
+	return f.store(localReference)
+}
+
+// store writes a new key under a fresh reference.
+func (f *FileKeyProvider) store(localReference string) (string, error) {
+	reference := uuid.New().String()
+	if err := f.write(reference, localReference); err != nil {
+		return "", err
+	}
+	return reference, nil
+}
+
+func (f *FileKeyProvider) PublicKey(spec KeySpec, reference string) ([]byte, error) {
+	localReference, err := f.read(reference)
+	if err != nil {
+		return nil, err
+	}
+	return f.local.PublicKey(spec, localReference)
+}
+
+func (f *FileKeyProvider) SignDigest(spec KeySpec, reference string, digest []byte) ([]byte, error) {
+	localReference, err := f.read(reference)
+	if err != nil {
+		return nil, err
+	}
+	return f.local.SignDigest(spec, localReference, digest)
+}
+
+func (f *FileKeyProvider) DeleteKey(reference string) error {
+	path, err := f.path(reference)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// RewrapKeys seals every stored key under the current master key of the LocalKeyProvider.
+// It returns the number of keys that were rewritten.
+func (f *FileKeyProvider) RewrapKeys() (int, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	rewrapped := 0
+	for _, entry := range entries {
+		reference, isKey := strings.CutSuffix(entry.Name(), ".pem")
+		if entry.IsDir() || !isKey {
+			continue
+		}
+
+		localReference, err := f.read(reference)
+		if err != nil {
+			return rewrapped, err
+		}
+		if !f.local.NeedsRewrap(localReference) {
+			continue
+		}
+
+		localReference, err = f.local.Rewrap(localReference)
+		if err != nil {
+			return rewrapped, err
+		}
+		if err := f.write(reference, localReference); err != nil {
+			return rewrapped, err
+		}
+		rewrapped++
+	}
+
+	return rewrapped, nil
+}
+
+// path maps a reference to its file, references are checked to be UUIDs so they cannot escape dir.
+func (f *FileKeyProvider) path(reference string) (string, error) {
+	if _, err := uuid.Parse(reference); err != nil {
+		return "", errors.New("invalid key reference")
+	}
+	return filepath.Join(f.dir, reference+".pem"), nil
+}
+
+func (f *FileKeyProvider) read(reference string) (string, error) {
+	path, err := f.path(reference)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", errors.New("key not found")
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// write replaces the key file atomically, a crash leaves either the old or the new key behind.
+func (f *FileKeyProvider) write(reference string, localReference string) error {
+	path, err := f.path(reference)
+	if err != nil {
+		return err
+	}
+
+	temporary, err := os.CreateTemp(f.dir, ".key-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+
+	if _, err := temporary.WriteString(localReference); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Sync(); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary.Name(), path)
+}
@@ -0,0 +1,93 @@
+package crypto
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// resolvedSpec returns the spec of a key of algorithm with its default parameters.
+func resolvedSpec(algorithm string) KeySpec {
+	registered, err := LookupAlgorithm(algorithm)
+	Expect(err).NotTo(HaveOccurred())
+	parameters, err := registered.ResolveParameters(domain.KeyParameters{})
+	Expect(err).NotTo(HaveOccurred())
+	return KeySpec{Algorithm: algorithm, Parameters: parameters}
+}
+
+// expectToSign signs a message with the referenced key of provider and verifies the signature
+// against the public key the provider hands out.
+func expectToSign(provider KeyProvider, spec KeySpec, reference string) {
+	algorithm, err := LookupAlgorithm(spec.Algorithm)
+	Expect(err).NotTo(HaveOccurred())
+	digest, err := algorithm.Digest([]byte("test-data"), spec.Parameters)
+	Expect(err).NotTo(HaveOccurred())
+
+	signature, err := provider.SignDigest(spec, reference, digest)
+	Expect(err).NotTo(HaveOccurred())
+	publicKey, err := provider.PublicKey(spec, reference)
+	Expect(err).NotTo(HaveOccurred())
+	verifier, err := algorithm.NewVerifier(publicKey, spec.Parameters)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(verifier.Verify([]byte("test-data"), signature)).To(Succeed())
+}
+
+var _ = Describe("File Key Provider", func() {
+	var (
+		keyDir    string
+		masterKey *MasterKey
+		provider  *FileKeyProvider
+	)
+
+	BeforeEach(func() {
+		var err error
+		keyDir = GinkgoT().TempDir()
+		masterKey, err = NewMasterKey(bytes.Repeat([]byte{1}, masterKeySize))
+		Expect(err).NotTo(HaveOccurred())
+		provider, err = NewFileKeyProvider(keyDir, NewLocalKeyProvider(NewKeySealer(masterKey)))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should keep every key sealed in a file of its own", func() {
+		spec := resolvedSpec("ECC")
+		reference, err := provider.CreateKey(spec)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reference).NotTo(ContainSubstring("PRIVATE"))
+
+		content, err := os.ReadFile(filepath.Join(keyDir, reference+".pem"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(IsSealed(content)).To(BeTrue())
+		expectToSign(provider, spec, reference)
+
+		Expect(provider.DeleteKey(reference)).To(Succeed())
+		Expect(filepath.Join(keyDir, reference+".pem")).NotTo(BeAnExistingFile())
+		Expect(provider.DeleteKey(reference)).To(Succeed())
+	})
+
+	It("should reject references outside of the key directory", func() {
+		_, err := provider.SignDigest(resolvedSpec("Ed25519"), "../journal", []byte("test-data"))
+		Expect(err).To(MatchError(ContainSubstring("invalid key reference")))
+	})
+
+	It("should rewrap the stored keys under a new master key", func() {
+		spec := resolvedSpec("Ed25519")
+		reference, err := provider.CreateKey(spec)
+		Expect(err).NotTo(HaveOccurred())
+
+		newMasterKey, err := NewMasterKey(bytes.Repeat([]byte{2}, masterKeySize))
+		Expect(err).NotTo(HaveOccurred())
+		provider, err = NewFileKeyProvider(keyDir, NewLocalKeyProvider(NewKeySealer(newMasterKey, masterKey)))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(provider.RewrapKeys()).To(Equal(1))
+		Expect(provider.RewrapKeys()).To(Equal(0))
+
+		// The old master key is no longer needed
+		provider, err = NewFileKeyProvider(keyDir, NewLocalKeyProvider(NewKeySealer(newMasterKey)))
+		Expect(err).NotTo(HaveOccurred())
+		expectToSign(provider, spec, reference)
+	})
+})
@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Formats a public key can be exported in.
+const (
+	PublicKeyFormatPEM = "pem"
+	PublicKeyFormatJWK = "jwk"
+	PublicKeyFormatDER = "der"
+)
+
+// ParsePublicKey decodes a PEM encoded public key into an *rsa.PublicKey, *ecdsa.PublicKey
+// or ed25519.PublicKey. Besides SPKI and PKCS#1 under the standard labels it reads the
+// RSA_PUBLIC_KEY and PUBLIC_KEY blocks stored by earlier versions.
+func ParsePublicKey(encoded []byte) (any, error) {
+	block, _ := pem.Decode(encoded)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY", "PUBLIC_KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY", "RSA_PUBLIC_KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported public key block: %s", block.Type)
+	}
+}
+
+// MarshalPublicKeyDER encodes a public key as DER SubjectPublicKeyInfo.
+func MarshalPublicKeyDER(publicKey any) ([]byte, error) {
+	return x509.MarshalPKIXPublicKey(publicKey)
+}
+
+// MarshalPublicKeyPEM encodes a public key as a standard "PUBLIC KEY" PEM block.
+func MarshalPublicKeyPEM(publicKey any) ([]byte, error) {
+	der, err := MarshalPublicKeyDER(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: der,
+	}), nil
+}
+
+// StandardPublicKeyPEM re-encodes a stored PEM public key under the standard label, so devices
+// created before the labels were fixed are shown the same way as new ones. A key that cannot be
+// parsed is returned as it is.
+func StandardPublicKeyPEM(publicKey string) string {
+	parsed, err := ParsePublicKey([]byte(publicKey))
+	if err != nil {
+		return publicKey
+	}
+
+	encoded, err := MarshalPublicKeyPEM(parsed)
+	if err != nil {
+		return publicKey
+	}
+	return string(encoded)
+}
+
+// NewPublicJWK describes a public key as a JWK, without kid, use and alg.
+func NewPublicJWK(publicKey any) (JWK, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		// Coordinates are padded to the size of the curve as RFC 7518 requires
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return JWK{
+			Kty: "EC",
+			Crv: key.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type: %T", publicKey)
+	}
+}
@@ -0,0 +1,76 @@
+package crypto
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+// Algorithm bundles the generator, marshaler, signer and verifier of a signature scheme.
+// Keys cross this interface PEM encoded, the way they are stored on a domain.Device.
+type Algorithm interface {
+	// Name identifies the algorithm, e.g. in domain.Device.Algorithm.
+	Name() string
+	// ResolveParameters validates the key parameters for this algorithm and fills in defaults.
+	ResolveParameters(parameters domain.KeyParameters) (domain.KeyParameters, error)
+	// Generate creates a new key pair and returns the encoded public and private key.
+	Generate(parameters domain.KeyParameters) ([]byte, []byte, error)
+	// Import checks that an externally generated private key belongs to this algorithm and is
+	// strong enough. It returns the parameters completed from the key and the encoded private key.
+	Import(privateKey []byte, parameters domain.KeyParameters) (domain.KeyParameters, []byte, error)
+	// Digest prepares a message for KeyPairSigner.SignDigest. Schemes that cannot sign a
+	// digest computed elsewhere, like Ed25519, return the message unchanged.
+	Digest(message []byte, parameters domain.KeyParameters) ([]byte, error)
+	NewSigner(privateKey []byte, parameters domain.KeyParameters) (KeyPairSigner, error)
+	NewVerifier(publicKey []byte, parameters domain.KeyParameters) (Verifier, error)
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Algorithm)
+)
+
+func init() {
+	Register(RSAAlgorithm{})
+	Register(ECCAlgorithm{})
+	Register(Ed25519Algorithm{})
+}
+
+// Register makes an algorithm available under its name.
+// It panics if an algorithm with the same name is already registered.
+func Register(algorithm Algorithm) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, exists := registry[algorithm.Name()]; exists {
+		panic(fmt.Sprintf("crypto: algorithm %s registered twice", algorithm.Name()))
+	}
+	registry[algorithm.Name()] = algorithm
+}
+
+// LookupAlgorithm returns the algorithm registered under name.
+func LookupAlgorithm(name string) (Algorithm, error) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	algorithm, exists := registry[name]
+	if !exists {
+		return nil, fmt.Errorf("unsupported algorithm: %s", name)
+	}
+	return algorithm, nil
+}
+
+// Algorithms returns the names of all registered algorithms in alphabetical order.
+func Algorithms() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
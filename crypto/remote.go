@@ -0,0 +1,233 @@
+package crypto
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"sync"
+)
+
+const keyServiceName = "KeyService"
+
+// KeyService exposes a KeyProvider over net/rpc. It is what the signer daemon serves on its socket.
+type KeyService struct {
+	provider KeyProvider
+}
+
+type CreateKeyRequest struct {
+	Spec KeySpec
+}
+
+type CreateKeyResponse struct {
+	Reference string
+}
+
+type ImportKeyRequest struct {
+	Spec       KeySpec
+	PrivateKey []byte
+}
+
+type ImportKeyResponse struct {
+	Reference string
+}
+
+type PublicKeyRequest struct {
+	Spec      KeySpec
+	Reference string
+}
+
+type PublicKeyResponse struct {
+	PublicKey []byte
+}
+
+type SignDigestRequest struct {
+	Spec      KeySpec
+	Reference string
+	Digest    []byte
+}
+
+type SignDigestResponse struct {
+	Signature []byte
+}
+
+type DeleteKeyRequest struct {
+	Reference string
+}
+
+type DeleteKeyResponse struct{}
+
+func (k *KeyService) CreateKey(request CreateKeyRequest, response *CreateKeyResponse) error {
+	reference, err := k.provider.CreateKey(request.Spec)
+	if err != nil {
+		return err
+	}
+	response.Reference = reference
+	return nil
+}
+
+func (k *KeyService) ImportKey(request ImportKeyRequest, response *ImportKeyResponse) error {
+	reference, err := k.provider.ImportKey(request.Spec, request.PrivateKey)
+	if err != nil {
+		return err
+	}
+	response.Reference = reference
+	return nil
+}
+
+func (k *KeyService) PublicKey(request PublicKeyRequest, response *PublicKeyResponse) error {
+	publicKey, err := k.provider.PublicKey(request.Spec, request.Reference)
+	if err != nil {
+		return err
+	}
+	response.PublicKey = publicKey
+	return nil
+}
+
+func (k *KeyService) SignDigest(request SignDigestRequest, response *SignDigestResponse) error {
+	signature, err := k.provider.SignDigest(request.Spec, request.Reference, request.Digest)
+	if err != nil {
+		return err
+	}
+	response.Signature = signature
+	return nil
+}
+
+func (k *KeyService) DeleteKey(request DeleteKeyRequest, response *DeleteKeyResponse) error {
+	return k.provider.DeleteKey(request.Reference)
+}
+
+// ServeKeyProvider answers the requests of RemoteKeyProviders with provider until listener is closed.
+func ServeKeyProvider(listener net.Listener, provider KeyProvider) error {
+	server := rpc.NewServer()
+	if err := server.RegisterName(keyServiceName, &KeyService{provider}); err != nil {
+		return err
+	}
+
+	server.Accept(listener)
+	return nil
+}
+
+// RemoteKeyProvider forwards every call to a KeyService, so no key material enters this process.
+// A connection lost to a restart of the signer daemon is dialed again on the next call.
+type RemoteKeyProvider struct {
+	network string
+	address string
+
+	mutex  sync.Mutex
+	client *rpc.Client
+}
+
+// DialKeyProvider connects to the KeyService listening on address, e.g. "unix" and "signer.sock".
+func DialKeyProvider(network string, address string) (*RemoteKeyProvider, error) {
+	remote := &RemoteKeyProvider{
+		network: network,
+		address: address,
+	}
+
+	if _, err := remote.connect(); err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+func (r *RemoteKeyProvider) CreateKey(spec KeySpec) (string, error) {
+	var response CreateKeyResponse
+	if err := r.call("CreateKey", CreateKeyRequest{Spec: spec}, &response); err != nil {
+		return "", err
+	}
+	return response.Reference, nil
+}
+
+func (r *RemoteKeyProvider) ImportKey(spec KeySpec, privateKey []byte) (string, error) {
+	var response ImportKeyResponse
+	if err := r.call("ImportKey", ImportKeyRequest{Spec: spec, PrivateKey: privateKey}, &response); err != nil {
+		return "", err
+	}
+	return response.Reference, nil
+}
+
+func (r *RemoteKeyProvider) PublicKey(spec KeySpec, reference string) ([]byte, error) {
+	var response PublicKeyResponse
+	if err := r.call("PublicKey", PublicKeyRequest{Spec: spec, Reference: reference}, &response); err != nil {
+		return nil, err
+	}
+	return response.PublicKey, nil
+}
+
+func (r *RemoteKeyProvider) SignDigest(spec KeySpec, reference string, digest []byte) ([]byte, error) {
+	var response SignDigestResponse
+	request := SignDigestRequest{Spec: spec, Reference: reference, Digest: digest}
+	if err := r.call("SignDigest", request, &response); err != nil {
+		return nil, err
+	}
+	return response.Signature, nil
+}
+
+func (r *RemoteKeyProvider) DeleteKey(reference string) error {
+	return r.call("DeleteKey", DeleteKeyRequest{Reference: reference}, &DeleteKeyResponse{})
+}
+
+// Close closes the connection to the KeyService.
+func (r *RemoteKeyProvider) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.client == nil {
+		return nil
+	}
+	err := r.client.Close()
+	r.client = nil
+	return err
+}
+
+// call invokes method on the KeyService. A connection that failed is dropped, so the next
+// call dials again. rpc.ErrShutdown means the request was never sent, so it is retried at once.
+func (r *RemoteKeyProvider) call(method string, request any, response any) error {
+	client, err := r.connect()
+	if err != nil {
+		return err
+	}
+
+	err = client.Call(keyServiceName+"."+method, request, response)
+	var serverError rpc.ServerError
+	if err == nil || errors.As(err, &serverError) {
+		return err
+	}
+
+	r.disconnect(client)
+	if !errors.Is(err, rpc.ErrShutdown) {
+		return err
+	}
+
+	client, err = r.connect()
+	if err != nil {
+		return err
+	}
+	return client.Call(keyServiceName+"."+method, request, response)
+}
+
+func (r *RemoteKeyProvider) connect() (*rpc.Client, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.client != nil {
+		return r.client, nil
+	}
+
+	client, err := rpc.Dial(r.network, r.address)
+	if err != nil {
+		return nil, err
+	}
+	r.client = client
+	return client, nil
+}
+
+func (r *RemoteKeyProvider) disconnect(client *rpc.Client) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.client == client {
+		r.client.Close()
+		r.client = nil
+	}
+}
@@ -0,0 +1,125 @@
+package crypto
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// daemonListener closes the accepted connections along with itself, like an exiting signer daemon.
+type daemonListener struct {
+	net.Listener
+	mutex sync.Mutex
+	conns []net.Conn
+}
+
+func (d *daemonListener) Accept() (net.Conn, error) {
+	conn, err := d.Listener.Accept()
+	if err == nil {
+		d.mutex.Lock()
+		d.conns = append(d.conns, conn)
+		d.mutex.Unlock()
+	}
+	return conn, err
+}
+
+func (d *daemonListener) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for _, conn := range d.conns {
+		conn.Close()
+	}
+	return d.Listener.Close()
+}
+
+var _ = Describe("Remote Key Provider", func() {
+	var (
+		keyDir   string
+		listener net.Listener
+		provider *RemoteKeyProvider
+	)
+
+	startDaemon := func(address string) {
+		fileProvider, err := NewFileKeyProvider(keyDir, NewLocalKeyProvider(nil))
+		Expect(err).NotTo(HaveOccurred())
+
+		unixListener, err := net.Listen("unix", address)
+		Expect(err).NotTo(HaveOccurred())
+		listener = &daemonListener{Listener: unixListener}
+		go ServeKeyProvider(listener, fileProvider)
+	}
+
+	BeforeEach(func() {
+		// Socket paths are limited in length, the test temp dir can be too deep
+		socketDir, err := os.MkdirTemp("", "signerd")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, socketDir)
+
+		// Stand-in for the signer daemon
+		keyDir = GinkgoT().TempDir()
+		startDaemon(filepath.Join(socketDir, "signer.sock"))
+		DeferCleanup(func() {
+			listener.Close()
+		})
+
+		provider, err = DialKeyProvider("unix", listener.Addr().String())
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(provider.Close)
+	})
+
+	DescribeTable("should sign with keys held by the signer daemon",
+		func(algorithm string) {
+			spec := resolvedSpec(algorithm)
+			reference, err := provider.CreateKey(spec)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(reference).NotTo(ContainSubstring("PRIVATE"))
+			Expect(filepath.Join(keyDir, reference+".pem")).To(BeAnExistingFile())
+
+			expectToSign(provider, spec, reference)
+		},
+		Entry("RSA", "RSA"),
+		Entry("ECC", "ECC"),
+		Entry("Ed25519", "Ed25519"),
+	)
+
+	It("should import keys and delete them on request", func() {
+		spec := resolvedSpec("Ed25519")
+		algorithm, err := LookupAlgorithm(spec.Algorithm)
+		Expect(err).NotTo(HaveOccurred())
+		_, privateKey, err := algorithm.Generate(spec.Parameters)
+		Expect(err).NotTo(HaveOccurred())
+
+		reference, err := provider.ImportKey(spec, privateKey)
+		Expect(err).NotTo(HaveOccurred())
+		expectToSign(provider, spec, reference)
+
+		Expect(provider.DeleteKey(reference)).To(Succeed())
+		_, err = provider.PublicKey(spec, reference)
+		Expect(err).To(MatchError(ContainSubstring("key not found")))
+	})
+
+	It("should reconnect after the signer daemon restarted", func() {
+		spec := resolvedSpec("Ed25519")
+		reference, err := provider.CreateKey(spec)
+		Expect(err).NotTo(HaveOccurred())
+
+		address := listener.Addr().String()
+		Expect(listener.Close()).To(Succeed())
+		_, err = provider.PublicKey(spec, reference)
+		Expect(err).To(HaveOccurred())
+
+		startDaemon(address)
+		_, err = provider.PublicKey(spec, reference)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject references outside of the key directory", func() {
+		_, err := provider.SignDigest(resolvedSpec("Ed25519"), "../journal", []byte("test-data"))
+		Expect(err).To(MatchError(ContainSubstring("invalid key reference")))
+	})
+})
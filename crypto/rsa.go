@@ -4,6 +4,7 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 )
 
 // RSAKeyPair is a DTO that holds RSA private and public keys.
@@ -22,19 +23,19 @@ func NewRSAMarshaler() RSAMarshaler {
 
 // Marshal takes an RSAKeyPair and encodes it to be written on disk.
 // It returns the public and the private key as a byte slice.
+// The private key is PKCS#1 and the public key SPKI encoded.
 func (m *RSAMarshaler) Marshal(keyPair RSAKeyPair) ([]byte, []byte, error) {
 	privateKeyBytes := x509.MarshalPKCS1PrivateKey(keyPair.Private)
-	publicKeyBytes := x509.MarshalPKCS1PublicKey(keyPair.Public)
 
 	encodedPrivate := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA_PRIVATE_KEY",
+		Type:  "RSA PRIVATE KEY",
 		Bytes: privateKeyBytes,
 	})
 
-	encodePublic := pem.EncodeToMemory(&pem.Block{
-		Type:  "RSA_PUBLIC_KEY",
-		Bytes: publicKeyBytes,
-	})
+	encodePublic, err := MarshalPublicKeyPEM(keyPair.Public)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	return encodePublic, encodedPrivate, nil
 }
@@ -42,6 +43,10 @@ func (m *RSAMarshaler) Marshal(keyPair RSAKeyPair) ([]byte, []byte, error) {
 // Unmarshal takes an encoded RSA private key and transforms it into a rsa.PrivateKey.
 func (m *RSAMarshaler) Unmarshal(privateKeyBytes []byte) (*RSAKeyPair, error) {
 	block, _ := pem.Decode(privateKeyBytes)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+
 	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
 	if err != nil {
 		return nil, err
@@ -52,3 +57,36 @@ func (m *RSAMarshaler) Unmarshal(privateKeyBytes []byte) (*RSAKeyPair, error) {
 		Public:  &privateKey.PublicKey,
 	}, nil
 }
+
+// UnmarshalPublic takes an encoded RSA public key and transforms it into a rsa.PublicKey.
+// Both SPKI and the PKCS#1 keys stored by earlier versions are accepted.
+func (m *RSAMarshaler) UnmarshalPublic(publicKeyBytes []byte) (*rsa.PublicKey, error) {
+	publicKey, err := ParsePublicKey(publicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPublicKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public key is not an RSA key")
+	}
+	return rsaPublicKey, nil
+}
+
+// Parse assembles an RSAKeyPair from an externally generated private key in PKCS#1, PKCS#8 or JWK encoding.
+func (m *RSAMarshaler) Parse(privateKeyBytes []byte) (*RSAKeyPair, error) {
+	privateKey, err := parsePrivateKey(privateKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+
+	return &RSAKeyPair{
+		Private: rsaPrivateKey,
+		Public:  &rsaPrivateKey.PublicKey,
+	}, nil
+}
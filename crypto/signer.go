@@ -1,11 +1,10 @@
 package crypto
 
 import (
-	"crypto"
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha256"
 )
 
 // Signer defines a contract for different types of signing implementations.
@@ -13,26 +12,64 @@ type Signer interface {
 	Sign(dataToBeSigned []byte) ([]byte, error)
 }
 
+// KeyPairSigner is a Signer holding a whole key pair, as used inside a KeyProvider.
+type KeyPairSigner interface {
+	Signer
+	// SignDigest signs a digest computed by Algorithm.Digest.
+	SignDigest(digest []byte) ([]byte, error)
+	// PublicKey returns the encoded public key of the pair.
+	PublicKey() ([]byte, error)
+}
+
 type RSASigner struct {
 	keyPair *RSAKeyPair
+	options SignatureOptions
 }
 
 type ECCSigner struct {
 	keyPair *ECCKeyPair
+	options SignatureOptions
+}
+
+type Ed25519Signer struct {
+	keyPair *Ed25519KeyPair
 }
 
 func NewRSASigner(keyPair *RSAKeyPair) *RSASigner {
-	return &RSASigner{keyPair}
+	return &RSASigner{keyPair: keyPair}
+}
+
+func NewRSASignerWithOptions(keyPair *RSAKeyPair, options SignatureOptions) *RSASigner {
+	return &RSASigner{keyPair, options}
 }
 
 func NewECCSigner(keyPair *ECCKeyPair) *ECCSigner {
-	return &ECCSigner{keyPair}
+	return &ECCSigner{keyPair: keyPair}
+}
+
+func NewECCSignerWithOptions(keyPair *ECCKeyPair, options SignatureOptions) *ECCSigner {
+	return &ECCSigner{keyPair, options}
+}
+
+func NewEd25519Signer(keyPair *Ed25519KeyPair) *Ed25519Signer {
+	return &Ed25519Signer{keyPair}
 }
 
 func (r *RSASigner) Sign(dataToBeSigned []byte) ([]byte, error) {
-	// Hash the data with SHA256 before signing
-	hashed := sha256.Sum256(dataToBeSigned)
-	signature, err := rsa.SignPSS(rand.Reader, r.keyPair.Private, crypto.SHA256, hashed[:], nil)
+	// Hash the data before signing
+	return r.SignDigest(digest(r.options.rsaHash(), dataToBeSigned))
+}
+
+func (r *RSASigner) SignDigest(hashed []byte) ([]byte, error) {
+	hash := r.options.rsaHash()
+
+	var signature []byte
+	var err error
+	if r.options.Padding == RSAPaddingPKCS1v15 {
+		signature, err = rsa.SignPKCS1v15(rand.Reader, r.keyPair.Private, hash, hashed)
+	} else {
+		signature, err = rsa.SignPSS(rand.Reader, r.keyPair.Private, hash, hashed, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -40,9 +77,44 @@ func (r *RSASigner) Sign(dataToBeSigned []byte) ([]byte, error) {
 }
 
 func (e *ECCSigner) Sign(dataToBeSigned []byte) ([]byte, error) {
-	signature, err := ecdsa.SignASN1(rand.Reader, e.keyPair.Private, dataToBeSigned)
+	if e.options.Hash != 0 {
+		dataToBeSigned = digest(e.options.Hash, dataToBeSigned)
+	}
+	return e.SignDigest(dataToBeSigned)
+}
+
+func (e *ECCSigner) SignDigest(hashed []byte) ([]byte, error) {
+	signature, err := ecdsa.SignASN1(rand.Reader, e.keyPair.Private, hashed)
 	if err != nil {
 		return nil, err
 	}
 	return signature, nil
-}
\ No newline at end of file
+}
+
+func (e *Ed25519Signer) Sign(dataToBeSigned []byte) ([]byte, error) {
+	// Ed25519 hashes the message internally, so it is signed as is
+	return ed25519.Sign(e.keyPair.Private, dataToBeSigned), nil
+}
+
+// SignDigest signs the message itself, Ed25519 cannot sign a digest computed elsewhere.
+func (e *Ed25519Signer) SignDigest(message []byte) ([]byte, error) {
+	return e.Sign(message)
+}
+
+func (r *RSASigner) PublicKey() ([]byte, error) {
+	marshaler := NewRSAMarshaler()
+	public, _, err := marshaler.Marshal(*r.keyPair)
+	return public, err
+}
+
+func (e *ECCSigner) PublicKey() ([]byte, error) {
+	marshaler := NewECCMarshaler()
+	public, _, err := marshaler.Encode(*e.keyPair)
+	return public, err
+}
+
+func (e *Ed25519Signer) PublicKey() ([]byte, error) {
+	marshaler := NewEd25519Marshaler()
+	public, _, err := marshaler.Encode(*e.keyPair)
+	return public, err
+}
@@ -0,0 +1,107 @@
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+)
+
+// ErrInvalidSignature is returned when a signature does not match the signed data.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// Verifier defines a contract for checking signatures created by the matching Signer.
+type Verifier interface {
+	Verify(signedData []byte, signature []byte) error
+}
+
+type RSAVerifier struct {
+	publicKey *rsa.PublicKey
+	options   SignatureOptions
+}
+
+type ECCVerifier struct {
+	publicKey *ecdsa.PublicKey
+	options   SignatureOptions
+}
+
+type Ed25519Verifier struct {
+	publicKey ed25519.PublicKey
+}
+
+func NewRSAVerifier(publicKey *rsa.PublicKey, options SignatureOptions) *RSAVerifier {
+	return &RSAVerifier{publicKey, options}
+}
+
+func NewECCVerifier(publicKey *ecdsa.PublicKey, options SignatureOptions) *ECCVerifier {
+	return &ECCVerifier{publicKey, options}
+}
+
+func NewEd25519Verifier(publicKey ed25519.PublicKey) *Ed25519Verifier {
+	return &Ed25519Verifier{publicKey}
+}
+
+// NewRSAVerifierFromPEM creates an RSAVerifier from a public key encoded by RSAMarshaler.Marshal.
+func NewRSAVerifierFromPEM(publicKeyBytes []byte, options SignatureOptions) (*RSAVerifier, error) {
+	marshaler := NewRSAMarshaler()
+	publicKey, err := marshaler.UnmarshalPublic(publicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	return NewRSAVerifier(publicKey, options), nil
+}
+
+// NewECCVerifierFromPEM creates an ECCVerifier from a public key encoded by ECCMarshaler.Encode.
+func NewECCVerifierFromPEM(publicKeyBytes []byte, options SignatureOptions) (*ECCVerifier, error) {
+	marshaler := NewECCMarshaler()
+	publicKey, err := marshaler.DecodePublic(publicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	return NewECCVerifier(publicKey, options), nil
+}
+
+// NewEd25519VerifierFromPEM creates an Ed25519Verifier from a public key encoded by Ed25519Marshaler.Encode.
+func NewEd25519VerifierFromPEM(publicKeyBytes []byte) (*Ed25519Verifier, error) {
+	marshaler := NewEd25519Marshaler()
+	publicKey, err := marshaler.DecodePublic(publicKeyBytes)
+	if err != nil {
+		return nil, err
+	}
+	return NewEd25519Verifier(publicKey), nil
+}
+
+func (r *RSAVerifier) Verify(signedData []byte, signature []byte) error {
+	// Hash the data like RSASigner does
+	hash := r.options.rsaHash()
+	hashed := digest(hash, signedData)
+
+	var err error
+	if r.options.Padding == RSAPaddingPKCS1v15 {
+		err = rsa.VerifyPKCS1v15(r.publicKey, hash, hashed, signature)
+	} else {
+		err = rsa.VerifyPSS(r.publicKey, hash, hashed, signature, nil)
+	}
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (e *ECCVerifier) Verify(signedData []byte, signature []byte) error {
+	if e.options.Hash != 0 {
+		signedData = digest(e.options.Hash, signedData)
+	}
+
+	if !ecdsa.VerifyASN1(e.publicKey, signedData, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (e *Ed25519Verifier) Verify(signedData []byte, signature []byte) error {
+	if !ed25519.Verify(e.publicKey, signedData, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
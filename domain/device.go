@@ -1,10 +1,80 @@
 package domain
 
+import (
+	"fmt"
+	"time"
+)
+
 type Device struct {
 	ID string
 	Algorithm string
 	PublicKey  string
-	PrivateKey  string
+	KeyReference string // Opaque handle of the private key inside its crypto.KeyProvider
 	SignatureCounter int
 	Label string
-}
\ No newline at end of file
+	KeyParameters KeyParameters
+	KeyHistory []RetiredKey // Keys the device signed with before its current one, oldest first
+	Status DeviceStatus // Empty for devices stored before statuses existed, which are active
+	Metadata map[string]string // Free-form attributes maintained by operators
+	CreatedAt time.Time // Zero for devices stored before creation times were recorded
+}
+
+// DeviceStatus is the lifecycle state of a device. Only active devices sign.
+type DeviceStatus string
+
+const (
+	DeviceStatusActive DeviceStatus = "active"
+	// DeviceStatusSuspended devices stop signing until they are activated again, e.g. a terminal reported lost.
+	DeviceStatusSuspended DeviceStatus = "suspended"
+	// DeviceStatusDecommissioned devices are archived for good, their chain stays readable.
+	DeviceStatusDecommissioned DeviceStatus = "decommissioned"
+)
+
+// ParseDeviceStatus checks that status names one of the DeviceStatus constants.
+func ParseDeviceStatus(status string) (DeviceStatus, error) {
+	switch DeviceStatus(status) {
+	case DeviceStatusActive, DeviceStatusSuspended, DeviceStatusDecommissioned:
+		return DeviceStatus(status), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidDeviceStatus, status)
+	}
+}
+
+// CurrentStatus returns the status of the device, devices stored before statuses existed are active.
+func (d *Device) CurrentStatus() DeviceStatus {
+	if d.Status == "" {
+		return DeviceStatusActive
+	}
+	return d.Status
+}
+
+// IsActive reports whether the device may sign.
+func (d *Device) IsActive() bool {
+	return d.CurrentStatus() == DeviceStatusActive
+}
+
+// RetiredKey is a public key a device signed with before its key was rotated. It signed the
+// counters FirstCounter to LastCounter, the last one being the rotation record that binds its successor.
+type RetiredKey struct {
+	PublicKey string
+	FirstCounter int
+	LastCounter int
+	RotatedAt time.Time
+}
+
+// CurrentKeyFirstCounter returns the first signature counter signed with the current key.
+func (d *Device) CurrentKeyFirstCounter() int {
+	if len(d.KeyHistory) == 0 {
+		return 0
+	}
+	return d.KeyHistory[len(d.KeyHistory)-1].LastCounter + 1
+}
+
+// KeyParameters describe how the key of a device was generated and how it signs.
+// Empty values stand for the defaults of the algorithm.
+type KeyParameters struct {
+	KeySize int // RSA modulus size in bits
+	Curve string // ECC curve, e.g. P-256
+	Hash string // Hash applied before signing, e.g. SHA-256
+	Padding string // RSA signature scheme, PSS or PKCS1v15
+}
@@ -0,0 +1,15 @@
+package domain
+
+import "errors"
+
+var (
+	// ErrDeviceNotActive is matched by errors for a device that was asked to sign while it is
+	// suspended or decommissioned.
+	ErrDeviceNotActive = errors.New("device is not active")
+
+	// ErrDeviceDecommissioned is matched by errors for a change to a decommissioned device.
+	ErrDeviceDecommissioned = errors.New("device is decommissioned")
+
+	// ErrInvalidDeviceStatus is wrapped by ParseDeviceStatus for a name that is no DeviceStatus.
+	ErrInvalidDeviceStatus = errors.New("unsupported device status")
+)
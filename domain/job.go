@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Job is a signing request processed in the background. Its items are signed in order,
+// after the items of earlier jobs of the same device.
+type Job struct {
+	ID string
+	DeviceID string
+	Data []string // The raw transaction data, in signing order
+	CallbackURL string // Notified once the job finished, empty for none
+	Status JobStatus
+	Signatures []*Signature // The signatures of Data once the job succeeded
+	Error string // Why the job failed, none of its items is signed then
+	CreatedAt time.Time
+	StartedAt time.Time // Zero while the job is queued
+	FinishedAt time.Time // Zero until the job succeeded or failed
+}
+
+// JobStatus is the processing state of a job.
+type JobStatus string
+
+const (
+	JobStatusQueued JobStatus = "queued"
+	JobStatusRunning JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed JobStatus = "failed"
+)
+
+// IsFinished reports whether the job will not change anymore.
+func (j *Job) IsFinished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed
+}
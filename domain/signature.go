@@ -1,8 +1,16 @@
 package domain
 
+import "time"
+
 type Signature struct {
 	ID string
 	DeviceID string
 	SignatureCounter int
 	SignatureValue string
-}
\ No newline at end of file
+	SignedData string // The exact <counter>_<data>_<last_signature> string that was signed
+	Data string // The raw transaction data
+	Algorithm string
+	PreviousSignatureID string // Empty for the first signature of a device
+	CreatedAt time.Time
+	IdempotencyKey string // Idempotency-Key of the request that created the signature, if any
+}
@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+// Events that webhooks can subscribe to.
+const (
+	EventDeviceCreated = "device.created"
+	EventDeviceDeactivated = "device.deactivated" // The device left the active status
+	EventSignatureCreated = "signature.created"
+	// EventJobFinished is posted to the callback URL of a job, webhooks cannot subscribe to it.
+	EventJobFinished = "job.finished"
+)
+
+// Events lists every event a webhook can subscribe to.
+var Events = []string{EventDeviceCreated, EventDeviceDeactivated, EventSignatureCreated}
+
+// Webhook is an endpoint that is told about events, either of a single device or of all devices.
+type Webhook struct {
+	ID string
+	URL string
+	Secret string // Key of the HMAC signature of every delivery
+	DeviceID string // Empty for webhooks receiving the events of all devices
+	Events []string // Empty for webhooks receiving every event
+	CreatedAt time.Time
+}
+
+// Subscribes reports whether the webhook receives event for the device.
+func (w *Webhook) Subscribes(event string, deviceID string) bool {
+	if w.DeviceID != "" && w.DeviceID != deviceID {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event waiting in the outbox to be delivered to a webhook, or to the
+// callback URL of a job. Deliveries are removed once the receiver accepted them.
+type WebhookDelivery struct {
+	ID string
+	WebhookID string // Empty for job callbacks
+	URL string // The callback URL of a job callback, webhook deliveries go to the URL of their webhook
+	EventID string // Shared by the deliveries of the same event to different webhooks
+	Event string
+	Payload string // The JSON body posted to the webhook
+	Status DeliveryStatus
+	Attempts int
+	NextAttemptAt time.Time
+	LastError string // Why the last attempt failed
+	CreatedAt time.Time
+}
+
+// IsCallback reports whether the delivery goes to the callback URL of a job rather than a webhook.
+func (d *WebhookDelivery) IsCallback() bool {
+	return d.WebhookID == ""
+}
+
+// DeliveryStatus tells whether a delivery is still attempted.
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending DeliveryStatus = "pending"
+	// DeliveryStatusDead deliveries ran out of attempts, they are kept until they are retried or their webhook is deleted.
+	DeliveryStatusDead DeliveryStatus = "dead"
+)
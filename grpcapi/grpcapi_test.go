@@ -0,0 +1,269 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/api"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	signingv1 "github.com/fiskaly/coding-challenges/signing-service-challenge/proto/signing/v1"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGRPCSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "gRPC API Suite")
+}
+
+var _ = Describe("gRPC API", func() {
+	var (
+		devices           *service.DeviceService
+		signing           *service.SigningService
+		webhookRepository persistence.IWebhookRepository
+		client            signingv1.SigningServiceClient
+		ctx               context.Context
+	)
+
+	createDevice := func() *signingv1.Device {
+		device, err := client.CreateDevice(ctx, &signingv1.CreateDeviceRequest{Algorithm: "Ed25519", Label: "till-1"})
+		Expect(err).NotTo(HaveOccurred())
+		return device
+	}
+
+	sign := func(deviceID string, data string) *signingv1.Signature {
+		response, err := client.SignTransaction(ctx, &signingv1.SignTransactionRequest{DeviceId: deviceID, Data: data})
+		Expect(err).NotTo(HaveOccurred())
+		return response.GetSignature()
+	}
+
+	BeforeEach(func() {
+		deviceRepository := persistence.NewDeviceRepository()
+		signatureRepository := persistence.NewSignatureRepository()
+		webhookRepository = persistence.NewWebhookRepository()
+		// Wired like main wires the gRPC API, sharing the services of the HTTP API
+		server := &api.Server{
+			DeviceRepository:    deviceRepository,
+			SignatureRepository: signatureRepository,
+			UnitOfWork:          persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, webhookRepository),
+			WebhookRepository:   webhookRepository,
+			SignatureBroker:     service.NewSignatureBroker(service.DefaultStreamBufferSize),
+		}
+		devices = server.DeviceService()
+		signing = server.SigningService()
+
+		listener := bufconn.Listen(1 << 20)
+		grpcServer := NewServer(devices, signing)
+		go grpcServer.Serve(listener)
+		DeferCleanup(grpcServer.Stop)
+
+		connection, err := grpc.NewClient("passthrough:///bufconn",
+			grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				return listener.Dial()
+			}),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(connection.Close)
+
+		client = signingv1.NewSigningServiceClient(connection)
+		ctx = context.Background()
+	})
+
+	It("should create and get a device", func() {
+		created := createDevice()
+		Expect(created.GetAlgorithm()).To(Equal("Ed25519"))
+		Expect(created.GetStatus()).To(Equal("active"))
+		Expect(created.GetPublicKey()).To(HavePrefix("-----BEGIN PUBLIC KEY-----"))
+
+		device, err := client.GetDevice(ctx, &signingv1.GetDeviceRequest{Id: created.GetId()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(proto.Equal(device, created)).To(BeTrue())
+	})
+
+	It("should answer NotFound for an unknown device", func() {
+		_, err := client.GetDevice(ctx, &signingv1.GetDeviceRequest{Id: "unknown"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+
+		_, err = client.SignTransaction(ctx, &signingv1.SignTransactionRequest{DeviceId: "unknown", Data: "receipt"})
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+	})
+
+	It("should reject an unsupported algorithm", func() {
+		_, err := client.CreateDevice(ctx, &signingv1.CreateDeviceRequest{Algorithm: "DSA"})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should page through the devices", func() {
+		for i := 0; i < 3; i++ {
+			createDevice()
+		}
+
+		first, err := client.ListDevices(ctx, &signingv1.ListDevicesRequest{PageSize: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.GetDevices()).To(HaveLen(2))
+		Expect(first.GetNextPageToken()).NotTo(BeEmpty())
+
+		second, err := client.ListDevices(ctx, &signingv1.ListDevicesRequest{PageSize: 2, PageToken: first.GetNextPageToken()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.GetDevices()).To(HaveLen(1))
+		Expect(second.GetNextPageToken()).To(BeEmpty())
+	})
+
+	It("should sign transactions and list the chain", func() {
+		device := createDevice()
+		first := sign(device.GetId(), "first")
+		second := sign(device.GetId(), "second")
+		Expect(second.GetSignatureCounter()).To(Equal(int64(1)))
+		Expect(second.GetPreviousSignatureId()).To(Equal(first.GetId()))
+		Expect(second.GetSignedData()).To(Equal("1_second_" + first.GetSignatureValue()))
+
+		response, err := client.ListSignatures(ctx, &signingv1.ListSignaturesRequest{DeviceId: device.GetId(), PageSize: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.GetSignatures()).To(HaveLen(1))
+		Expect(response.GetSignatures()[0].GetId()).To(Equal(first.GetId()))
+
+		response, err = client.ListSignatures(ctx, &signingv1.ListSignaturesRequest{DeviceId: device.GetId(), PageToken: response.GetNextPageToken()})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(response.GetSignatures()).To(HaveLen(1))
+		Expect(response.GetSignatures()[0].GetId()).To(Equal(second.GetId()))
+	})
+
+	It("should announce devices and signatures created over gRPC to the webhooks", func() {
+		Expect(webhookRepository.CreateWebhook(&domain.Webhook{ID: "webhook", URL: "https://pos.example.com/hooks"})).To(Succeed())
+
+		device := createDevice()
+		signature := sign(device.GetId(), "receipt")
+
+		deliveries, err := webhookRepository.ListDeliveries(persistence.DeliveryQuery{})
+		Expect(err).NotTo(HaveOccurred())
+		events := make(map[string]service.WebhookEvent)
+		for _, delivery := range deliveries {
+			Expect(delivery.WebhookID).To(Equal("webhook"))
+			var event service.WebhookEvent
+			Expect(json.Unmarshal([]byte(delivery.Payload), &event)).To(Succeed())
+			events[event.Type] = event
+		}
+		Expect(events).To(HaveLen(2))
+		Expect(events[domain.EventDeviceCreated].Data).To(HaveKeyWithValue("id", device.GetId()))
+		Expect(events[domain.EventSignatureCreated].Data).To(HaveKeyWithValue("id", signature.GetId()))
+		Expect(events[domain.EventSignatureCreated].Data).To(HaveKeyWithValue("device_id", device.GetId()))
+	})
+
+	It("should reject negative counter bounds", func() {
+		device := createDevice()
+
+		_, err := client.ListSignatures(ctx, &signingv1.ListSignaturesRequest{DeviceId: device.GetId(), FromCounter: proto.Int64(-1)})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(Equal("from_counter must be a non-negative number"))
+
+		_, err = client.ListSignatures(ctx, &signingv1.ListSignaturesRequest{DeviceId: device.GetId(), ToCounter: proto.Int64(-5)})
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+		Expect(status.Convert(err).Message()).To(Equal("to_counter must be a non-negative number"))
+	})
+
+	It("should replay a retried idempotency key and reject its reuse", func() {
+		device := createDevice()
+		request := &signingv1.SignTransactionRequest{DeviceId: device.GetId(), Data: "receipt", IdempotencyKey: "key-1"}
+
+		original, err := client.SignTransaction(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		retried, err := client.SignTransaction(ctx, request)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(retried.GetReplayed()).To(BeTrue())
+		Expect(retried.GetSignature().GetId()).To(Equal(original.GetSignature().GetId()))
+
+		request.Data = "other receipt"
+		_, err = client.SignTransaction(ctx, request)
+		Expect(status.Code(err)).To(Equal(codes.AlreadyExists))
+	})
+
+	It("should refuse to sign with a device that is not active", func() {
+		device := createDevice()
+		stored, err := signing.DeviceRepository.GetDevice(device.GetId())
+		Expect(err).NotTo(HaveOccurred())
+		suspended := *stored
+		suspended.Status = domain.DeviceStatusSuspended
+		Expect(signing.DeviceRepository.UpdateDevice(&suspended)).To(Succeed())
+
+		_, err = client.SignTransaction(ctx, &signingv1.SignTransactionRequest{DeviceId: device.GetId(), Data: "receipt"})
+		Expect(status.Code(err)).To(Equal(codes.FailedPrecondition))
+	})
+
+	It("should sign a stream of transactions in order", func() {
+		device := createDevice()
+		stream, err := client.SignTransactions(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, data := range []string{"a", "b", "c"} {
+			Expect(stream.Send(&signingv1.SignTransactionRequest{DeviceId: device.GetId(), Data: data})).To(Succeed())
+		}
+		Expect(stream.CloseSend()).To(Succeed())
+
+		for counter := 0; counter < 3; counter++ {
+			response, err := stream.Recv()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(response.GetSignature().GetSignatureCounter()).To(Equal(int64(counter)))
+		}
+		_, err = stream.Recv()
+		Expect(err).To(Equal(io.EOF))
+	})
+
+	It("should end a signing stream with the first failure", func() {
+		device := createDevice()
+		stream, err := client.SignTransactions(ctx)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(stream.Send(&signingv1.SignTransactionRequest{DeviceId: device.GetId(), Data: "a"})).To(Succeed())
+		Expect(stream.Send(&signingv1.SignTransactionRequest{DeviceId: device.GetId()})).To(Succeed())
+
+		_, err = stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.InvalidArgument))
+	})
+
+	It("should watch the signatures of a device", func() {
+		device := createDevice()
+		sign(device.GetId(), "before")
+
+		watchContext, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := client.WatchSignatures(watchContext, &signingv1.WatchSignaturesRequest{
+			DeviceId:     device.GetId(),
+			AfterCounter: proto.Int64(-1),
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		replayed, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayed.GetData()).To(Equal("before"))
+
+		sign(device.GetId(), "after")
+		followed, err := stream.Recv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(followed.GetData()).To(Equal("after"))
+		Expect(followed.GetSignatureCounter()).To(Equal(int64(1)))
+	})
+
+	It("should answer Unavailable when watching is not enabled", func() {
+		signing.Broker = nil
+		device := createDevice()
+
+		stream, err := client.WatchSignatures(ctx, &signingv1.WatchSignaturesRequest{DeviceId: device.GetId()})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = stream.Recv()
+		Expect(status.Code(err)).To(Equal(codes.Unavailable))
+	})
+})
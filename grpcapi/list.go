@@ -0,0 +1,147 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	signingv1 "github.com/fiskaly/coding-challenges/signing-service-challenge/proto/signing/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Page sizes of the list RPCs, a page_size picks one up to MaxPageSize.
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// decodePageToken reads a next_page_token, an empty one stands for the first page.
+func decodePageToken(encoded string) (*persistence.PageCursor, error) {
+	token, err := persistence.DecodePageCursor(encoded)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+	}
+	return token, nil
+}
+
+// pageSize returns the requested page size, or the default if none was requested.
+func pageSize(requested int32) (int, error) {
+	if requested == 0 {
+		return DefaultPageSize, nil
+	}
+	if requested < 0 || requested > MaxPageSize {
+		return 0, status.Error(codes.InvalidArgument, fmt.Sprintf("page_size must be between 1 and %d", MaxPageSize))
+	}
+	return int(requested), nil
+}
+
+// counter checks a signature counter bound of a request, counters start at 0.
+func counter(value int64, name string) (*int, error) {
+	if value < 0 {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("%s must be a non-negative number", name))
+	}
+	converted := int(value)
+	return &converted, nil
+}
+
+func (s *Server) ListDevices(ctx context.Context, request *signingv1.ListDevicesRequest) (*signingv1.ListDevicesResponse, error) {
+	limit, err := pageSize(request.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	token, err := decodePageToken(request.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	query := persistence.DeviceQuery{
+		Algorithm:   request.GetAlgorithm(),
+		LabelPrefix: request.GetLabelPrefix(),
+		Limit:       limit + 1, // One more tells whether there is a next page
+		Descending:  request.GetDescending(),
+	}
+	if request.GetStatus() != "" {
+		query.Status, err = domain.ParseDeviceStatus(request.GetStatus())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	if token != nil {
+		if query.After, err = token.DeviceCursor(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	devices, err := s.Devices.ListDevices(query)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &signingv1.ListDevicesResponse{}
+	if len(devices) > limit {
+		devices = devices[:limit]
+		response.NextPageToken = persistence.EncodeDevicePageCursor(devices[len(devices)-1])
+	}
+	for _, device := range devices {
+		response.Devices = append(response.Devices, deviceMessage(device))
+	}
+	return response, nil
+}
+
+func (s *Server) ListSignatures(ctx context.Context, request *signingv1.ListSignaturesRequest) (*signingv1.ListSignaturesResponse, error) {
+	if request.GetDeviceId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "device_id is required")
+	}
+	limit, err := pageSize(request.GetPageSize())
+	if err != nil {
+		return nil, err
+	}
+	token, err := decodePageToken(request.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+
+	query := persistence.SignatureQuery{
+		DeviceID:   request.GetDeviceId(),
+		Limit:      limit + 1, // One more tells whether there is a next page
+		Descending: request.GetDescending(),
+	}
+	if request.FromCounter != nil {
+		if query.FromCounter, err = counter(request.GetFromCounter(), "from_counter"); err != nil {
+			return nil, err
+		}
+	}
+	if request.ToCounter != nil {
+		if query.ToCounter, err = counter(request.GetToCounter(), "to_counter"); err != nil {
+			return nil, err
+		}
+	}
+	if request.CreatedFrom != nil {
+		query.CreatedFrom = request.GetCreatedFrom().AsTime()
+	}
+	if request.CreatedTo != nil {
+		query.CreatedTo = request.GetCreatedTo().AsTime()
+	}
+	if token != nil {
+		if query.AfterCounter, err = token.SignatureCounter(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+	}
+
+	signatures, err := s.Signing.ListSignatures(query)
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &signingv1.ListSignaturesResponse{}
+	if len(signatures) > limit {
+		signatures = signatures[:limit]
+		response.NextPageToken = persistence.EncodeSignaturePageCursor(signatures[len(signatures)-1])
+	}
+	for _, signature := range signatures {
+		response.Signatures = append(response.Signatures, signatureMessage(signature))
+	}
+	return response, nil
+}
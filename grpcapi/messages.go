@@ -0,0 +1,50 @@
+package grpcapi
+
+import (
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	signingv1 "github.com/fiskaly/coding-challenges/signing-service-challenge/proto/signing/v1"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func deviceMessage(device *domain.Device) *signingv1.Device {
+	message := &signingv1.Device{
+		Id:               device.ID,
+		Algorithm:        device.Algorithm,
+		PublicKey:        crypto.StandardPublicKeyPEM(device.PublicKey),
+		SignatureCounter: int64(device.SignatureCounter),
+		Label:            device.Label,
+		KeySize:          int32(device.KeyParameters.KeySize),
+		Curve:            device.KeyParameters.Curve,
+		Hash:             device.KeyParameters.Hash,
+		Padding:          device.KeyParameters.Padding,
+		Status:           string(device.CurrentStatus()),
+		Metadata:         device.Metadata,
+	}
+	if !device.CreatedAt.IsZero() {
+		message.CreatedAt = timestamppb.New(device.CreatedAt)
+	}
+	for _, retiredKey := range device.KeyHistory {
+		message.KeyHistory = append(message.KeyHistory, &signingv1.RetiredKey{
+			PublicKey:    crypto.StandardPublicKeyPEM(retiredKey.PublicKey),
+			FirstCounter: int64(retiredKey.FirstCounter),
+			LastCounter:  int64(retiredKey.LastCounter),
+			RotatedAt:    timestamppb.New(retiredKey.RotatedAt),
+		})
+	}
+	return message
+}
+
+func signatureMessage(signature *domain.Signature) *signingv1.Signature {
+	return &signingv1.Signature{
+		Id:                  signature.ID,
+		DeviceId:            signature.DeviceID,
+		SignatureCounter:    int64(signature.SignatureCounter),
+		SignatureValue:      signature.SignatureValue,
+		SignedData:          signature.SignedData,
+		Data:                signature.Data,
+		Algorithm:           signature.Algorithm,
+		PreviousSignatureId: signature.PreviousSignatureID,
+		CreatedAt:           timestamppb.New(signature.CreatedAt),
+	}
+}
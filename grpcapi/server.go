@@ -0,0 +1,185 @@
+// Package grpcapi exposes the DeviceService and SigningService over gRPC, next to the HTTP API of package api.
+// The service definition is proto/signing/v1/signing.proto.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	signingv1 "github.com/fiskaly/coding-challenges/signing-service-challenge/proto/signing/v1"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements signingv1.SigningServiceServer on top of the services.
+type Server struct {
+	signingv1.UnimplementedSigningServiceServer
+
+	Devices *service.DeviceService
+	Signing *service.SigningService
+}
+
+// NewServer returns a gRPC server offering the signing service, the standard health service and
+// server reflection, so tools like grpcurl can be used without the proto files.
+func NewServer(devices *service.DeviceService, signing *service.SigningService) *grpc.Server {
+	grpcServer := grpc.NewServer()
+	signingv1.RegisterSigningServiceServer(grpcServer, &Server{Devices: devices, Signing: signing})
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+// ListenAndServe serves the signing service over gRPC on the TCP address.
+func ListenAndServe(address string, devices *service.DeviceService, signing *service.SigningService) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return NewServer(devices, signing).Serve(listener)
+}
+
+func (s *Server) CreateDevice(ctx context.Context, request *signingv1.CreateDeviceRequest) (*signingv1.Device, error) {
+	if request.GetAlgorithm() == "" {
+		return nil, status.Error(codes.InvalidArgument, "algorithm is required")
+	}
+
+	device, err := s.Devices.CreateDevice(service.NewDevice{
+		Algorithm: request.GetAlgorithm(),
+		Label:     request.GetLabel(),
+		KeyParameters: domain.KeyParameters{
+			KeySize: int(request.GetKeySize()),
+			Curve:   request.GetCurve(),
+			Hash:    request.GetHash(),
+			Padding: request.GetPadding(),
+		},
+		Metadata:   request.GetMetadata(),
+		PrivateKey: []byte(request.GetPrivateKey()),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return deviceMessage(device), nil
+}
+
+func (s *Server) GetDevice(ctx context.Context, request *signingv1.GetDeviceRequest) (*signingv1.Device, error) {
+	if request.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	device, err := s.Devices.GetDevice(request.GetId())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return deviceMessage(device), nil
+}
+
+func (s *Server) SignTransaction(ctx context.Context, request *signingv1.SignTransactionRequest) (*signingv1.SignTransactionResponse, error) {
+	return s.sign(request)
+}
+
+// SignTransactions answers every request on the stream in order. Terminals keep one stream open
+// instead of paying for a call per transaction, the first failure ends the stream.
+func (s *Server) SignTransactions(stream grpc.BidiStreamingServer[signingv1.SignTransactionRequest, signingv1.SignTransactionResponse]) error {
+	for {
+		request, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := s.sign(request)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) sign(request *signingv1.SignTransactionRequest) (*signingv1.SignTransactionResponse, error) {
+	if request.GetDeviceId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "device_id is required")
+	}
+	if request.GetData() == "" {
+		return nil, status.Error(codes.InvalidArgument, "data is required")
+	}
+
+	signature, replayed, err := s.Signing.Sign(request.GetDeviceId(), request.GetData(), request.GetIdempotencyKey())
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &signingv1.SignTransactionResponse{
+		Signature: signatureMessage(signature),
+		Replayed:  replayed,
+	}, nil
+}
+
+// WatchSignatures streams the signatures of a device while they are created. Without an
+// after_counter it starts with the next signature, like the Server-Sent Events stream of the HTTP API.
+func (s *Server) WatchSignatures(request *signingv1.WatchSignaturesRequest, stream grpc.ServerStreamingServer[signingv1.Signature]) error {
+	if request.GetDeviceId() == "" {
+		return status.Error(codes.InvalidArgument, "device_id is required")
+	}
+
+	device, err := s.Devices.GetDevice(request.GetDeviceId())
+	if err != nil {
+		return statusError(err)
+	}
+
+	// The counter of the last signature the client has, -1 if it has none
+	afterCounter := device.SignatureCounter - 1
+	if request.AfterCounter != nil {
+		if request.GetAfterCounter() < -1 {
+			return status.Error(codes.InvalidArgument, "after_counter must be a signature counter or -1")
+		}
+		afterCounter = int(request.GetAfterCounter())
+	}
+
+	err = s.Signing.WatchSignatures(stream.Context(), device.ID, afterCounter, func(signature *domain.Signature) error {
+		return stream.Send(signatureMessage(signature))
+	})
+	return statusError(err)
+}
+
+// statusError translates the errors of the services into gRPC status errors.
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var invalid *service.InvalidArgumentError
+	switch {
+	case errors.As(err, &invalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrDeviceNotActive):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, service.ErrConflict):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrWatchDisabled):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, err.Error())
+}
@@ -2,19 +2,103 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/fiskaly/coding-challenges/signing-service-challenge/api"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/grpcapi"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 const (
 	ListenAddress = ":8080"
+	// GRPCListenAddress is where the gRPC API listens unless GRPC_LISTEN_ADDRESS says otherwise.
+	GRPCListenAddress = ":9090"
 	// TODO: add further configuration parameters here ...
 )
 
 func main() {
-	server := api.NewServer(ListenAddress)
+	config := api.Config{
+		ListenAddress: ListenAddress,
+		Storage:       getEnv("STORAGE", api.StorageMemory),
+		DataDir:       getEnv("DATA_DIR", "data"),
+		SQLDriver:     getEnv("SQL_DRIVER", "sqlite3"),
+		SQLDataSource: getEnv("SQL_DATA_SOURCE", "file:signing.db?_foreign_keys=on"),
+		MasterKey:     getEnv("MASTER_KEY", ""),
+		MasterKeyFile: getEnv("MASTER_KEY_FILE", ""),
+		KeyProvider:   getEnv("KEY_PROVIDER", api.KeyProviderLocal),
+		SignerSocket:  getEnv("SIGNER_SOCKET", "signer.sock"),
+		CADir:         getEnv("CA_DIR", ""),
+	}
+	if previous := getEnv("PREVIOUS_MASTER_KEYS", ""); previous != "" {
+		config.PreviousMasterKeys = strings.Split(previous, ",")
+	}
+	idempotencyRetention, err := time.ParseDuration(getEnv("IDEMPOTENCY_RETENTION", "24h"))
+	if err != nil {
+		log.Fatal("Invalid IDEMPOTENCY_RETENTION: ", err)
+	}
+	config.IdempotencyRetention = idempotencyRetention
+	if config.MaxBatchItems, err = strconv.Atoi(getEnv("BATCH_MAX_ITEMS", strconv.Itoa(api.DefaultMaxBatchItems))); err != nil {
+		log.Fatal("Invalid BATCH_MAX_ITEMS: ", err)
+	}
+	if config.JobWorkers, err = strconv.Atoi(getEnv("JOB_WORKERS", strconv.Itoa(api.DefaultJobWorkers))); err != nil {
+		log.Fatal("Invalid JOB_WORKERS: ", err)
+	}
+	if config.JobQueueSize, err = strconv.Atoi(getEnv("JOB_QUEUE_SIZE", strconv.Itoa(api.DefaultJobQueueSize))); err != nil {
+		log.Fatal("Invalid JOB_QUEUE_SIZE: ", err)
+	}
+	if config.JobRetention, err = time.ParseDuration(getEnv("JOB_RETENTION", "24h")); err != nil {
+		log.Fatal("Invalid JOB_RETENTION: ", err)
+	}
+	if config.WebhookMaxAttempts, err = strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", strconv.Itoa(api.DefaultWebhookMaxAttempts))); err != nil {
+		log.Fatal("Invalid WEBHOOK_MAX_ATTEMPTS: ", err)
+	}
+	if config.WebhookBackoff, err = time.ParseDuration(getEnv("WEBHOOK_BACKOFF", api.DefaultWebhookBackoff.String())); err != nil {
+		log.Fatal("Invalid WEBHOOK_BACKOFF: ", err)
+	}
+	if config.AllowPrivateEndpoints, err = strconv.ParseBool(getEnv("ALLOW_PRIVATE_ENDPOINTS", "false")); err != nil {
+		log.Fatal("Invalid ALLOW_PRIVATE_ENDPOINTS: ", err)
+	}
+	if config.AllowPlaintextKeys, err = strconv.ParseBool(getEnv("ALLOW_PLAINTEXT_KEYS", "false")); err != nil {
+		log.Fatal("Invalid ALLOW_PLAINTEXT_KEYS: ", err)
+	}
+
+	server, err := api.NewServer(config)
+	if err != nil {
+		log.Fatal("Could not set up server: ", err)
+	}
+
+	// Let running jobs and webhook deliveries finish on shutdown, queued ones are picked up by the next start
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		<-signals
+		server.Stop()
+		os.Exit(0)
+	}()
+
+	// An empty GRPC_LISTEN_ADDRESS serves the HTTP API only
+	if grpcListenAddress := getEnv("GRPC_LISTEN_ADDRESS", GRPCListenAddress); grpcListenAddress != "" {
+		go func() {
+			if err := grpcapi.ListenAndServe(grpcListenAddress, server.DeviceService(), server.SigningService()); err != nil {
+				log.Fatal("Could not start gRPC server on ", grpcListenAddress, ": ", err)
+			}
+		}()
+	}
 
 	if err := server.Run(); err != nil {
 		log.Fatal("Could not start server on ", ListenAddress)
 	}
 }
+
+// getEnv returns the value of the environment variable key or fallback if it is unset.
+func getEnv(key string, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
@@ -11,9 +11,11 @@ type IDeviceRepository interface {
 	CreateDevice(device *domain.Device) error
 	CountDevices() int
 	GetDevice(id string) (*domain.Device, error)
+	UpdateDevice(device *domain.Device) error
 	IncrementSignatureCounter(deviceID string) error
 	GetDeviceMutex(deviceID string) *sync.Mutex
 	GetAllDevices() ([]*domain.Device, error)
+	ListDevices(query DeviceQuery) ([]*domain.Device, error)
 }
 
 type DeviceRepository struct {
@@ -23,6 +25,10 @@ type DeviceRepository struct {
 }
 
 func NewDeviceRepository() IDeviceRepository {
+	return newDeviceRepository()
+}
+
+func newDeviceRepository() *DeviceRepository {
 	return &DeviceRepository{
 		mutex:   sync.RWMutex{},
 		devices: make(map[string]*domain.Device),
@@ -51,19 +57,35 @@ func (m *DeviceRepository) GetDevice(id string) (*domain.Device, error) {
 
 	device, exists := m.devices[id]
 	if !exists {
-		return nil, fmt.Errorf("device with id %s not found", id)
+		return nil, fmt.Errorf("device with id %s %w", id, ErrNotFound)
 	}
 
 	return device, nil
 }
 
+// UpdateDevice replaces a stored device. The signature counter is kept as stored,
+// it only ever changes through IncrementSignatureCounter.
+func (m *DeviceRepository) UpdateDevice(device *domain.Device) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	existing, exists := m.devices[device.ID]
+	if !exists {
+		return fmt.Errorf("device with id %s %w", device.ID, ErrNotFound)
+	}
+
+	device.SignatureCounter = existing.SignatureCounter
+	m.devices[device.ID] = device
+	return nil
+}
+
 func (m *DeviceRepository) IncrementSignatureCounter(deviceID string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	device, exists := m.devices[deviceID]
 	if !exists {
-		return fmt.Errorf("device with id %s not found", deviceID)
+		return fmt.Errorf("device with id %s %w", deviceID, ErrNotFound)
 	}
 
 	device.SignatureCounter++
@@ -89,4 +111,12 @@ func (m *DeviceRepository) GetAllDevices() ([]*domain.Device, error) {
 		devices = append(devices, device)
 	}
 	return devices, nil
-}
\ No newline at end of file
+}
+
+func (m *DeviceRepository) ListDevices(query DeviceQuery) ([]*domain.Device, error) {
+	devices, err := m.GetAllDevices()
+	if err != nil {
+		return nil, err
+	}
+	return query.Apply(devices), nil
+}
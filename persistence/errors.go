@@ -0,0 +1,17 @@
+package persistence
+
+import "errors"
+
+// ErrNotFound is wrapped by the errors of the repositories when the record asked for does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrAlreadyExists is wrapped by the errors of the repositories when a record with the same ID is stored twice.
+var ErrAlreadyExists = errors.New("already exists")
+
+// ErrInvalidCursor is returned for a page cursor that was not handed out by EncodeDevicePageCursor
+// or EncodeSignaturePageCursor, or that belongs to the other kind of query.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrCounterMismatch is wrapped when a unit of work expects a different signature counter than
+// the stored one, i.e. another writer signed with the device in the meantime.
+var ErrCounterMismatch = errors.New("signature counter mismatch")
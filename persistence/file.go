@@ -0,0 +1,741 @@
+package persistence
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+const journalFileName = "journal.log"
+
+const (
+	entryDeviceCreated      = "device_created"
+	entryDeviceUpdated      = "device_updated"
+	entrySignatureCreated   = "signature_created"
+	entryCounterIncremented = "counter_incremented"
+	entryUnitOfWork         = "unit_of_work"
+	entryWebhookCreated     = "webhook_created"
+	entryWebhookDeleted     = "webhook_deleted"
+	entryDeliveriesCreated  = "deliveries_created"
+	entryDeliveryUpdated    = "delivery_updated"
+	entryDeliveryDeleted    = "delivery_deleted"
+	entryJobCreated         = "job_created"
+	entryJobUpdated         = "job_updated"
+	entryJobsDeleted        = "jobs_deleted"
+)
+
+// ErrCorruptJournal is returned when the journal contains a damaged entry that is
+// followed by further entries, meaning it cannot be explained by an interrupted write.
+var ErrCorruptJournal = errors.New("journal is corrupt")
+
+// journalEntry is a single record of the append-only journal.
+type journalEntry struct {
+	Type       string                    `json:"type"`
+	Device     *domain.Device            `json:"device,omitempty"`
+	Signature  *domain.Signature         `json:"signature,omitempty"`
+	DeviceID   string                    `json:"device_id,omitempty"`
+	Entries    []journalEntry            `json:"entries,omitempty"`
+	Webhook    *domain.Webhook           `json:"webhook,omitempty"`
+	Deliveries []*domain.WebhookDelivery `json:"deliveries,omitempty"`
+	ID         string                    `json:"id,omitempty"` // Webhook or delivery that was deleted
+	Job        *domain.Job               `json:"job,omitempty"`
+	Before     *time.Time                `json:"before,omitempty"` // Jobs finished before this time were deleted
+}
+
+// FileStore is a durable implementation of IDeviceRepository, ISignatureRepository, IUnitOfWork, IWebhookRepository and IJobRepository.
+// Every write is appended to a journal on disk and fsynced before it is applied to the
+// in-memory index, on startup the journal is replayed to rebuild that index.
+type FileStore struct {
+	mutex  sync.Mutex //Serializes journal appends
+	path   string
+	file   *os.File
+	size   int64
+	broken error // Set once a failed append could not be rolled back, later appends are refused
+
+	devices    *DeviceRepository
+	signatures *SignatureRepository
+	webhooks   *WebhookRepository
+	jobs       *JobRepository
+}
+
+// NewFileStore opens (or creates) the journal inside dir and recovers its state.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(dir, journalFileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &FileStore{
+		path:       path,
+		file:       file,
+		devices:    newDeviceRepository(),
+		signatures: newSignatureRepository(),
+		webhooks:   newWebhookRepository(),
+		jobs:       newJobRepository(),
+	}
+
+	if err := store.recover(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// recover replays the journal into the in-memory index. A torn entry at the end of the
+// journal is the trace of a crash during an append and gets truncated away.
+func (f *FileStore) recover() error {
+	reader := bufio.NewReader(f.file)
+	var offset int64
+
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) == 0 && readErr == io.EOF {
+			break
+		}
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+
+		entry, err := decodeJournalLine(line)
+		if err != nil {
+			if _, peekErr := reader.Peek(1); peekErr != io.EOF {
+				return fmt.Errorf("%w: entry at offset %d: %v", ErrCorruptJournal, offset, err)
+			}
+			if err := f.file.Truncate(offset); err != nil {
+				return err
+			}
+			break
+		}
+
+		if err := f.apply(entry); err != nil {
+			return fmt.Errorf("%w: entry at offset %d: %v", ErrCorruptJournal, offset, err)
+		}
+		offset += int64(len(line))
+	}
+
+	f.size = offset
+	if _, err := f.file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	f.reconcileCounters()
+	return f.file.Sync()
+}
+
+// reconcileCounters makes every device counter agree with its signature chain, a crash
+// between storing a signature and incrementing the counter would otherwise leave them apart.
+func (f *FileStore) reconcileCounters() {
+	for _, device := range f.devices.devices {
+		latestSignature, err := f.signatures.GetLatestSignature(device.ID)
+		if err != nil {
+			continue
+		}
+		device.SignatureCounter = latestSignature.SignatureCounter + 1
+	}
+}
+
+func encodeJournalLine(entry journalEntry) ([]byte, error) {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, "%08x %s\n", crc32.ChecksumIEEE(payload), payload), nil
+}
+
+func decodeJournalLine(line []byte) (journalEntry, error) {
+	var entry journalEntry
+
+	if len(line) == 0 || line[len(line)-1] != '\n' {
+		return entry, errors.New("incomplete entry")
+	}
+
+	checksum, payload, found := bytes.Cut(bytes.TrimSuffix(line, []byte("\n")), []byte(" "))
+	if !found {
+		return entry, errors.New("missing checksum")
+	}
+	if fmt.Sprintf("%08x", crc32.ChecksumIEEE(payload)) != string(checksum) {
+		return entry, errors.New("checksum mismatch")
+	}
+
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		return entry, err
+	}
+
+	// Devices journaled before keys were held by a key provider carry the private key
+	// itself, which is exactly the reference the local key provider uses
+	if entry.Device != nil && entry.Device.KeyReference == "" {
+		var legacy struct {
+			Device struct {
+				PrivateKey string
+			}
+		}
+		if err := json.Unmarshal(payload, &legacy); err != nil {
+			return entry, err
+		}
+		entry.Device.KeyReference = legacy.Device.PrivateKey
+	}
+	return entry, nil
+}
+
+func (f *FileStore) apply(entry journalEntry) error {
+	switch entry.Type {
+	case entryDeviceCreated:
+		if entry.Device == nil {
+			return errors.New("device missing")
+		}
+		return f.devices.CreateDevice(entry.Device)
+	case entryDeviceUpdated:
+		if entry.Device == nil {
+			return errors.New("device missing")
+		}
+		return f.devices.UpdateDevice(entry.Device)
+	case entrySignatureCreated:
+		if entry.Signature == nil {
+			return errors.New("signature missing")
+		}
+		return f.signatures.CreateSignature(entry.Signature)
+	case entryCounterIncremented:
+		return f.devices.IncrementSignatureCounter(entry.DeviceID)
+	case entryWebhookCreated:
+		if entry.Webhook == nil {
+			return errors.New("webhook missing")
+		}
+		return f.webhooks.CreateWebhook(entry.Webhook)
+	case entryWebhookDeleted:
+		return f.webhooks.DeleteWebhook(entry.ID)
+	// A webhook can be deleted between the check and the append of a delivery entry,
+	// deliveries of deleted webhooks are skipped instead of failing the replay
+	case entryDeliveriesCreated:
+		// Deliveries to webhooks deleted in the meantime are skipped
+		return f.webhooks.CreateDeliveries(entry.Deliveries)
+	case entryDeliveryUpdated:
+		if len(entry.Deliveries) != 1 {
+			return errors.New("delivery missing")
+		}
+		if _, err := f.webhooks.GetDelivery(entry.Deliveries[0].ID); err != nil {
+			return nil
+		}
+		return f.webhooks.UpdateDelivery(entry.Deliveries[0])
+	case entryDeliveryDeleted:
+		if _, err := f.webhooks.GetDelivery(entry.ID); err != nil {
+			return nil
+		}
+		return f.webhooks.DeleteDelivery(entry.ID)
+	case entryJobCreated:
+		if entry.Job == nil {
+			return errors.New("job missing")
+		}
+		return f.jobs.CreateJob(entry.Job)
+	case entryJobUpdated:
+		if entry.Job == nil {
+			return errors.New("job missing")
+		}
+		return f.jobs.UpdateJob(entry.Job)
+	case entryJobsDeleted:
+		if entry.Before == nil {
+			return errors.New("time missing")
+		}
+		_, err := f.jobs.DeleteJobsFinishedBefore(*entry.Before)
+		return err
+	case entryUnitOfWork:
+		for _, nested := range entry.Entries {
+			if err := f.apply(nested); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown entry type %q", entry.Type)
+	}
+}
+
+// append writes an entry to the journal and flushes it to stable storage before the
+// entry is applied to the in-memory index.
+func (f *FileStore) append(entry journalEntry) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.appendLocked(entry)
+}
+
+// appendLocked is append for callers already holding f.mutex.
+func (f *FileStore) appendLocked(entry journalEntry) error {
+	if f.broken != nil {
+		return f.broken
+	}
+
+	line, err := encodeJournalLine(entry)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.file.Write(line); err != nil {
+		return f.rollback(err)
+	}
+	if err := f.file.Sync(); err != nil {
+		// The entry may reach the disk later on, it must not come back on recovery
+		return f.rollback(err)
+	}
+	f.size += int64(len(line))
+
+	return f.apply(entry)
+}
+
+// rollback drops whatever part of a failed append made it to the journal. If that fails too,
+// the journal no longer matches the in-memory index and the store refuses further appends.
+func (f *FileStore) rollback(appendErr error) error {
+	if err := f.file.Truncate(f.size); err != nil {
+		f.broken = fmt.Errorf("journal is unusable, a failed append could not be rolled back: %w", err)
+		return errors.Join(appendErr, f.broken)
+	}
+	if _, err := f.file.Seek(f.size, io.SeekStart); err != nil {
+		f.broken = fmt.Errorf("journal is unusable, a failed append could not be rolled back: %w", err)
+		return errors.Join(appendErr, f.broken)
+	}
+	return appendErr
+}
+
+// Close releases the journal file.
+func (f *FileStore) Close() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.file.Close()
+}
+
+// Compact rewrites the journal as a snapshot of the current state. The entries it drops are the
+// ones superseded since, among them device updates holding a private key in plaintext or sealed
+// under a retired master key. The snapshot is written to a temporary file that replaces the
+// journal once it is on disk, after a crash either the old or the new journal is recovered.
+func (f *FileStore) Compact() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.broken != nil {
+		return f.broken
+	}
+
+	temporary, err := os.CreateTemp(filepath.Dir(f.path), journalFileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+
+	size, err := f.writeSnapshot(temporary)
+	if err == nil {
+		err = temporary.Sync()
+	}
+	if closeErr := temporary.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Rename(temporary.Name(), f.path); err != nil {
+		return err
+	}
+
+	// From here on the old file is gone, appends to it would be lost on the next start
+	file, err := os.OpenFile(f.path, os.O_RDWR, 0o600)
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(f.path))
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		f.broken = fmt.Errorf("journal is unusable, it could not be reopened after compaction: %w", err)
+		return f.broken
+	}
+
+	f.file.Close()
+	f.file = file
+	f.size = size
+	return nil
+}
+
+// writeSnapshot writes an entry for every stored record to w, in an order replaying them
+// restores the same state, and returns the number of bytes written.
+func (f *FileStore) writeSnapshot(w io.Writer) (int64, error) {
+	var entries []journalEntry
+
+	devices, _ := f.devices.GetAllDevices()
+	sort.Slice(devices, func(i, j int) bool {
+		if !devices[i].CreatedAt.Equal(devices[j].CreatedAt) {
+			return devices[i].CreatedAt.Before(devices[j].CreatedAt)
+		}
+		return devices[i].ID < devices[j].ID
+	})
+	for _, device := range devices {
+		entries = append(entries, journalEntry{Type: entryDeviceCreated, Device: device})
+	}
+
+	// Keyed signatures are replayed in the order they were stored, it decides which keys a purge drops
+	signatures, _ := f.signatures.GetAllSignatures()
+	sort.Slice(signatures, func(i, j int) bool {
+		if !signatures[i].CreatedAt.Equal(signatures[j].CreatedAt) {
+			return signatures[i].CreatedAt.Before(signatures[j].CreatedAt)
+		}
+		if signatures[i].DeviceID != signatures[j].DeviceID {
+			return signatures[i].DeviceID < signatures[j].DeviceID
+		}
+		return signatures[i].SignatureCounter < signatures[j].SignatureCounter
+	})
+	for _, signature := range signatures {
+		entries = append(entries, journalEntry{Type: entrySignatureCreated, Signature: signature})
+	}
+
+	webhooks, _ := f.webhooks.GetAllWebhooks()
+	for _, webhook := range webhooks {
+		entries = append(entries, journalEntry{Type: entryWebhookCreated, Webhook: webhook})
+	}
+	if deliveries, _ := f.webhooks.ListDeliveries(DeliveryQuery{}); len(deliveries) > 0 {
+		entries = append(entries, journalEntry{Type: entryDeliveriesCreated, Deliveries: deliveries})
+	}
+
+	jobs := make([]*domain.Job, 0, len(f.jobs.jobs))
+	for _, job := range f.jobs.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	for _, job := range jobs {
+		entries = append(entries, journalEntry{Type: entryJobCreated, Job: job})
+	}
+
+	writer := bufio.NewWriter(w)
+	var size int64
+	for _, entry := range entries {
+		line, err := encodeJournalLine(entry)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := writer.Write(line); err != nil {
+			return 0, err
+		}
+		size += int64(len(line))
+	}
+	return size, writer.Flush()
+}
+
+// syncDir flushes a rename inside dir to stable storage.
+func syncDir(dir string) error {
+	file, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return file.Sync()
+}
+
+func (f *FileStore) CreateDevice(device *domain.Device) error {
+	return f.append(journalEntry{
+		Type:   entryDeviceCreated,
+		Device: device,
+	})
+}
+
+func (f *FileStore) CountDevices() int {
+	return f.devices.CountDevices()
+}
+
+func (f *FileStore) GetDevice(id string) (*domain.Device, error) {
+	return f.devices.GetDevice(id)
+}
+
+func (f *FileStore) UpdateDevice(device *domain.Device) error {
+	if _, err := f.devices.GetDevice(device.ID); err != nil {
+		return err
+	}
+
+	return f.append(journalEntry{
+		Type:   entryDeviceUpdated,
+		Device: device,
+	})
+}
+
+func (f *FileStore) IncrementSignatureCounter(deviceID string) error {
+	if _, err := f.devices.GetDevice(deviceID); err != nil {
+		return err
+	}
+
+	return f.append(journalEntry{
+		Type:     entryCounterIncremented,
+		DeviceID: deviceID,
+	})
+}
+
+func (f *FileStore) GetDeviceMutex(deviceID string) *sync.Mutex {
+	return f.devices.GetDeviceMutex(deviceID)
+}
+
+func (f *FileStore) GetAllDevices() ([]*domain.Device, error) {
+	return f.devices.GetAllDevices()
+}
+
+func (f *FileStore) ListDevices(query DeviceQuery) ([]*domain.Device, error) {
+	return f.devices.ListDevices(query)
+}
+
+func (f *FileStore) CreateSignature(signature *domain.Signature) error {
+	return f.append(journalEntry{
+		Type:      entrySignatureCreated,
+		Signature: signature,
+	})
+}
+
+// Execute journals all writes of work as a single entry, so they are recovered together or not at all.
+// The journal mutex is held from the checks to the append, like the in-memory UnitOfWork no other
+// write can come in between.
+func (f *FileStore) Execute(work func(tx ITransaction) error) error {
+	tx := &stagedTransaction{}
+	if err := work(tx); err != nil {
+		return err
+	}
+
+	if err := f.commit(tx); err != nil {
+		return err
+	}
+	tx.afterCommit()
+	return nil
+}
+
+// commit checks the writes staged in tx and journals them while holding the journal mutex.
+func (f *FileStore) commit(tx *stagedTransaction) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := tx.checkIncrements(f.devices); err != nil {
+		return err
+	}
+
+	entry := journalEntry{Type: entryUnitOfWork}
+	for _, device := range tx.devices {
+		entry.Entries = append(entry.Entries, journalEntry{
+			Type:   entryDeviceCreated,
+			Device: device,
+		})
+	}
+	for _, signature := range tx.signatures {
+		entry.Entries = append(entry.Entries, journalEntry{
+			Type:      entrySignatureCreated,
+			Signature: signature,
+		})
+	}
+	for _, device := range tx.updates {
+		if _, err := f.devices.GetDevice(device.ID); err != nil {
+			return err
+		}
+		entry.Entries = append(entry.Entries, journalEntry{
+			Type:   entryDeviceUpdated,
+			Device: device,
+		})
+	}
+	for _, increment := range tx.increments {
+		entry.Entries = append(entry.Entries, journalEntry{
+			Type:     entryCounterIncremented,
+			DeviceID: increment.deviceID,
+		})
+	}
+	for _, job := range tx.jobs {
+		if _, err := f.jobs.GetJob(job.ID); err != nil {
+			return err
+		}
+		entry.Entries = append(entry.Entries, journalEntry{
+			Type: entryJobUpdated,
+			Job:  job,
+		})
+	}
+	if deliveries := f.knownDeliveries(tx.deliveries); len(deliveries) > 0 {
+		entry.Entries = append(entry.Entries, journalEntry{
+			Type:       entryDeliveriesCreated,
+			Deliveries: deliveries,
+		})
+	}
+
+	return f.appendLocked(entry)
+}
+
+func (f *FileStore) GetLatestSignature(deviceID string) (*domain.Signature, error) {
+	return f.signatures.GetLatestSignature(deviceID)
+}
+
+func (f *FileStore) GetAllSignatures() ([]*domain.Signature, error) {
+	return f.signatures.GetAllSignatures()
+}
+
+func (f *FileStore) GetAllSignaturesByDeviceID(deviceID string) ([]*domain.Signature, error) {
+	return f.signatures.GetAllSignaturesByDeviceID(deviceID)
+}
+
+func (f *FileStore) ListSignatures(query SignatureQuery) ([]*domain.Signature, error) {
+	return f.signatures.ListSignatures(query)
+}
+
+func (f *FileStore) GetSignatureByIdempotencyKey(deviceID string, key string) (*domain.Signature, error) {
+	return f.signatures.GetSignatureByIdempotencyKey(deviceID, key)
+}
+
+// DeleteIdempotencyKeysBefore only forgets the keys in memory, the journal keeps them with their
+// signatures and a restart indexes them again until the next purge.
+func (f *FileStore) DeleteIdempotencyKeysBefore(before time.Time) error {
+	return f.signatures.DeleteIdempotencyKeysBefore(before)
+}
+
+// The outbox is journaled like everything else, so pending deliveries survive a restart.
+
+func (f *FileStore) CreateWebhook(webhook *domain.Webhook) error {
+	return f.append(journalEntry{
+		Type:    entryWebhookCreated,
+		Webhook: webhook,
+	})
+}
+
+func (f *FileStore) GetWebhook(id string) (*domain.Webhook, error) {
+	return f.webhooks.GetWebhook(id)
+}
+
+func (f *FileStore) GetAllWebhooks() ([]*domain.Webhook, error) {
+	return f.webhooks.GetAllWebhooks()
+}
+
+func (f *FileStore) DeleteWebhook(id string) error {
+	if _, err := f.webhooks.GetWebhook(id); err != nil {
+		return err
+	}
+
+	return f.append(journalEntry{
+		Type: entryWebhookDeleted,
+		ID:   id,
+	})
+}
+
+func (f *FileStore) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	deliveries = f.knownDeliveries(deliveries)
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return f.append(journalEntry{
+		Type:       entryDeliveriesCreated,
+		Deliveries: deliveries,
+	})
+}
+
+// knownDeliveries drops the deliveries to webhooks that no longer exist, job callbacks have none.
+// A webhook deleted before the deliveries are journaled is skipped again when they are applied.
+func (f *FileStore) knownDeliveries(deliveries []*domain.WebhookDelivery) []*domain.WebhookDelivery {
+	known := make([]*domain.WebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		if _, err := f.webhooks.GetWebhook(delivery.WebhookID); err == nil || delivery.IsCallback() {
+			known = append(known, delivery)
+		}
+	}
+	return known
+}
+
+func (f *FileStore) GetDelivery(id string) (*domain.WebhookDelivery, error) {
+	return f.webhooks.GetDelivery(id)
+}
+
+func (f *FileStore) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	if _, err := f.webhooks.GetDelivery(delivery.ID); err != nil {
+		return err
+	}
+
+	return f.append(journalEntry{
+		Type:       entryDeliveryUpdated,
+		Deliveries: []*domain.WebhookDelivery{delivery},
+	})
+}
+
+func (f *FileStore) DeleteDelivery(id string) error {
+	if _, err := f.webhooks.GetDelivery(id); err != nil {
+		return err
+	}
+
+	return f.append(journalEntry{
+		Type: entryDeliveryDeleted,
+		ID:   id,
+	})
+}
+
+func (f *FileStore) ListDeliveries(query DeliveryQuery) ([]*domain.WebhookDelivery, error) {
+	return f.webhooks.ListDeliveries(query)
+}
+
+// Jobs are journaled, so queued jobs are picked up again and finished jobs can be polled after a restart.
+
+func (f *FileStore) CreateJob(job *domain.Job) error {
+	return f.append(journalEntry{
+		Type: entryJobCreated,
+		Job:  job,
+	})
+}
+
+func (f *FileStore) GetJob(id string) (*domain.Job, error) {
+	return f.jobs.GetJob(id)
+}
+
+func (f *FileStore) UpdateJob(job *domain.Job) error {
+	if _, err := f.jobs.GetJob(job.ID); err != nil {
+		return err
+	}
+
+	return f.append(journalEntry{
+		Type: entryJobUpdated,
+		Job:  job,
+	})
+}
+
+func (f *FileStore) ListUnfinishedJobs() ([]*domain.Job, error) {
+	return f.jobs.ListUnfinishedJobs()
+}
+
+// DeleteJobsFinishedBefore only journals the deletion if there is a job to delete, it runs
+// whenever a job is submitted.
+func (f *FileStore) DeleteJobsFinishedBefore(before time.Time) (int, error) {
+	// The index is only written under the journal mutex, so it cannot change while counting
+	f.mutex.Lock()
+	deleted := 0
+	for _, job := range f.jobs.jobs {
+		if job.IsFinished() && job.FinishedAt.Before(before) {
+			deleted++
+		}
+	}
+	f.mutex.Unlock()
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	if err := f.append(journalEntry{Type: entryJobsDeleted, Before: &before}); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
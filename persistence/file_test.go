@@ -0,0 +1,239 @@
+package persistence
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("File Store", func() {
+	var (
+		dir   string
+		store *FileStore
+	)
+
+	signAndCount := func(store *FileStore, deviceID string, counter int, value string) {
+		Expect(store.CreateSignature(&domain.Signature{
+			ID:               value,
+			DeviceID:         deviceID,
+			SignatureCounter: counter,
+			SignatureValue:   value,
+		})).To(Succeed())
+		Expect(store.IncrementSignatureCounter(deviceID)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir = GinkgoT().TempDir()
+		store, err = NewFileStore(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(store.CreateDevice(&domain.Device{
+			ID:        "test-device",
+			Algorithm: "ECC",
+			Label:     "test-device",
+		})).To(Succeed())
+		signAndCount(store, "test-device", 0, "first")
+		signAndCount(store, "test-device", 1, "second")
+	})
+
+	Context("When the store is reopened", func() {
+		It("should rebuild devices and signature chains", func() {
+			Expect(store.Close()).To(Succeed())
+
+			reopened, err := NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			defer reopened.Close()
+
+			device, err := reopened.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.SignatureCounter).To(Equal(2))
+
+			latestSignature, err := reopened.GetLatestSignature("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latestSignature.SignatureValue).To(Equal("second"))
+		})
+	})
+
+	Context("When jobs were journaled", func() {
+		It("should restore unfinished jobs and the outcome of finished ones", func() {
+			now := time.Now().UTC()
+			Expect(store.CreateJob(&domain.Job{ID: "queued", DeviceID: "test-device", Data: []string{"third"}, Status: domain.JobStatusQueued, CreatedAt: now})).To(Succeed())
+			Expect(store.CreateJob(&domain.Job{ID: "finished", DeviceID: "test-device", Data: []string{"first", "second"}, Status: domain.JobStatusRunning, CreatedAt: now})).To(Succeed())
+			Expect(store.CreateJob(&domain.Job{ID: "expired", DeviceID: "test-device", Status: domain.JobStatusFailed, FinishedAt: now.Add(-2 * time.Hour)})).To(Succeed())
+
+			signatures, err := store.GetAllSignaturesByDeviceID("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(store.Execute(func(tx ITransaction) error {
+				return tx.UpdateJob(&domain.Job{ID: "finished", DeviceID: "test-device", Data: []string{"first", "second"}, Status: domain.JobStatusSucceeded, Signatures: signatures, CreatedAt: now, FinishedAt: now})
+			})).To(Succeed())
+			Expect(store.DeleteJobsFinishedBefore(now.Add(-time.Hour))).To(Equal(1))
+			Expect(store.Close()).To(Succeed())
+
+			reopened, err := NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			defer reopened.Close()
+
+			unfinished, err := reopened.ListUnfinishedJobs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(unfinished).To(HaveLen(1))
+			Expect(unfinished[0].ID).To(Equal("queued"))
+			Expect(unfinished[0].Data).To(Equal([]string{"third"}))
+
+			finished, err := reopened.GetJob("finished")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(finished.Status).To(Equal(domain.JobStatusSucceeded))
+			Expect(finished.Signatures).To(HaveLen(2))
+
+			_, err = reopened.GetJob("expired")
+			Expect(err).To(MatchError(ErrNotFound))
+		})
+	})
+
+	Context("When the process crashed during an append", func() {
+		It("should drop the torn entry and keep appending", func() {
+			Expect(store.Close()).To(Succeed())
+
+			journal, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_APPEND|os.O_WRONLY, 0o600)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = journal.WriteString(`0badc0de {"type":"signature_cre`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(journal.Close()).To(Succeed())
+
+			reopened, err := NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			signAndCount(reopened, "test-device", 2, "third")
+			Expect(reopened.Close()).To(Succeed())
+
+			reopened, err = NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			defer reopened.Close()
+
+			signatures, err := reopened.GetAllSignaturesByDeviceID("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signatures).To(HaveLen(3))
+		})
+
+		It("should restore the counter when the increment never reached the disk", func() {
+			Expect(store.CreateSignature(&domain.Signature{
+				ID:               "third",
+				DeviceID:         "test-device",
+				SignatureCounter: 2,
+				SignatureValue:   "third",
+			})).To(Succeed())
+			Expect(store.Close()).To(Succeed())
+
+			reopened, err := NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			defer reopened.Close()
+
+			device, err := reopened.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.SignatureCounter).To(Equal(3))
+		})
+	})
+
+	Context("When an append fails and cannot be rolled back", func() {
+		It("should refuse further appends", func() {
+			writable := store.file
+			defer writable.Close()
+
+			// Neither writes nor truncates work on a read-only handle
+			readOnly, err := os.Open(filepath.Join(dir, journalFileName))
+			Expect(err).NotTo(HaveOccurred())
+			defer readOnly.Close()
+			store.file = readOnly
+
+			err = store.CreateDevice(&domain.Device{ID: "lost-device", Algorithm: "ECC"})
+			Expect(err).To(MatchError(ContainSubstring("journal is unusable")))
+			_, err = store.GetDevice("lost-device")
+			Expect(err).To(MatchError(ErrNotFound))
+
+			store.file = writable
+			Expect(store.CreateDevice(&domain.Device{ID: "later-device", Algorithm: "ECC"})).To(MatchError(ContainSubstring("journal is unusable")))
+		})
+	})
+
+	Context("When an entry in the middle of the journal is damaged", func() {
+		It("should refuse to start", func() {
+			Expect(store.Close()).To(Succeed())
+
+			path := filepath.Join(dir, journalFileName)
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			content[12] ^= 0xff
+			Expect(os.WriteFile(path, content, 0o600)).To(Succeed())
+
+			_, err = NewFileStore(dir)
+			Expect(err).To(MatchError(ErrCorruptJournal))
+		})
+	})
+
+	Context("When the journal is compacted", func() {
+		It("should drop overwritten device keys and keep the state", func() {
+			now := time.Now().UTC()
+			Expect(store.UpdateDevice(&domain.Device{ID: "test-device", Algorithm: "ECC", Label: "test-device", KeyReference: "plaintext-key"})).To(Succeed())
+			Expect(store.UpdateDevice(&domain.Device{ID: "test-device", Algorithm: "ECC", Label: "test-device", KeyReference: "sealed-key"})).To(Succeed())
+			Expect(store.CreateWebhook(&domain.Webhook{ID: "test-webhook", URL: "http://example.com"})).To(Succeed())
+			Expect(store.CreateDeliveries([]*domain.WebhookDelivery{{ID: "test-delivery", WebhookID: "test-webhook", Status: domain.DeliveryStatusPending, CreatedAt: now}})).To(Succeed())
+			Expect(store.CreateJob(&domain.Job{ID: "queued", DeviceID: "test-device", Status: domain.JobStatusQueued, CreatedAt: now})).To(Succeed())
+
+			Expect(store.Compact()).To(Succeed())
+			journal, err := os.ReadFile(filepath.Join(dir, journalFileName))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(journal)).NotTo(ContainSubstring("plaintext-key"))
+
+			// Appends go to the compacted journal
+			signAndCount(store, "test-device", 2, "third")
+			Expect(store.Close()).To(Succeed())
+
+			reopened, err := NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			defer reopened.Close()
+
+			device, err := reopened.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.KeyReference).To(Equal("sealed-key"))
+			Expect(device.SignatureCounter).To(Equal(3))
+
+			signatures, err := reopened.GetAllSignaturesByDeviceID("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signatures).To(HaveLen(3))
+
+			_, err = reopened.GetDelivery("test-delivery")
+			Expect(err).NotTo(HaveOccurred())
+			unfinished, err := reopened.ListUnfinishedJobs()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(unfinished).To(HaveLen(1))
+
+			entries, err := os.ReadDir(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+		})
+	})
+
+	Context("When the journal was written before devices referred to their key", func() {
+		It("should read the private key as the key reference", func() {
+			Expect(store.Close()).To(Succeed())
+
+			legacyDir := GinkgoT().TempDir()
+			payload := `{"type":"device_created","device":{"ID":"legacy-device","Algorithm":"ECC","PrivateKey":"legacy-key"}}`
+			line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE([]byte(payload)), payload)
+			Expect(os.WriteFile(filepath.Join(legacyDir, journalFileName), []byte(line), 0o600)).To(Succeed())
+
+			reopened, err := NewFileStore(legacyDir)
+			Expect(err).NotTo(HaveOccurred())
+			defer reopened.Close()
+
+			device, err := reopened.GetDevice("legacy-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.KeyReference).To(Equal("legacy-key"))
+		})
+	})
+})
@@ -0,0 +1,112 @@
+package persistence
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+// IJobRepository keeps the background signing jobs. The outcome of a job is stored through
+// ITransaction.UpdateJob together with its signatures.
+type IJobRepository interface {
+	CreateJob(job *domain.Job) error
+	GetJob(id string) (*domain.Job, error)
+	UpdateJob(job *domain.Job) error
+	// ListUnfinishedJobs returns the queued and running jobs in the order they were created.
+	ListUnfinishedJobs() ([]*domain.Job, error)
+	DeleteJobsFinishedBefore(before time.Time) (int, error)
+}
+
+type JobRepository struct {
+	mutex sync.RWMutex
+	jobs map[string]*domain.Job
+}
+
+func NewJobRepository() IJobRepository {
+	return newJobRepository()
+}
+
+func newJobRepository() *JobRepository {
+	return &JobRepository{
+		jobs: make(map[string]*domain.Job),
+	}
+}
+
+// Jobs are stored and handed out as copies, a worker updating a job never races with a reader.
+
+func (m *JobRepository) CreateJob(job *domain.Job) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.jobs[job.ID]; exists {
+		return fmt.Errorf("job with id %s %w", job.ID, ErrAlreadyExists)
+	}
+
+	stored := *job
+	m.jobs[job.ID] = &stored
+	return nil
+}
+
+func (m *JobRepository) GetJob(id string) (*domain.Job, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	job, exists := m.jobs[id]
+	if !exists {
+		return nil, fmt.Errorf("job with id %s %w", id, ErrNotFound)
+	}
+
+	found := *job
+	return &found, nil
+}
+
+func (m *JobRepository) UpdateJob(job *domain.Job) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.jobs[job.ID]; !exists {
+		return fmt.Errorf("job with id %s %w", job.ID, ErrNotFound)
+	}
+
+	stored := *job
+	m.jobs[job.ID] = &stored
+	return nil
+}
+
+func (m *JobRepository) ListUnfinishedJobs() ([]*domain.Job, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	jobs := make([]*domain.Job, 0)
+	for _, job := range m.jobs {
+		if !job.IsFinished() {
+			found := *job
+			jobs = append(jobs, &found)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs, nil
+}
+
+// DeleteJobsFinishedBefore forgets the jobs that finished before the given time and returns how many.
+func (m *JobRepository) DeleteJobsFinishedBefore(before time.Time) (int, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	deleted := 0
+	for id, job := range m.jobs {
+		if job.IsFinished() && job.FinishedAt.Before(before) {
+			delete(m.jobs, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,59 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Job Repository", func() {
+	var jobRepository IJobRepository
+
+	BeforeEach(func() {
+		jobRepository = NewJobRepository()
+	})
+
+	It("should hand out copies of the stored jobs", func() {
+		job := &domain.Job{ID: "job", Status: domain.JobStatusQueued}
+		Expect(jobRepository.CreateJob(job)).To(Succeed())
+		job.Status = domain.JobStatusRunning
+
+		stored, err := jobRepository.GetJob("job")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Status).To(Equal(domain.JobStatusQueued))
+
+		stored.Status = domain.JobStatusSucceeded
+		Expect(jobRepository.UpdateJob(stored)).To(Succeed())
+		updated, err := jobRepository.GetJob("job")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Status).To(Equal(domain.JobStatusSucceeded))
+	})
+
+	It("should reject unknown and duplicate jobs", func() {
+		Expect(jobRepository.CreateJob(&domain.Job{ID: "job"})).To(Succeed())
+
+		Expect(jobRepository.CreateJob(&domain.Job{ID: "job"})).To(MatchError(ErrAlreadyExists))
+		Expect(jobRepository.UpdateJob(&domain.Job{ID: "unknown"})).To(MatchError(ErrNotFound))
+		_, err := jobRepository.GetJob("unknown")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should only delete jobs that finished before the given time", func() {
+		now := time.Now()
+		Expect(jobRepository.CreateJob(&domain.Job{ID: "old", Status: domain.JobStatusSucceeded, FinishedAt: now.Add(-2 * time.Hour)})).To(Succeed())
+		Expect(jobRepository.CreateJob(&domain.Job{ID: "old-failed", Status: domain.JobStatusFailed, FinishedAt: now.Add(-2 * time.Hour)})).To(Succeed())
+		Expect(jobRepository.CreateJob(&domain.Job{ID: "recent", Status: domain.JobStatusSucceeded, FinishedAt: now})).To(Succeed())
+		Expect(jobRepository.CreateJob(&domain.Job{ID: "queued", Status: domain.JobStatusQueued})).To(Succeed())
+
+		Expect(jobRepository.DeleteJobsFinishedBefore(now.Add(-time.Hour))).To(Equal(2))
+
+		_, err := jobRepository.GetJob("old")
+		Expect(err).To(HaveOccurred())
+		_, err = jobRepository.GetJob("recent")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = jobRepository.GetJob("queued")
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -9,6 +9,7 @@ import (
 	sync "sync"
 
 	domain "github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	persistence "github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -120,3 +121,32 @@ func (mr *MockIDeviceRepositoryMockRecorder) IncrementSignatureCounter(deviceID
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementSignatureCounter", reflect.TypeOf((*MockIDeviceRepository)(nil).IncrementSignatureCounter), deviceID)
 }
+
+// ListDevices mocks base method.
+func (m *MockIDeviceRepository) ListDevices(query persistence.DeviceQuery) ([]*domain.Device, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDevices", query)
+	ret0, _ := ret[0].([]*domain.Device)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDevices indicates an expected call of ListDevices.
+func (mr *MockIDeviceRepositoryMockRecorder) ListDevices(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDevices", reflect.TypeOf((*MockIDeviceRepository)(nil).ListDevices), query)
+}
+
+// UpdateDevice mocks base method.
+func (m *MockIDeviceRepository) UpdateDevice(device *domain.Device) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDevice", device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDevice indicates an expected call of UpdateDevice.
+func (mr *MockIDeviceRepositoryMockRecorder) UpdateDevice(device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDevice", reflect.TypeOf((*MockIDeviceRepository)(nil).UpdateDevice), device)
+}
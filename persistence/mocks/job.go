@@ -0,0 +1,109 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: persistence/job.go
+
+// Package mock_persistence is a generated GoMock package.
+package mock_persistence
+
+import (
+	reflect "reflect"
+	time "time"
+
+	domain "github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIJobRepository is a mock of IJobRepository interface.
+type MockIJobRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIJobRepositoryMockRecorder
+}
+
+// MockIJobRepositoryMockRecorder is the mock recorder for MockIJobRepository.
+type MockIJobRepositoryMockRecorder struct {
+	mock *MockIJobRepository
+}
+
+// NewMockIJobRepository creates a new mock instance.
+func NewMockIJobRepository(ctrl *gomock.Controller) *MockIJobRepository {
+	mock := &MockIJobRepository{ctrl: ctrl}
+	mock.recorder = &MockIJobRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIJobRepository) EXPECT() *MockIJobRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateJob mocks base method.
+func (m *MockIJobRepository) CreateJob(job *domain.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJob", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateJob indicates an expected call of CreateJob.
+func (mr *MockIJobRepositoryMockRecorder) CreateJob(job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockIJobRepository)(nil).CreateJob), job)
+}
+
+// DeleteJobsFinishedBefore mocks base method.
+func (m *MockIJobRepository) DeleteJobsFinishedBefore(before time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteJobsFinishedBefore", before)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteJobsFinishedBefore indicates an expected call of DeleteJobsFinishedBefore.
+func (mr *MockIJobRepositoryMockRecorder) DeleteJobsFinishedBefore(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteJobsFinishedBefore", reflect.TypeOf((*MockIJobRepository)(nil).DeleteJobsFinishedBefore), before)
+}
+
+// GetJob mocks base method.
+func (m *MockIJobRepository) GetJob(id string) (*domain.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJob", id)
+	ret0, _ := ret[0].(*domain.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJob indicates an expected call of GetJob.
+func (mr *MockIJobRepositoryMockRecorder) GetJob(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJob", reflect.TypeOf((*MockIJobRepository)(nil).GetJob), id)
+}
+
+// ListUnfinishedJobs mocks base method.
+func (m *MockIJobRepository) ListUnfinishedJobs() ([]*domain.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnfinishedJobs")
+	ret0, _ := ret[0].([]*domain.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUnfinishedJobs indicates an expected call of ListUnfinishedJobs.
+func (mr *MockIJobRepositoryMockRecorder) ListUnfinishedJobs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnfinishedJobs", reflect.TypeOf((*MockIJobRepository)(nil).ListUnfinishedJobs))
+}
+
+// UpdateJob mocks base method.
+func (m *MockIJobRepository) UpdateJob(job *domain.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJob", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateJob indicates an expected call of UpdateJob.
+func (mr *MockIJobRepositoryMockRecorder) UpdateJob(job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJob", reflect.TypeOf((*MockIJobRepository)(nil).UpdateJob), job)
+}
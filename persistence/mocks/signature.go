@@ -6,8 +6,10 @@ package mock_persistence
 
 import (
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	persistence "github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -48,6 +50,20 @@ func (mr *MockISignatureRepositoryMockRecorder) CreateSignature(signature interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSignature", reflect.TypeOf((*MockISignatureRepository)(nil).CreateSignature), signature)
 }
 
+// DeleteIdempotencyKeysBefore mocks base method.
+func (m *MockISignatureRepository) DeleteIdempotencyKeysBefore(before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdempotencyKeysBefore", before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdempotencyKeysBefore indicates an expected call of DeleteIdempotencyKeysBefore.
+func (mr *MockISignatureRepositoryMockRecorder) DeleteIdempotencyKeysBefore(before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdempotencyKeysBefore", reflect.TypeOf((*MockISignatureRepository)(nil).DeleteIdempotencyKeysBefore), before)
+}
+
 // GetAllSignatures mocks base method.
 func (m *MockISignatureRepository) GetAllSignatures() ([]*domain.Signature, error) {
 	m.ctrl.T.Helper()
@@ -92,3 +108,33 @@ func (mr *MockISignatureRepositoryMockRecorder) GetLatestSignature(deviceID inte
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLatestSignature", reflect.TypeOf((*MockISignatureRepository)(nil).GetLatestSignature), deviceID)
 }
+
+// GetSignatureByIdempotencyKey mocks base method.
+func (m *MockISignatureRepository) GetSignatureByIdempotencyKey(deviceID, key string) (*domain.Signature, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSignatureByIdempotencyKey", deviceID, key)
+	ret0, _ := ret[0].(*domain.Signature)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSignatureByIdempotencyKey indicates an expected call of GetSignatureByIdempotencyKey.
+func (mr *MockISignatureRepositoryMockRecorder) GetSignatureByIdempotencyKey(deviceID, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSignatureByIdempotencyKey", reflect.TypeOf((*MockISignatureRepository)(nil).GetSignatureByIdempotencyKey), deviceID, key)
+}
+
+// ListSignatures mocks base method.
+func (m *MockISignatureRepository) ListSignatures(query persistence.SignatureQuery) ([]*domain.Signature, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSignatures", query)
+	ret0, _ := ret[0].([]*domain.Signature)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSignatures indicates an expected call of ListSignatures.
+func (mr *MockISignatureRepositoryMockRecorder) ListSignatures(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSignatures", reflect.TypeOf((*MockISignatureRepository)(nil).ListSignatures), query)
+}
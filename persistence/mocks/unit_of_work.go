@@ -0,0 +1,169 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: persistence/unit_of_work.go
+
+// Package mock_persistence is a generated GoMock package.
+package mock_persistence
+
+import (
+	reflect "reflect"
+
+	domain "github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	persistence "github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockITransaction is a mock of ITransaction interface.
+type MockITransaction struct {
+	ctrl     *gomock.Controller
+	recorder *MockITransactionMockRecorder
+}
+
+// MockITransactionMockRecorder is the mock recorder for MockITransaction.
+type MockITransactionMockRecorder struct {
+	mock *MockITransaction
+}
+
+// NewMockITransaction creates a new mock instance.
+func NewMockITransaction(ctrl *gomock.Controller) *MockITransaction {
+	mock := &MockITransaction{ctrl: ctrl}
+	mock.recorder = &MockITransactionMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockITransaction) EXPECT() *MockITransactionMockRecorder {
+	return m.recorder
+}
+
+// AfterCommit mocks base method.
+func (m *MockITransaction) AfterCommit(fn func()) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AfterCommit", fn)
+}
+
+// AfterCommit indicates an expected call of AfterCommit.
+func (mr *MockITransactionMockRecorder) AfterCommit(fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AfterCommit", reflect.TypeOf((*MockITransaction)(nil).AfterCommit), fn)
+}
+
+// CreateDeliveries mocks base method.
+func (m *MockITransaction) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeliveries", deliveries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDeliveries indicates an expected call of CreateDeliveries.
+func (mr *MockITransactionMockRecorder) CreateDeliveries(deliveries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeliveries", reflect.TypeOf((*MockITransaction)(nil).CreateDeliveries), deliveries)
+}
+
+// CreateDevice mocks base method.
+func (m *MockITransaction) CreateDevice(device *domain.Device) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDevice", device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDevice indicates an expected call of CreateDevice.
+func (mr *MockITransactionMockRecorder) CreateDevice(device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDevice", reflect.TypeOf((*MockITransaction)(nil).CreateDevice), device)
+}
+
+// CreateSignature mocks base method.
+func (m *MockITransaction) CreateSignature(signature *domain.Signature) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSignature", signature)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSignature indicates an expected call of CreateSignature.
+func (mr *MockITransactionMockRecorder) CreateSignature(signature interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSignature", reflect.TypeOf((*MockITransaction)(nil).CreateSignature), signature)
+}
+
+// IncrementSignatureCounter mocks base method.
+func (m *MockITransaction) IncrementSignatureCounter(deviceID string, counter int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementSignatureCounter", deviceID, counter)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// IncrementSignatureCounter indicates an expected call of IncrementSignatureCounter.
+func (mr *MockITransactionMockRecorder) IncrementSignatureCounter(deviceID, counter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementSignatureCounter", reflect.TypeOf((*MockITransaction)(nil).IncrementSignatureCounter), deviceID, counter)
+}
+
+// UpdateDevice mocks base method.
+func (m *MockITransaction) UpdateDevice(device *domain.Device) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDevice", device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDevice indicates an expected call of UpdateDevice.
+func (mr *MockITransactionMockRecorder) UpdateDevice(device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDevice", reflect.TypeOf((*MockITransaction)(nil).UpdateDevice), device)
+}
+
+// UpdateJob mocks base method.
+func (m *MockITransaction) UpdateJob(job *domain.Job) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateJob", job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateJob indicates an expected call of UpdateJob.
+func (mr *MockITransactionMockRecorder) UpdateJob(job interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateJob", reflect.TypeOf((*MockITransaction)(nil).UpdateJob), job)
+}
+
+// MockIUnitOfWork is a mock of IUnitOfWork interface.
+type MockIUnitOfWork struct {
+	ctrl     *gomock.Controller
+	recorder *MockIUnitOfWorkMockRecorder
+}
+
+// MockIUnitOfWorkMockRecorder is the mock recorder for MockIUnitOfWork.
+type MockIUnitOfWorkMockRecorder struct {
+	mock *MockIUnitOfWork
+}
+
+// NewMockIUnitOfWork creates a new mock instance.
+func NewMockIUnitOfWork(ctrl *gomock.Controller) *MockIUnitOfWork {
+	mock := &MockIUnitOfWork{ctrl: ctrl}
+	mock.recorder = &MockIUnitOfWorkMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIUnitOfWork) EXPECT() *MockIUnitOfWorkMockRecorder {
+	return m.recorder
+}
+
+// Execute mocks base method.
+func (m *MockIUnitOfWork) Execute(work func(persistence.ITransaction) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Execute", work)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Execute indicates an expected call of Execute.
+func (mr *MockIUnitOfWorkMockRecorder) Execute(work interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Execute", reflect.TypeOf((*MockIUnitOfWork)(nil).Execute), work)
+}
@@ -0,0 +1,166 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: persistence/webhook.go
+
+// Package mock_persistence is a generated GoMock package.
+package mock_persistence
+
+import (
+	reflect "reflect"
+
+	domain "github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	persistence "github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIWebhookRepository is a mock of IWebhookRepository interface.
+type MockIWebhookRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockIWebhookRepositoryMockRecorder
+}
+
+// MockIWebhookRepositoryMockRecorder is the mock recorder for MockIWebhookRepository.
+type MockIWebhookRepositoryMockRecorder struct {
+	mock *MockIWebhookRepository
+}
+
+// NewMockIWebhookRepository creates a new mock instance.
+func NewMockIWebhookRepository(ctrl *gomock.Controller) *MockIWebhookRepository {
+	mock := &MockIWebhookRepository{ctrl: ctrl}
+	mock.recorder = &MockIWebhookRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIWebhookRepository) EXPECT() *MockIWebhookRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CreateDeliveries mocks base method.
+func (m *MockIWebhookRepository) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeliveries", deliveries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateDeliveries indicates an expected call of CreateDeliveries.
+func (mr *MockIWebhookRepositoryMockRecorder) CreateDeliveries(deliveries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeliveries", reflect.TypeOf((*MockIWebhookRepository)(nil).CreateDeliveries), deliveries)
+}
+
+// CreateWebhook mocks base method.
+func (m *MockIWebhookRepository) CreateWebhook(webhook *domain.Webhook) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhook", webhook)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWebhook indicates an expected call of CreateWebhook.
+func (mr *MockIWebhookRepositoryMockRecorder) CreateWebhook(webhook interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhook", reflect.TypeOf((*MockIWebhookRepository)(nil).CreateWebhook), webhook)
+}
+
+// DeleteDelivery mocks base method.
+func (m *MockIWebhookRepository) DeleteDelivery(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDelivery", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDelivery indicates an expected call of DeleteDelivery.
+func (mr *MockIWebhookRepositoryMockRecorder) DeleteDelivery(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDelivery", reflect.TypeOf((*MockIWebhookRepository)(nil).DeleteDelivery), id)
+}
+
+// DeleteWebhook mocks base method.
+func (m *MockIWebhookRepository) DeleteWebhook(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhook", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhook indicates an expected call of DeleteWebhook.
+func (mr *MockIWebhookRepositoryMockRecorder) DeleteWebhook(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhook", reflect.TypeOf((*MockIWebhookRepository)(nil).DeleteWebhook), id)
+}
+
+// GetAllWebhooks mocks base method.
+func (m *MockIWebhookRepository) GetAllWebhooks() ([]*domain.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllWebhooks")
+	ret0, _ := ret[0].([]*domain.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllWebhooks indicates an expected call of GetAllWebhooks.
+func (mr *MockIWebhookRepositoryMockRecorder) GetAllWebhooks() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllWebhooks", reflect.TypeOf((*MockIWebhookRepository)(nil).GetAllWebhooks))
+}
+
+// GetDelivery mocks base method.
+func (m *MockIWebhookRepository) GetDelivery(id string) (*domain.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDelivery", id)
+	ret0, _ := ret[0].(*domain.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDelivery indicates an expected call of GetDelivery.
+func (mr *MockIWebhookRepositoryMockRecorder) GetDelivery(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDelivery", reflect.TypeOf((*MockIWebhookRepository)(nil).GetDelivery), id)
+}
+
+// GetWebhook mocks base method.
+func (m *MockIWebhookRepository) GetWebhook(id string) (*domain.Webhook, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhook", id)
+	ret0, _ := ret[0].(*domain.Webhook)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhook indicates an expected call of GetWebhook.
+func (mr *MockIWebhookRepositoryMockRecorder) GetWebhook(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhook", reflect.TypeOf((*MockIWebhookRepository)(nil).GetWebhook), id)
+}
+
+// ListDeliveries mocks base method.
+func (m *MockIWebhookRepository) ListDeliveries(query persistence.DeliveryQuery) ([]*domain.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListDeliveries", query)
+	ret0, _ := ret[0].([]*domain.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListDeliveries indicates an expected call of ListDeliveries.
+func (mr *MockIWebhookRepositoryMockRecorder) ListDeliveries(query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDeliveries", reflect.TypeOf((*MockIWebhookRepository)(nil).ListDeliveries), query)
+}
+
+// UpdateDelivery mocks base method.
+func (m *MockIWebhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockIWebhookRepositoryMockRecorder) UpdateDelivery(delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockIWebhookRepository)(nil).UpdateDelivery), delivery)
+}
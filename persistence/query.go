@@ -0,0 +1,237 @@
+package persistence
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+// DeviceQuery selects a page of devices. Devices are ordered by creation time, devices created
+// at the same time (or before creation times were stored) by ID.
+type DeviceQuery struct {
+	Algorithm   string              // Only devices of this algorithm, if set
+	LabelPrefix string              // Only devices whose label starts with this prefix, if set
+	Status      domain.DeviceStatus // Only devices in this status, if set
+	After       *DeviceCursor       // Continue behind this device, in the direction of the query
+	Limit       int                 // Maximum number of devices, 0 means no limit
+	Descending  bool
+}
+
+// DeviceCursor is the position of a device in the order of DeviceQuery.
+type DeviceCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// CursorOf returns the position of device.
+func CursorOf(device *domain.Device) DeviceCursor {
+	return DeviceCursor{CreatedAt: device.CreatedAt, ID: device.ID}
+}
+
+// Before reports whether c comes before other in ascending order.
+func (c DeviceCursor) Before(other DeviceCursor) bool {
+	if !c.CreatedAt.Equal(other.CreatedAt) {
+		return c.CreatedAt.Before(other.CreatedAt)
+	}
+	return c.ID < other.ID
+}
+
+// Matches reports whether device passes the filters of the query, ignoring its position.
+func (q DeviceQuery) Matches(device *domain.Device) bool {
+	return (q.Algorithm == "" || device.Algorithm == q.Algorithm) &&
+		strings.HasPrefix(device.Label, q.LabelPrefix) &&
+		(q.Status == "" || device.CurrentStatus() == q.Status)
+}
+
+// Apply filters, orders and pages devices the way the query describes.
+func (q DeviceQuery) Apply(devices []*domain.Device) []*domain.Device {
+	selected := make([]*domain.Device, 0, len(devices))
+	for _, device := range devices {
+		if !q.Matches(device) {
+			continue
+		}
+		if q.After != nil && !q.behindCursor(CursorOf(device)) {
+			continue
+		}
+		selected = append(selected, device)
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		if q.Descending {
+			return CursorOf(selected[j]).Before(CursorOf(selected[i]))
+		}
+		return CursorOf(selected[i]).Before(CursorOf(selected[j]))
+	})
+
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected
+}
+
+// behindCursor reports whether position comes after the cursor in the direction of the query.
+func (q DeviceQuery) behindCursor(position DeviceCursor) bool {
+	if q.Descending {
+		return position.Before(*q.After)
+	}
+	return q.After.Before(position)
+}
+
+// SignatureQuery selects a page of the signatures of one device, ordered by signature counter.
+// Counter bounds are inclusive, time bounds include From and exclude To.
+type SignatureQuery struct {
+	DeviceID     string
+	FromCounter  *int
+	ToCounter    *int
+	CreatedFrom  time.Time // Zero means unbounded
+	CreatedTo    time.Time // Zero means unbounded
+	AfterCounter *int      // Continue behind this counter, in the direction of the query
+	Limit        int       // Maximum number of signatures, 0 means no limit
+	Descending   bool
+}
+
+// Matches reports whether signature passes the filters and the cursor of the query.
+func (q SignatureQuery) Matches(signature *domain.Signature) bool {
+	counter := signature.SignatureCounter
+	switch {
+	case signature.DeviceID != q.DeviceID:
+		return false
+	case q.FromCounter != nil && counter < *q.FromCounter:
+		return false
+	case q.ToCounter != nil && counter > *q.ToCounter:
+		return false
+	case !q.CreatedFrom.IsZero() && signature.CreatedAt.Before(q.CreatedFrom):
+		return false
+	case !q.CreatedTo.IsZero() && !signature.CreatedAt.Before(q.CreatedTo):
+		return false
+	case q.AfterCounter != nil && q.Descending && counter >= *q.AfterCounter:
+		return false
+	case q.AfterCounter != nil && !q.Descending && counter <= *q.AfterCounter:
+		return false
+	}
+	return true
+}
+
+// Apply filters, orders and pages signatures the way the query describes.
+func (q SignatureQuery) Apply(signatures []*domain.Signature) []*domain.Signature {
+	selected := make([]*domain.Signature, 0)
+	for _, signature := range signatures {
+		if q.Matches(signature) {
+			selected = append(selected, signature)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		if q.Descending {
+			return selected[i].SignatureCounter > selected[j].SignatureCounter
+		}
+		return selected[i].SignatureCounter < selected[j].SignatureCounter
+	})
+
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected
+}
+
+// PageCursor is the position the next page of a DeviceQuery or SignatureQuery starts behind.
+// It is handed to clients base64url encoded, so they treat it as opaque.
+type PageCursor struct {
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ID        string     `json:"id,omitempty"`
+	Counter   *int       `json:"counter,omitempty"`
+}
+
+// EncodeDevicePageCursor returns the cursor of the page that follows device.
+func EncodeDevicePageCursor(device *domain.Device) string {
+	createdAt := device.CreatedAt
+	return encodePageCursor(PageCursor{CreatedAt: &createdAt, ID: device.ID})
+}
+
+// EncodeSignaturePageCursor returns the cursor of the page that follows signature.
+func EncodeSignaturePageCursor(signature *domain.Signature) string {
+	counter := signature.SignatureCounter
+	return encodePageCursor(PageCursor{Counter: &counter})
+}
+
+func encodePageCursor(cursor PageCursor) string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// DecodePageCursor reads an encoded cursor, an empty one stands for the first page.
+func DecodePageCursor(encoded string) (*PageCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &PageCursor{}
+	if err := json.Unmarshal(decoded, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// DeviceCursor returns the position a DeviceQuery continues behind.
+func (c *PageCursor) DeviceCursor() (*DeviceCursor, error) {
+	if c.CreatedAt == nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &DeviceCursor{CreatedAt: *c.CreatedAt, ID: c.ID}, nil
+}
+
+// SignatureCounter returns the counter a SignatureQuery continues behind.
+func (c *PageCursor) SignatureCounter() (*int, error) {
+	if c.Counter == nil {
+		return nil, ErrInvalidCursor
+	}
+	return c.Counter, nil
+}
+
+// DeliveryQuery selects outbox deliveries, oldest first.
+type DeliveryQuery struct {
+	Status domain.DeliveryStatus // Empty matches every status
+	DueBy  time.Time             // Only deliveries to attempt at or before this time, zero matches every delivery
+	Limit  int                   // Maximum number of deliveries, 0 means no limit
+}
+
+// Matches reports whether the delivery is selected by the query.
+func (q DeliveryQuery) Matches(delivery *domain.WebhookDelivery) bool {
+	if q.Status != "" && delivery.Status != q.Status {
+		return false
+	}
+	if !q.DueBy.IsZero() && delivery.NextAttemptAt.After(q.DueBy) {
+		return false
+	}
+	return true
+}
+
+// Apply filters, orders and limits deliveries.
+func (q DeliveryQuery) Apply(deliveries []*domain.WebhookDelivery) []*domain.WebhookDelivery {
+	selected := make([]*domain.WebhookDelivery, 0)
+	for _, delivery := range deliveries {
+		if q.Matches(delivery) {
+			selected = append(selected, delivery)
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool {
+		if !selected[i].CreatedAt.Equal(selected[j].CreatedAt) {
+			return selected[i].CreatedAt.Before(selected[j].CreatedAt)
+		}
+		return selected[i].ID < selected[j].ID
+	})
+
+	if q.Limit > 0 && len(selected) > q.Limit {
+		selected = selected[:q.Limit]
+	}
+	return selected
+}
@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Queries", func() {
+	base := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	Context("When listing devices", func() {
+		var devices []*domain.Device
+
+		ids := func(devices []*domain.Device) []string {
+			result := make([]string, 0, len(devices))
+			for _, device := range devices {
+				result = append(result, device.ID)
+			}
+			return result
+		}
+
+		BeforeEach(func() {
+			devices = []*domain.Device{
+				{ID: "c", Algorithm: "ECC", Label: "till-1", CreatedAt: base.Add(time.Minute)},
+				{ID: "a", Algorithm: "RSA", Label: "till-2", CreatedAt: base.Add(time.Minute)},
+				{ID: "legacy", Algorithm: "ECC", Label: "kiosk"},
+				{ID: "b", Algorithm: "ECC", Label: "till-3", CreatedAt: base, Status: domain.DeviceStatusSuspended},
+			}
+		})
+
+		It("should order by creation time and then by ID", func() {
+			Expect(ids(DeviceQuery{}.Apply(devices))).To(Equal([]string{"legacy", "b", "a", "c"}))
+			Expect(ids(DeviceQuery{Descending: true}.Apply(devices))).To(Equal([]string{"c", "a", "b", "legacy"}))
+		})
+
+		It("should continue behind the cursor in either direction", func() {
+			after := CursorOf(devices[1])
+
+			Expect(ids(DeviceQuery{After: &after}.Apply(devices))).To(Equal([]string{"c"}))
+			Expect(ids(DeviceQuery{After: &after, Descending: true, Limit: 1}.Apply(devices))).To(Equal([]string{"b"}))
+		})
+
+		It("should filter by algorithm, label prefix and status", func() {
+			Expect(ids(DeviceQuery{Algorithm: "ECC", LabelPrefix: "till-"}.Apply(devices))).To(Equal([]string{"b", "c"}))
+			Expect(ids(DeviceQuery{Status: domain.DeviceStatusActive}.Apply(devices))).To(Equal([]string{"legacy", "a", "c"}))
+		})
+
+		It("should match multibyte label prefixes", func() {
+			devices = append(devices,
+				&domain.Device{ID: "cafe", Label: "Café-Berlin"},
+				&domain.Device{ID: "cafes", Label: "Cafés-Berlin"},
+			)
+			Expect(ids(DeviceQuery{LabelPrefix: "Café-"}.Apply(devices))).To(Equal([]string{"cafe"}))
+		})
+	})
+
+	Context("When listing signatures", func() {
+		var signatures []*domain.Signature
+
+		counters := func(signatures []*domain.Signature) []int {
+			result := make([]int, 0, len(signatures))
+			for _, signature := range signatures {
+				result = append(result, signature.SignatureCounter)
+			}
+			return result
+		}
+
+		BeforeEach(func() {
+			signatures = []*domain.Signature{
+				{DeviceID: "other", SignatureCounter: 0, CreatedAt: base},
+			}
+			for counter := 4; counter >= 0; counter-- {
+				signatures = append(signatures, &domain.Signature{
+					DeviceID:         "test-device",
+					SignatureCounter: counter,
+					CreatedAt:        base.Add(time.Duration(counter) * time.Minute),
+				})
+			}
+		})
+
+		It("should order the signatures of the device by counter", func() {
+			Expect(counters(SignatureQuery{DeviceID: "test-device"}.Apply(signatures))).To(Equal([]int{0, 1, 2, 3, 4}))
+		})
+
+		It("should filter by counter and time range", func() {
+			from, to := 1, 3
+			query := SignatureQuery{DeviceID: "test-device", FromCounter: &from, ToCounter: &to}
+			Expect(counters(query.Apply(signatures))).To(Equal([]int{1, 2, 3}))
+
+			query = SignatureQuery{DeviceID: "test-device", CreatedFrom: base.Add(2 * time.Minute), CreatedTo: base.Add(4 * time.Minute)}
+			Expect(counters(query.Apply(signatures))).To(Equal([]int{2, 3}))
+		})
+
+		It("should page behind the cursor", func() {
+			after := 1
+			query := SignatureQuery{DeviceID: "test-device", AfterCounter: &after, Limit: 2}
+			Expect(counters(query.Apply(signatures))).To(Equal([]int{2, 3}))
+
+			query.Descending = true
+			Expect(counters(query.Apply(signatures))).To(Equal([]int{0}))
+		})
+	})
+})
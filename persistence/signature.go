@@ -3,6 +3,7 @@ package persistence
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
 )
@@ -12,17 +13,38 @@ type ISignatureRepository interface {
 	GetLatestSignature(deviceID string) (*domain.Signature, error)
 	GetAllSignatures() ([]*domain.Signature, error)
 	GetAllSignaturesByDeviceID(deviceID string) ([]*domain.Signature, error)
+	ListSignatures(query SignatureQuery) ([]*domain.Signature, error)
+	// GetSignatureByIdempotencyKey returns the latest signature of the device created under key,
+	// or nil if there is none.
+	GetSignatureByIdempotencyKey(deviceID string, key string) (*domain.Signature, error)
+	// DeleteIdempotencyKeysBefore forgets the idempotency keys of signatures created before the
+	// given time, their signatures are no longer found by GetSignatureByIdempotencyKey.
+	DeleteIdempotencyKeysBefore(before time.Time) error
 }
 
 type SignatureRepository struct {
 	mutex sync.RWMutex
 	signatures map[string]*domain.Signature
+
+	idempotencyKeys  map[idempotencyKey]*domain.Signature // Latest signature per device and key
+	keyedSignatures  []*domain.Signature                  // Signatures with a key in the order they were stored, oldest first
+}
+
+// idempotencyKey identifies a key of GetSignatureByIdempotencyKey, keys are scoped to a device.
+type idempotencyKey struct {
+	deviceID string
+	key      string
 }
 
 func NewSignatureRepository() ISignatureRepository {
+	return newSignatureRepository()
+}
+
+func newSignatureRepository() *SignatureRepository {
 	return &SignatureRepository{
-		mutex:      sync.RWMutex{},
-		signatures: make(map[string]*domain.Signature),
+		mutex:           sync.RWMutex{},
+		signatures:      make(map[string]*domain.Signature),
+		idempotencyKeys: make(map[idempotencyKey]*domain.Signature),
 	}
 }
 
@@ -31,6 +53,14 @@ func (s *SignatureRepository) CreateSignature(signature *domain.Signature) error
 	defer s.mutex.Unlock()
 
 	s.signatures[signature.ID] = signature
+
+	if signature.IdempotencyKey != "" {
+		indexKey := idempotencyKey{deviceID: signature.DeviceID, key: signature.IdempotencyKey}
+		if latest := s.idempotencyKeys[indexKey]; latest == nil || signature.SignatureCounter > latest.SignatureCounter {
+			s.idempotencyKeys[indexKey] = signature
+		}
+		s.keyedSignatures = append(s.keyedSignatures, signature)
+	}
 	return nil
 }
 
@@ -78,4 +108,41 @@ func (s *SignatureRepository) GetAllSignaturesByDeviceID(deviceID string) ([]*do
 		}
 	}
 	return signatures, nil
-}
\ No newline at end of file
+}
+
+func (s *SignatureRepository) ListSignatures(query SignatureQuery) ([]*domain.Signature, error) {
+	signatures, err := s.GetAllSignaturesByDeviceID(query.DeviceID)
+	if err != nil {
+		return nil, err
+	}
+	return query.Apply(signatures), nil
+}
+
+func (s *SignatureRepository) GetSignatureByIdempotencyKey(deviceID string, key string) (*domain.Signature, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.idempotencyKeys[idempotencyKey{deviceID: deviceID, key: key}], nil
+}
+
+// DeleteIdempotencyKeysBefore drops the keys from the front of the keyed signatures, so it only
+// costs as much as there are keys to forget. Signatures are stored about in the order they were
+// created, one stored out of order is forgotten with the next purge after its successor.
+func (s *SignatureRepository) DeleteIdempotencyKeysBefore(before time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expired := 0
+	for _, signature := range s.keyedSignatures {
+		if !signature.CreatedAt.Before(before) {
+			break
+		}
+		indexKey := idempotencyKey{deviceID: signature.DeviceID, key: signature.IdempotencyKey}
+		if s.idempotencyKeys[indexKey] == signature {
+			delete(s.idempotencyKeys, indexKey)
+		}
+		expired++
+	}
+	s.keyedSignatures = s.keyedSignatures[expired:]
+	return nil
+}
@@ -0,0 +1,63 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signature Repository", func() {
+	var repository ISignatureRepository
+	base := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	sign := func(id string, deviceID string, counter int, key string, createdAt time.Time) {
+		Expect(repository.CreateSignature(&domain.Signature{
+			ID:               id,
+			DeviceID:         deviceID,
+			SignatureCounter: counter,
+			IdempotencyKey:   key,
+			CreatedAt:        createdAt,
+		})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		repository = NewSignatureRepository()
+	})
+
+	It("should find the latest signature of a device by idempotency key", func() {
+		sign("first", "test-device", 0, "key-1", base)
+		sign("second", "test-device", 1, "key-1", base.Add(time.Minute))
+		sign("other", "other-device", 0, "key-1", base)
+		sign("unkeyed", "test-device", 2, "", base)
+
+		signature, err := repository.GetSignatureByIdempotencyKey("test-device", "key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature.ID).To(Equal("second"))
+
+		signature, err = repository.GetSignatureByIdempotencyKey("test-device", "key-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature).To(BeNil())
+	})
+
+	It("should forget the keys of signatures created before the retention window", func() {
+		sign("expired", "test-device", 0, "key-1", base)
+		sign("retained", "test-device", 1, "key-2", base.Add(time.Hour))
+
+		Expect(repository.DeleteIdempotencyKeysBefore(base.Add(time.Minute))).To(Succeed())
+
+		signature, err := repository.GetSignatureByIdempotencyKey("test-device", "key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature).To(BeNil())
+
+		signature, err = repository.GetSignatureByIdempotencyKey("test-device", "key-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signature.ID).To(Equal("retained"))
+
+		// The signature itself is kept
+		signatures, err := repository.GetAllSignaturesByDeviceID("test-device")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signatures).To(HaveLen(2))
+	})
+})
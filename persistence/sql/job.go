@@ -0,0 +1,178 @@
+package sql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+)
+
+// jobColumns are the columns read by scanJob.
+const jobColumns = `id, device_id, data, callback_url, status, error, created_at, started_at, finished_at`
+
+func (s *Store) CreateJob(job *domain.Job) error {
+	data, err := json.Marshal(job.Data)
+	if err != nil {
+		return err
+	}
+
+	return withTx(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO jobs (`+jobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			job.ID, job.DeviceID, string(data), job.CallbackURL, job.Status, job.Error,
+			job.CreatedAt.UTC(), job.StartedAt.UTC(), job.FinishedAt.UTC(),
+		)
+		if err != nil {
+			return alreadyExists(err, "job with id "+job.ID)
+		}
+		return insertJobSignatures(tx, job)
+	})
+}
+
+func (s *Store) GetJob(id string) (*domain.Job, error) {
+	row := s.db.QueryRow(`SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id)
+
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("job with id %s %w", id, persistence.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadJobSignatures(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *Store) UpdateJob(job *domain.Job) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return updateJob(tx, job)
+	})
+}
+
+func (t transaction) UpdateJob(job *domain.Job) error {
+	return updateJob(t.tx, job)
+}
+
+// ListUnfinishedJobs selects the queued and running jobs in the order they were created.
+func (s *Store) ListUnfinishedJobs() ([]*domain.Job, error) {
+	rows, err := s.db.Query(
+		`SELECT `+jobColumns+` FROM jobs WHERE status IN (?, ?) ORDER BY created_at, id`,
+		domain.JobStatusQueued, domain.JobStatusRunning,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := make([]*domain.Job, 0)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, job := range jobs {
+		if err := s.loadJobSignatures(job); err != nil {
+			return nil, err
+		}
+	}
+	return jobs, nil
+}
+
+// DeleteJobsFinishedBefore removes the jobs that finished before the given time, the signatures
+// they created are kept.
+func (s *Store) DeleteJobsFinishedBefore(before time.Time) (int, error) {
+	finished := `status IN (?, ?) AND finished_at < ?`
+	args := []interface{}{domain.JobStatusSucceeded, domain.JobStatusFailed, before.UTC()}
+
+	var deleted int64
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM job_signatures WHERE job_id IN (SELECT id FROM jobs WHERE `+finished+`)`, args...); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`DELETE FROM jobs WHERE `+finished, args...)
+		if err != nil {
+			return err
+		}
+		deleted, err = result.RowsAffected()
+		return err
+	})
+	return int(deleted), err
+}
+
+func updateJob(db execer, job *domain.Job) error {
+	result, err := db.Exec(
+		`UPDATE jobs SET status = ?, error = ?, started_at = ?, finished_at = ? WHERE id = ?`,
+		job.Status, job.Error, job.StartedAt.UTC(), job.FinishedAt.UTC(), job.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(result, fmt.Errorf("job with id %s %w", job.ID, persistence.ErrNotFound)); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DELETE FROM job_signatures WHERE job_id = ?`, job.ID); err != nil {
+		return err
+	}
+	return insertJobSignatures(db, job)
+}
+
+// insertJobSignatures links the job to its signatures, which are stored on their own.
+func insertJobSignatures(db execer, job *domain.Job) error {
+	for position, signature := range job.Signatures {
+		_, err := db.Exec(
+			`INSERT INTO job_signatures (job_id, position, signature_id) VALUES (?, ?, ?)`,
+			job.ID, position, signature.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) loadJobSignatures(job *domain.Job) error {
+	signatures, err := s.querySignatures(
+		`SELECT `+signatureColumns+` FROM signatures JOIN job_signatures ON signature_id = id WHERE job_id = ? ORDER BY position`,
+		job.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if len(signatures) > 0 {
+		job.Signatures = signatures
+	}
+	return nil
+}
+
+func scanJob(row scanner) (*domain.Job, error) {
+	var job domain.Job
+	var data string
+	err := row.Scan(
+		&job.ID, &job.DeviceID, &data, &job.CallbackURL, &job.Status, &job.Error,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &job.Data); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
@@ -0,0 +1,172 @@
+package sql
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migrations holds the schema changes in the order they have to be applied.
+// The version of a migration is its index plus one, released migrations must never be edited.
+var migrations = []string{
+	// 1: devices and their signature chains
+	`CREATE TABLE devices (
+		id                TEXT PRIMARY KEY,
+		algorithm         TEXT NOT NULL,
+		public_key        TEXT NOT NULL,
+		private_key       TEXT NOT NULL,
+		signature_counter INTEGER NOT NULL DEFAULT 0,
+		label             TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE signatures (
+		id                TEXT PRIMARY KEY,
+		device_id         TEXT NOT NULL REFERENCES devices (id),
+		signature_counter INTEGER NOT NULL,
+		signature_value   TEXT NOT NULL,
+		CONSTRAINT signatures_device_counter_unique UNIQUE (device_id, signature_counter)
+	);`,
+	// 2: the exact string that was signed, required to verify the chain
+	`ALTER TABLE signatures ADD COLUMN signed_data TEXT NOT NULL DEFAULT ''`,
+	// 3: the signed payload and its context, required to rebuild receipts
+	`ALTER TABLE signatures ADD COLUMN data TEXT NOT NULL DEFAULT '';
+	ALTER TABLE signatures ADD COLUMN algorithm TEXT NOT NULL DEFAULT '';
+	ALTER TABLE signatures ADD COLUMN previous_signature_id TEXT NOT NULL DEFAULT '';
+	ALTER TABLE signatures ADD COLUMN created_at TIMESTAMP;`,
+	// 4: key parameters chosen per device
+	`ALTER TABLE devices ADD COLUMN key_size INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE devices ADD COLUMN curve TEXT NOT NULL DEFAULT '';
+	ALTER TABLE devices ADD COLUMN hash TEXT NOT NULL DEFAULT '';
+	ALTER TABLE devices ADD COLUMN padding TEXT NOT NULL DEFAULT '';`,
+	// 5: devices only refer to their key, existing private keys are valid references of the local key provider
+	`ALTER TABLE devices RENAME COLUMN private_key TO key_reference`,
+	// 6: public keys a device signed with before its key was rotated
+	`CREATE TABLE device_key_history (
+		device_id     TEXT NOT NULL REFERENCES devices (id),
+		public_key    TEXT NOT NULL,
+		first_counter INTEGER NOT NULL,
+		last_counter  INTEGER NOT NULL,
+		rotated_at    TIMESTAMP NOT NULL,
+		PRIMARY KEY (device_id, first_counter)
+	);`,
+	// 7: device lifecycle and operator metadata, existing devices are active
+	`ALTER TABLE devices ADD COLUMN status TEXT NOT NULL DEFAULT 'active';
+	CREATE TABLE device_metadata (
+		device_id TEXT NOT NULL REFERENCES devices (id),
+		key       TEXT NOT NULL,
+		value     TEXT NOT NULL,
+		PRIMARY KEY (device_id, key)
+	);`,
+	// 8: creation time of devices to page through them in order, existing devices sort first
+	`ALTER TABLE devices ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '0001-01-01 00:00:00+00:00';
+	CREATE INDEX devices_created_at ON devices (created_at, id);`,
+	// 9: Idempotency-Key of the sign request, a retry with the same key returns the stored signature
+	`ALTER TABLE signatures ADD COLUMN idempotency_key TEXT NOT NULL DEFAULT '';
+	CREATE INDEX signatures_idempotency_key ON signatures (device_id, idempotency_key);`,
+	// 10: webhooks and the outbox of their deliveries
+	`CREATE TABLE webhooks (
+		id         TEXT PRIMARY KEY,
+		url        TEXT NOT NULL,
+		secret     TEXT NOT NULL,
+		device_id  TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL
+	);
+	CREATE TABLE webhook_events (
+		webhook_id TEXT NOT NULL REFERENCES webhooks (id),
+		event      TEXT NOT NULL,
+		PRIMARY KEY (webhook_id, event)
+	);
+	CREATE TABLE webhook_deliveries (
+		id              TEXT PRIMARY KEY,
+		webhook_id      TEXT NOT NULL REFERENCES webhooks (id),
+		event_id        TEXT NOT NULL,
+		event           TEXT NOT NULL,
+		payload         TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		attempts        INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		last_error      TEXT NOT NULL DEFAULT '',
+		created_at      TIMESTAMP NOT NULL
+	);
+	CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);`,
+	// 11: background signing jobs with the signatures they created
+	`CREATE TABLE jobs (
+		id           TEXT PRIMARY KEY,
+		device_id    TEXT NOT NULL,
+		data         TEXT NOT NULL,
+		callback_url TEXT NOT NULL DEFAULT '',
+		status       TEXT NOT NULL,
+		error        TEXT NOT NULL DEFAULT '',
+		created_at   TIMESTAMP NOT NULL,
+		started_at   TIMESTAMP NOT NULL,
+		finished_at  TIMESTAMP NOT NULL
+	);
+	CREATE INDEX jobs_status ON jobs (status, created_at);
+	CREATE TABLE job_signatures (
+		job_id       TEXT NOT NULL REFERENCES jobs (id),
+		position     INTEGER NOT NULL,
+		signature_id TEXT NOT NULL REFERENCES signatures (id),
+		PRIMARY KEY (job_id, position)
+	);`,
+	// 12: job callbacks go through the outbox with a URL instead of a webhook, SQLite cannot
+	// drop the foreign key on webhook_id in place so the table is rebuilt
+	`CREATE TABLE webhook_deliveries_rebuilt (
+		id              TEXT PRIMARY KEY,
+		webhook_id      TEXT NOT NULL DEFAULT '',
+		url             TEXT NOT NULL DEFAULT '',
+		event_id        TEXT NOT NULL,
+		event           TEXT NOT NULL,
+		payload         TEXT NOT NULL,
+		status          TEXT NOT NULL,
+		attempts        INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		last_error      TEXT NOT NULL DEFAULT '',
+		created_at      TIMESTAMP NOT NULL
+	);
+	INSERT INTO webhook_deliveries_rebuilt (id, webhook_id, event_id, event, payload, status, attempts, next_attempt_at, last_error, created_at)
+		SELECT id, webhook_id, event_id, event, payload, status, attempts, next_attempt_at, last_error, created_at FROM webhook_deliveries;
+	DROP TABLE webhook_deliveries;
+	ALTER TABLE webhook_deliveries_rebuilt RENAME TO webhook_deliveries;
+	CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+	CREATE INDEX webhook_deliveries_webhook ON webhook_deliveries (webhook_id);`,
+}
+
+// migrate brings the schema up to the latest version, each migration runs in its own transaction.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for version := current + 1; version <= len(migrations); version++ {
+		err := withTx(db, func(tx *sql.Tx) error {
+			if _, err := tx.Exec(migrations[version-1]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d failed: %w", version, err)
+		}
+	}
+
+	return nil
+}
+
+// withTx runs fn inside a transaction which is committed if fn succeeds and rolled back otherwise.
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,13 @@
+package sql
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSQLSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SQL Persistence Suite")
+}
@@ -0,0 +1,599 @@
+// Package sql implements the persistence interfaces on top of database/sql.
+//
+// Queries use "?" placeholders and the SQLite dialect, the store is used with the
+// github.com/mattn/go-sqlite3 driver. Its UNIQUE and PRIMARY KEY violations are reported as
+// persistence.ErrAlreadyExists.
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/mattn/go-sqlite3"
+)
+
+// deviceColumns are the columns read by scanDevice.
+const deviceColumns = `id, algorithm, public_key, key_reference, signature_counter, label, key_size, curve, hash, padding, status, created_at`
+
+// signatureColumns are the columns read by scanSignature.
+const signatureColumns = `id, device_id, signature_counter, signature_value, signed_data, data, algorithm, previous_signature_id, created_at, idempotency_key`
+
+// Store implements IDeviceRepository, ISignatureRepository, IUnitOfWork, IWebhookRepository and IJobRepository on a SQL database.
+type Store struct {
+	db *sql.DB
+
+	mutex          sync.Mutex
+	devicesMutexes map[string]*sync.Mutex //For locking per device
+}
+
+// Open connects to the database behind driverName and dataSourceName and migrates its schema.
+func Open(driverName string, dataSourceName string) (*Store, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := NewStore(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// NewStore creates a Store on an existing connection pool and migrates its schema.
+func NewStore(db *sql.DB) (*Store, error) {
+	if err := migrate(db); err != nil {
+		return nil, err
+	}
+
+	return &Store{
+		db:             db,
+		devicesMutexes: make(map[string]*sync.Mutex),
+	}, nil
+}
+
+// Close closes the underlying connection pool.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Compact rebuilds the database file, so the pages of overwritten rows, e.g. device keys sealed
+// under a retired master key, do not linger on disk.
+func (s *Store) Compact() error {
+	_, err := s.db.Exec("VACUUM")
+	return err
+}
+
+func (s *Store) CreateDevice(device *domain.Device) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return insertDevice(tx, device)
+	})
+}
+
+func (s *Store) CountDevices() int {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM devices`).Scan(&count); err != nil {
+		return 0
+	}
+	return count
+}
+
+func (s *Store) GetDevice(id string) (*domain.Device, error) {
+	row := s.db.QueryRow(
+		`SELECT `+deviceColumns+` FROM devices WHERE id = ?`,
+		id,
+	)
+
+	device, err := scanDevice(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("device with id %s %w", id, persistence.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadKeyHistory(device); err != nil {
+		return nil, err
+	}
+	if err := s.loadMetadata(device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// UpdateDevice replaces a stored device except for its signature counter.
+func (s *Store) UpdateDevice(device *domain.Device) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return updateDevice(tx, device)
+	})
+}
+
+func (s *Store) IncrementSignatureCounter(deviceID string) error {
+	result, err := s.db.Exec(`UPDATE devices SET signature_counter = signature_counter + 1 WHERE id = ?`, deviceID)
+	if err != nil {
+		return err
+	}
+
+	return expectOneRow(result, fmt.Errorf("device with id %s %w", deviceID, persistence.ErrNotFound))
+}
+
+func (s *Store) GetDeviceMutex(deviceID string) *sync.Mutex {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.devicesMutexes[deviceID] == nil {
+		s.devicesMutexes[deviceID] = &sync.Mutex{}
+	}
+	return s.devicesMutexes[deviceID]
+}
+
+func (s *Store) GetAllDevices() ([]*domain.Device, error) {
+	rows, err := s.db.Query(`SELECT `+deviceColumns+` FROM devices ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]*domain.Device, 0)
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, device := range devices {
+		if err := s.loadKeyHistory(device); err != nil {
+			return nil, err
+		}
+		if err := s.loadMetadata(device); err != nil {
+			return nil, err
+		}
+	}
+	return devices, nil
+}
+
+func (s *Store) CreateSignature(signature *domain.Signature) error {
+	return insertSignature(s.db, signature)
+}
+
+// Execute runs work inside a database transaction.
+func (s *Store) Execute(work func(tx persistence.ITransaction) error) error {
+	var committed []func()
+	err := withTx(s.db, func(tx *sql.Tx) error {
+		return work(transaction{tx: tx, committed: &committed})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, fn := range committed {
+		fn()
+	}
+	return nil
+}
+
+// transaction adapts a *sql.Tx to persistence.ITransaction.
+type transaction struct {
+	tx        *sql.Tx
+	committed *[]func() // Called by Execute once tx is committed
+}
+
+func (t transaction) CreateDevice(device *domain.Device) error {
+	return insertDevice(t.tx, device)
+}
+
+func (t transaction) CreateSignature(signature *domain.Signature) error {
+	return insertSignature(t.tx, signature)
+}
+
+// IncrementSignatureCounter only bumps the counter if it still is counter, so a concurrent
+// writer, e.g. another process on the same database, makes the transaction fail.
+func (t transaction) IncrementSignatureCounter(deviceID string, counter int) error {
+	result, err := t.tx.Exec(
+		`UPDATE devices SET signature_counter = signature_counter + 1 WHERE id = ? AND signature_counter = ?`,
+		deviceID, counter,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 1 {
+		return nil
+	}
+
+	// Tell a missing device from a counter that moved on
+	var stored int
+	err = t.tx.QueryRow(`SELECT signature_counter FROM devices WHERE id = ?`, deviceID).Scan(&stored)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("device with id %s %w", deviceID, persistence.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("signature counter of device %s is %d, not %d: %w", deviceID, stored, counter, persistence.ErrCounterMismatch)
+}
+
+func (t transaction) UpdateDevice(device *domain.Device) error {
+	return updateDevice(t.tx, device)
+}
+
+func (s *Store) GetLatestSignature(deviceID string) (*domain.Signature, error) {
+	row := s.db.QueryRow(
+		`SELECT `+signatureColumns+` FROM signatures WHERE device_id = ? ORDER BY signature_counter DESC LIMIT 1`,
+		deviceID,
+	)
+
+	signature, err := scanSignature(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no signatures found for device %s", deviceID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return signature, nil
+}
+
+func (s *Store) GetAllSignatures() ([]*domain.Signature, error) {
+	return s.querySignatures(`SELECT `+signatureColumns+` FROM signatures ORDER BY device_id, signature_counter`)
+}
+
+func (s *Store) GetAllSignaturesByDeviceID(deviceID string) ([]*domain.Signature, error) {
+	return s.querySignatures(
+		`SELECT `+signatureColumns+` FROM signatures WHERE device_id = ? ORDER BY signature_counter`,
+		deviceID,
+	)
+}
+
+func (s *Store) GetSignatureByIdempotencyKey(deviceID string, key string) (*domain.Signature, error) {
+	row := s.db.QueryRow(
+		`SELECT `+signatureColumns+` FROM signatures WHERE device_id = ? AND idempotency_key = ? ORDER BY signature_counter DESC LIMIT 1`,
+		deviceID, key,
+	)
+
+	signature, err := scanSignature(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return signature, err
+}
+
+// DeleteIdempotencyKeysBefore keeps the keys, they are looked up through the
+// signatures_idempotency_key index and take no memory. Expired keys are not honored by the
+// signing service either way.
+func (s *Store) DeleteIdempotencyKeysBefore(before time.Time) error {
+	return nil
+}
+
+// ListDevices pages through the devices in the order of persistence.DeviceQuery.
+func (s *Store) ListDevices(query persistence.DeviceQuery) ([]*domain.Device, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Algorithm != "" {
+		conditions = append(conditions, `algorithm = ?`)
+		args = append(args, query.Algorithm)
+	}
+	if query.LabelPrefix != "" {
+		// length() counts characters like substr() does, a byte count cuts multibyte prefixes short
+		conditions = append(conditions, `substr(label, 1, length(?)) = ?`)
+		args = append(args, query.LabelPrefix, query.LabelPrefix)
+	}
+	if query.Status != "" {
+		conditions = append(conditions, `status = ?`)
+		args = append(args, query.Status)
+	}
+	if query.After != nil {
+		comparison := ">"
+		if query.Descending {
+			comparison = "<"
+		}
+		conditions = append(conditions, `(created_at `+comparison+` ? OR (created_at = ? AND id `+comparison+` ?))`)
+		createdAt := query.After.CreatedAt.UTC()
+		args = append(args, createdAt, createdAt, query.After.ID)
+	}
+
+	order := ` ORDER BY created_at, id`
+	if query.Descending {
+		order = ` ORDER BY created_at DESC, id DESC`
+	}
+
+	statement := `SELECT ` + deviceColumns + ` FROM devices` + where(conditions) + order
+	if query.Limit > 0 {
+		statement += ` LIMIT ?`
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]*domain.Device, 0)
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, device := range devices {
+		if err := s.loadKeyHistory(device); err != nil {
+			return nil, err
+		}
+		if err := s.loadMetadata(device); err != nil {
+			return nil, err
+		}
+	}
+	return devices, nil
+}
+
+// ListSignatures pages through the signatures of a device in the order of persistence.SignatureQuery.
+func (s *Store) ListSignatures(query persistence.SignatureQuery) ([]*domain.Signature, error) {
+	conditions := []string{`device_id = ?`}
+	args := []interface{}{query.DeviceID}
+	if query.FromCounter != nil {
+		conditions = append(conditions, `signature_counter >= ?`)
+		args = append(args, *query.FromCounter)
+	}
+	if query.ToCounter != nil {
+		conditions = append(conditions, `signature_counter <= ?`)
+		args = append(args, *query.ToCounter)
+	}
+	if !query.CreatedFrom.IsZero() {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, query.CreatedFrom.UTC())
+	}
+	if !query.CreatedTo.IsZero() {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, query.CreatedTo.UTC())
+	}
+
+	if query.AfterCounter != nil {
+		if query.Descending {
+			conditions = append(conditions, `signature_counter < ?`)
+		} else {
+			conditions = append(conditions, `signature_counter > ?`)
+		}
+		args = append(args, *query.AfterCounter)
+	}
+
+	order := ` ORDER BY signature_counter`
+	if query.Descending {
+		order = ` ORDER BY signature_counter DESC`
+	}
+
+	statement := `SELECT ` + signatureColumns + ` FROM signatures` + where(conditions) + order
+	if query.Limit > 0 {
+		statement += ` LIMIT ?`
+		args = append(args, query.Limit)
+	}
+	return s.querySignatures(statement, args...)
+}
+
+// where joins conditions into a WHERE clause, or returns an empty string if there are none.
+func where(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conditions, ` AND `)
+}
+
+func (s *Store) querySignatures(query string, args ...interface{}) ([]*domain.Signature, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	signatures := make([]*domain.Signature, 0)
+	for rows.Next() {
+		signature, err := scanSignature(rows)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, signature)
+	}
+	return signatures, rows.Err()
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+// alreadyExists wraps persistence.ErrAlreadyExists around the violation of a UNIQUE or PRIMARY KEY
+// constraint while storing record, other errors are returned as they are.
+func alreadyExists(err error, record string) error {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		return fmt.Errorf("%s %w", record, persistence.ErrAlreadyExists)
+	}
+	return err
+}
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func insertSignature(db execer, signature *domain.Signature) error {
+	_, err := db.Exec(
+		`INSERT INTO signatures (`+signatureColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		signature.ID, signature.DeviceID, signature.SignatureCounter, signature.SignatureValue, signature.SignedData,
+		signature.Data, signature.Algorithm, signature.PreviousSignatureID, signature.CreatedAt, signature.IdempotencyKey,
+	)
+	return alreadyExists(err, fmt.Sprintf("signature with id %s or counter %d of device %s", signature.ID, signature.SignatureCounter, signature.DeviceID))
+}
+
+func insertDevice(db execer, device *domain.Device) error {
+	_, err := db.Exec(
+		`INSERT INTO devices (`+deviceColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		device.ID, device.Algorithm, device.PublicKey, device.KeyReference, device.SignatureCounter, device.Label,
+		device.KeyParameters.KeySize, device.KeyParameters.Curve, device.KeyParameters.Hash, device.KeyParameters.Padding,
+		device.CurrentStatus(), device.CreatedAt.UTC(),
+	)
+	if err != nil {
+		return alreadyExists(err, "device with id "+device.ID)
+	}
+	if err := insertKeyHistory(db, device); err != nil {
+		return err
+	}
+	return insertMetadata(db, device)
+}
+
+func updateDevice(db execer, device *domain.Device) error {
+	result, err := db.Exec(
+		`UPDATE devices SET algorithm = ?, public_key = ?, key_reference = ?, label = ?, key_size = ?, curve = ?, hash = ?, padding = ?, status = ? WHERE id = ?`,
+		device.Algorithm, device.PublicKey, device.KeyReference, device.Label,
+		device.KeyParameters.KeySize, device.KeyParameters.Curve, device.KeyParameters.Hash, device.KeyParameters.Padding,
+		device.CurrentStatus(), device.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectOneRow(result, fmt.Errorf("device with id %s %w", device.ID, persistence.ErrNotFound)); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DELETE FROM device_key_history WHERE device_id = ?`, device.ID); err != nil {
+		return err
+	}
+	if err := insertKeyHistory(db, device); err != nil {
+		return err
+	}
+
+	if _, err := db.Exec(`DELETE FROM device_metadata WHERE device_id = ?`, device.ID); err != nil {
+		return err
+	}
+	return insertMetadata(db, device)
+}
+
+func insertKeyHistory(db execer, device *domain.Device) error {
+	for _, key := range device.KeyHistory {
+		_, err := db.Exec(
+			`INSERT INTO device_key_history (device_id, public_key, first_counter, last_counter, rotated_at) VALUES (?, ?, ?, ?, ?)`,
+			device.ID, key.PublicKey, key.FirstCounter, key.LastCounter, key.RotatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) loadKeyHistory(device *domain.Device) error {
+	rows, err := s.db.Query(
+		`SELECT public_key, first_counter, last_counter, rotated_at FROM device_key_history WHERE device_id = ? ORDER BY first_counter`,
+		device.ID,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key domain.RetiredKey
+		if err := rows.Scan(&key.PublicKey, &key.FirstCounter, &key.LastCounter, &key.RotatedAt); err != nil {
+			return err
+		}
+		device.KeyHistory = append(device.KeyHistory, key)
+	}
+	return rows.Err()
+}
+
+func insertMetadata(db execer, device *domain.Device) error {
+	for key, value := range device.Metadata {
+		_, err := db.Exec(
+			`INSERT INTO device_metadata (device_id, key, value) VALUES (?, ?, ?)`,
+			device.ID, key, value,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) loadMetadata(device *domain.Device) error {
+	rows, err := s.db.Query(`SELECT key, value FROM device_metadata WHERE device_id = ?`, device.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return err
+		}
+		if device.Metadata == nil {
+			device.Metadata = make(map[string]string)
+		}
+		device.Metadata[key] = value
+	}
+	return rows.Err()
+}
+
+func scanDevice(row scanner) (*domain.Device, error) {
+	var device domain.Device
+	err := row.Scan(
+		&device.ID, &device.Algorithm, &device.PublicKey, &device.KeyReference, &device.SignatureCounter, &device.Label,
+		&device.KeyParameters.KeySize, &device.KeyParameters.Curve, &device.KeyParameters.Hash, &device.KeyParameters.Padding,
+		&device.Status, &device.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &device, nil
+}
+
+func scanSignature(row scanner) (*domain.Signature, error) {
+	var signature domain.Signature
+	var createdAt sql.NullTime
+	err := row.Scan(
+		&signature.ID, &signature.DeviceID, &signature.SignatureCounter, &signature.SignatureValue, &signature.SignedData,
+		&signature.Data, &signature.Algorithm, &signature.PreviousSignatureID, &createdAt, &signature.IdempotencyKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	signature.CreatedAt = createdAt.Time
+	return &signature, nil
+}
+
+// expectOneRow returns notAffected unless exactly one row was changed by result.
+func expectOneRow(result sql.Result, notAffected error) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected != 1 {
+		return notAffected
+	}
+	return nil
+}
@@ -0,0 +1,544 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	_ "github.com/mattn/go-sqlite3"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SQL Store", func() {
+	var (
+		dataSource string
+		store      *Store
+	)
+
+	BeforeEach(func() {
+		var err error
+		dataSource = "file:" + filepath.Join(GinkgoT().TempDir(), "test.db") + "?_foreign_keys=on"
+		store, err = Open("sqlite3", dataSource)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(store.Close)
+
+		Expect(store.CreateDevice(&domain.Device{
+			ID:        "test-device",
+			Algorithm: "ECC",
+			Label:     "test-device",
+		})).To(Succeed())
+	})
+
+	Context("When migrating the schema", func() {
+		It("should not reapply migrations on an up-to-date database", func() {
+			reopened, err := Open("sqlite3", dataSource)
+			Expect(err).NotTo(HaveOccurred())
+			defer reopened.Close()
+
+			var version int
+			Expect(reopened.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version)).To(Succeed())
+			Expect(version).To(Equal(len(migrations)))
+			Expect(reopened.CountDevices()).To(Equal(1))
+		})
+	})
+
+	Context("When updating a device", func() {
+		It("should keep its status and metadata", func() {
+			device, err := store.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.Status).To(Equal(domain.DeviceStatusActive))
+
+			device.Status = domain.DeviceStatusSuspended
+			device.Metadata = map[string]string{"store": "berlin-01"}
+			Expect(store.UpdateDevice(device)).To(Succeed())
+
+			device.Metadata = map[string]string{"store": "berlin-02", "till": "3"}
+			Expect(store.UpdateDevice(device)).To(Succeed())
+
+			device, err = store.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.Status).To(Equal(domain.DeviceStatusSuspended))
+			Expect(device.Metadata).To(Equal(map[string]string{"store": "berlin-02", "till": "3"}))
+		})
+
+		It("should report an unknown device as not found", func() {
+			_, err := store.GetDevice("unknown")
+			Expect(err).To(MatchError(persistence.ErrNotFound))
+			Expect(store.UpdateDevice(&domain.Device{ID: "unknown"})).To(MatchError(persistence.ErrNotFound))
+		})
+	})
+
+	Context("When storing a record twice", func() {
+		It("should report a duplicate device as already existing", func() {
+			err := store.CreateDevice(&domain.Device{ID: "test-device", Algorithm: "ECC"})
+			Expect(err).To(MatchError(persistence.ErrAlreadyExists))
+		})
+
+		It("should report a duplicate signature ID or counter as already existing", func() {
+			Expect(store.CreateSignature(&domain.Signature{ID: "first", DeviceID: "test-device", SignatureCounter: 0})).To(Succeed())
+
+			err := store.CreateSignature(&domain.Signature{ID: "first", DeviceID: "test-device", SignatureCounter: 1})
+			Expect(err).To(MatchError(persistence.ErrAlreadyExists))
+			err = store.Execute(func(tx persistence.ITransaction) error {
+				return tx.CreateSignature(&domain.Signature{ID: "second", DeviceID: "test-device", SignatureCounter: 0})
+			})
+			Expect(err).To(MatchError(persistence.ErrAlreadyExists))
+		})
+	})
+
+	Context("When listing", func() {
+		It("should page through devices in the order they were created", func() {
+			created := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+			for _, id := range []string{"b", "a"} {
+				Expect(store.CreateDevice(&domain.Device{
+					ID:        id,
+					Algorithm: "RSA",
+					Label:     "till-" + id,
+					CreatedAt: created,
+				})).To(Succeed())
+			}
+
+			firstPage, err := store.ListDevices(persistence.DeviceQuery{Limit: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(firstPage).To(HaveLen(2))
+			Expect(firstPage[0].ID).To(Equal("test-device"))
+			Expect(firstPage[1].ID).To(Equal("a"))
+
+			after := persistence.CursorOf(firstPage[1])
+			secondPage, err := store.ListDevices(persistence.DeviceQuery{After: &after, Limit: 2})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(secondPage).To(HaveLen(1))
+			Expect(secondPage[0].ID).To(Equal("b"))
+			Expect(secondPage[0].CreatedAt.Equal(created)).To(BeTrue())
+
+			filtered, err := store.ListDevices(persistence.DeviceQuery{Algorithm: "RSA", LabelPrefix: "till-b", Descending: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filtered).To(HaveLen(1))
+			Expect(filtered[0].ID).To(Equal("b"))
+		})
+
+		It("should match label prefixes by characters rather than bytes", func() {
+			for id, label := range map[string]string{"cafe": "Café-Berlin", "cafes": "Cafés-Berlin"} {
+				Expect(store.CreateDevice(&domain.Device{ID: id, Algorithm: "ECC", Label: label})).To(Succeed())
+			}
+
+			filtered, err := store.ListDevices(persistence.DeviceQuery{LabelPrefix: "Café-"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filtered).To(HaveLen(1))
+			Expect(filtered[0].ID).To(Equal("cafe"))
+		})
+
+		It("should filter signatures by counter and time range", func() {
+			created := time.Date(2025, 10, 19, 12, 0, 0, 0, time.UTC)
+			for counter := 0; counter < 5; counter++ {
+				Expect(store.CreateSignature(&domain.Signature{
+					ID:               fmt.Sprint(counter),
+					DeviceID:         "test-device",
+					SignatureCounter: counter,
+					CreatedAt:        created.Add(time.Duration(counter) * time.Minute),
+				})).To(Succeed())
+			}
+
+			from, after := 1, 1
+			signatures, err := store.ListSignatures(persistence.SignatureQuery{
+				DeviceID:     "test-device",
+				FromCounter:  &from,
+				CreatedTo:    created.Add(4 * time.Minute),
+				AfterCounter: &after,
+				Limit:        2,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signatures).To(HaveLen(2))
+			Expect(signatures[0].SignatureCounter).To(Equal(2))
+			Expect(signatures[1].SignatureCounter).To(Equal(3))
+
+			signatures, err = store.ListSignatures(persistence.SignatureQuery{
+				DeviceID:    "test-device",
+				CreatedFrom: created.Add(3 * time.Minute),
+				Descending:  true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signatures).To(HaveLen(2))
+			Expect(signatures[0].SignatureCounter).To(Equal(4))
+		})
+	})
+
+	Context("When storing signatures", func() {
+		It("should store the signature and bump the counter together", func() {
+			Expect(store.Execute(func(tx persistence.ITransaction) error {
+				if err := tx.CreateSignature(&domain.Signature{
+					ID:               "first",
+					DeviceID:         "test-device",
+					SignatureCounter: 0,
+					SignatureValue:   "first",
+				}); err != nil {
+					return err
+				}
+				return tx.IncrementSignatureCounter("test-device", 0)
+			})).To(Succeed())
+
+			device, err := store.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.SignatureCounter).To(Equal(1))
+
+			latestSignature, err := store.GetLatestSignature("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latestSignature.SignatureValue).To(Equal("first"))
+		})
+
+		It("should roll back the signature if the counter moved on", func() {
+			Expect(store.IncrementSignatureCounter("test-device")).To(Succeed())
+
+			Expect(store.Execute(func(tx persistence.ITransaction) error {
+				if err := tx.CreateSignature(&domain.Signature{
+					ID:               "stale",
+					DeviceID:         "test-device",
+					SignatureCounter: 0,
+					SignatureValue:   "stale",
+				}); err != nil {
+					return err
+				}
+				return tx.IncrementSignatureCounter("test-device", 0)
+			})).To(MatchError(persistence.ErrCounterMismatch))
+
+			signatures, err := store.GetAllSignaturesByDeviceID("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signatures).To(BeEmpty())
+		})
+
+		It("should keep the signed payload and its context", func() {
+			createdAt := time.Date(2025, 10, 19, 13, 7, 27, 0, time.UTC)
+			Expect(store.CreateSignature(&domain.Signature{
+				ID:                  "second",
+				DeviceID:            "test-device",
+				SignatureCounter:    1,
+				SignatureValue:      "second",
+				SignedData:          "1_receipt_first",
+				Data:                "receipt",
+				Algorithm:           "ECC",
+				PreviousSignatureID: "first",
+				CreatedAt:           createdAt,
+			})).To(Succeed())
+
+			latestSignature, err := store.GetLatestSignature("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latestSignature.SignedData).To(Equal("1_receipt_first"))
+			Expect(latestSignature.Data).To(Equal("receipt"))
+			Expect(latestSignature.Algorithm).To(Equal("ECC"))
+			Expect(latestSignature.PreviousSignatureID).To(Equal("first"))
+			Expect(latestSignature.CreatedAt.Equal(createdAt)).To(BeTrue())
+		})
+
+		It("should find a signature by its idempotency key", func() {
+			Expect(store.CreateSignature(&domain.Signature{
+				ID:               "first",
+				DeviceID:         "test-device",
+				SignatureCounter: 0,
+				SignatureValue:   "first",
+				IdempotencyKey:   "receipt-1",
+			})).To(Succeed())
+
+			signature, err := store.GetSignatureByIdempotencyKey("test-device", "receipt-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signature.ID).To(Equal("first"))
+
+			signature, err = store.GetSignatureByIdempotencyKey("test-device", "receipt-2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(signature).To(BeNil())
+		})
+
+		It("should reject a second signature with the same counter", func() {
+			signature := &domain.Signature{
+				ID:               "first",
+				DeviceID:         "test-device",
+				SignatureCounter: 0,
+				SignatureValue:   "first",
+			}
+			Expect(store.CreateSignature(signature)).To(Succeed())
+
+			signature.ID = "duplicate"
+			Expect(store.CreateSignature(signature)).NotTo(Succeed())
+		})
+	})
+})
+
+var _ = Describe("SQL Webhook Outbox", func() {
+	var (
+		store *Store
+		now   = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		var err error
+		store, err = Open("sqlite3", "file:"+filepath.Join(GinkgoT().TempDir(), "test.db")+"?_foreign_keys=on")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(store.Close)
+
+		Expect(store.CreateWebhook(&domain.Webhook{
+			ID:        "webhook",
+			URL:       "http://localhost/hook",
+			Secret:    "secret",
+			DeviceID:  "test-device",
+			Events:    []string{domain.EventSignatureCreated, domain.EventDeviceCreated},
+			CreatedAt: now,
+		})).To(Succeed())
+	})
+
+	It("should store webhooks with their events", func() {
+		webhook, err := store.GetWebhook("webhook")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(webhook.URL).To(Equal("http://localhost/hook"))
+		Expect(webhook.Secret).To(Equal("secret"))
+		Expect(webhook.DeviceID).To(Equal("test-device"))
+		Expect(webhook.Events).To(ConsistOf(domain.EventSignatureCreated, domain.EventDeviceCreated))
+		Expect(webhook.CreatedAt.Equal(now)).To(BeTrue())
+
+		webhooks, err := store.GetAllWebhooks()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(webhooks).To(HaveLen(1))
+	})
+
+	It("should select due deliveries and keep the outcome of attempts", func() {
+		Expect(store.CreateDeliveries([]*domain.WebhookDelivery{
+			{ID: "due", WebhookID: "webhook", EventID: "event", Event: domain.EventSignatureCreated, Payload: `{}`, Status: domain.DeliveryStatusPending, NextAttemptAt: now, CreatedAt: now},
+			{ID: "later", WebhookID: "webhook", EventID: "event", Event: domain.EventSignatureCreated, Payload: `{}`, Status: domain.DeliveryStatusPending, NextAttemptAt: now.Add(time.Hour), CreatedAt: now},
+		})).To(Succeed())
+
+		due, err := store.ListDeliveries(persistence.DeliveryQuery{Status: domain.DeliveryStatusPending, DueBy: now.Add(time.Minute)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(due).To(HaveLen(1))
+		Expect(due[0].ID).To(Equal("due"))
+
+		due[0].Status = domain.DeliveryStatusDead
+		due[0].Attempts = 10
+		due[0].LastError = "503 Service Unavailable"
+		Expect(store.UpdateDelivery(due[0])).To(Succeed())
+
+		dead, err := store.ListDeliveries(persistence.DeliveryQuery{Status: domain.DeliveryStatusDead})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dead).To(HaveLen(1))
+		Expect(dead[0].Attempts).To(Equal(10))
+		Expect(dead[0].LastError).To(Equal("503 Service Unavailable"))
+
+		Expect(store.DeleteDelivery("later")).To(Succeed())
+		Expect(store.DeleteDelivery("later")).NotTo(Succeed())
+	})
+
+	It("should delete a webhook together with its deliveries", func() {
+		Expect(store.CreateDeliveries([]*domain.WebhookDelivery{
+			{ID: "due", WebhookID: "webhook", EventID: "event", Event: domain.EventSignatureCreated, Payload: `{}`, Status: domain.DeliveryStatusPending, NextAttemptAt: now, CreatedAt: now},
+		})).To(Succeed())
+
+		Expect(store.DeleteWebhook("webhook")).To(Succeed())
+
+		_, err := store.GetWebhook("webhook")
+		Expect(err).To(HaveOccurred())
+		deliveries, err := store.ListDeliveries(persistence.DeliveryQuery{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deliveries).To(BeEmpty())
+	})
+
+	It("should skip deliveries to a deleted webhook and store the others", func() {
+		Expect(store.CreateWebhook(&domain.Webhook{ID: "deleted", URL: "http://localhost/deleted", Secret: "secret", CreatedAt: now})).To(Succeed())
+		Expect(store.DeleteWebhook("deleted")).To(Succeed())
+
+		Expect(store.Execute(func(tx persistence.ITransaction) error {
+			return tx.CreateDeliveries([]*domain.WebhookDelivery{
+				{ID: "orphan", WebhookID: "deleted", EventID: "event", Event: domain.EventSignatureCreated, Payload: `{}`, Status: domain.DeliveryStatusPending, NextAttemptAt: now, CreatedAt: now},
+				{ID: "due", WebhookID: "webhook", EventID: "event", Event: domain.EventSignatureCreated, Payload: `{}`, Status: domain.DeliveryStatusPending, NextAttemptAt: now, CreatedAt: now},
+			})
+		})).To(Succeed())
+
+		deliveries, err := store.ListDeliveries(persistence.DeliveryQuery{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deliveries).To(HaveLen(1))
+		Expect(deliveries[0].ID).To(Equal("due"))
+	})
+})
+
+var _ = Describe("SQL Jobs", func() {
+	var (
+		store *Store
+		now   = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		var err error
+		store, err = Open("sqlite3", "file:"+filepath.Join(GinkgoT().TempDir(), "test.db")+"?_foreign_keys=on")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(store.Close)
+
+		Expect(store.CreateDevice(&domain.Device{ID: "test-device", Algorithm: "ECC"})).To(Succeed())
+		Expect(store.CreateJob(&domain.Job{
+			ID:          "job",
+			DeviceID:    "test-device",
+			Data:        []string{"first", "second"},
+			CallbackURL: "https://pos.example.com/jobs",
+			Status:      domain.JobStatusQueued,
+			CreatedAt:   now,
+		})).To(Succeed())
+	})
+
+	It("should store the outcome of a job together with its signatures", func() {
+		unfinished, err := store.ListUnfinishedJobs()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unfinished).To(HaveLen(1))
+		Expect(unfinished[0].Data).To(Equal([]string{"first", "second"}))
+		Expect(unfinished[0].StartedAt.IsZero()).To(BeTrue())
+
+		signatures := []*domain.Signature{
+			{ID: "first", DeviceID: "test-device", SignatureCounter: 0, SignatureValue: "first"},
+			{ID: "second", DeviceID: "test-device", SignatureCounter: 1, SignatureValue: "second"},
+		}
+		err = store.Execute(func(tx persistence.ITransaction) error {
+			for _, signature := range signatures {
+				if err := tx.CreateSignature(signature); err != nil {
+					return err
+				}
+			}
+			return tx.UpdateJob(&domain.Job{
+				ID:         "job",
+				Status:     domain.JobStatusSucceeded,
+				Signatures: signatures,
+				StartedAt:  now,
+				FinishedAt: now.Add(time.Second),
+			})
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		job, err := store.GetJob("job")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Status).To(Equal(domain.JobStatusSucceeded))
+		Expect(job.CallbackURL).To(Equal("https://pos.example.com/jobs"))
+		Expect(job.Signatures).To(HaveLen(2))
+		Expect(job.Signatures[1].ID).To(Equal("second"))
+		Expect(job.FinishedAt.Equal(now.Add(time.Second))).To(BeTrue())
+
+		unfinished, err = store.ListUnfinishedJobs()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unfinished).To(BeEmpty())
+
+		Expect(store.DeleteJobsFinishedBefore(now)).To(Equal(0))
+		Expect(store.DeleteJobsFinishedBefore(now.Add(time.Hour))).To(Equal(1))
+		_, err = store.GetJob("job")
+		Expect(err).To(MatchError(persistence.ErrNotFound))
+		// The signatures outlive the job
+		Expect(store.GetAllSignaturesByDeviceID("test-device")).To(HaveLen(2))
+	})
+
+	It("should keep job callbacks in the outbox without a webhook", func() {
+		Expect(store.Execute(func(tx persistence.ITransaction) error {
+			return tx.CreateDeliveries([]*domain.WebhookDelivery{
+				{ID: "callback", URL: "https://pos.example.com/jobs", EventID: "event", Event: domain.EventJobFinished, Payload: `{}`, Status: domain.DeliveryStatusPending, NextAttemptAt: now, CreatedAt: now},
+			})
+		})).To(Succeed())
+
+		delivery, err := store.GetDelivery("callback")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(delivery.IsCallback()).To(BeTrue())
+		Expect(delivery.URL).To(Equal("https://pos.example.com/jobs"))
+	})
+})
+
+var _ = Describe("SQL Unit of Work", func() {
+	var store *Store
+
+	BeforeEach(func() {
+		var err error
+		store, err = Open("sqlite3", "file:"+filepath.Join(GinkgoT().TempDir(), "test.db")+"?_foreign_keys=on")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(store.Close)
+
+		Expect(store.CreateDevice(&domain.Device{
+			ID:        "test-device",
+			Algorithm: "ECC",
+		})).To(Succeed())
+	})
+
+	It("should create devices and call AfterCommit only once the transaction is committed", func() {
+		var committed []string
+		Expect(store.Execute(func(tx persistence.ITransaction) error {
+			Expect(tx.CreateDevice(&domain.Device{ID: "new-device", Algorithm: "ECC", Metadata: map[string]string{"store": "berlin-01"}})).To(Succeed())
+			tx.AfterCommit(func() { committed = append(committed, "new-device") })
+			Expect(committed).To(BeEmpty())
+			return nil
+		})).To(Succeed())
+		Expect(committed).To(Equal([]string{"new-device"}))
+		device, err := store.GetDevice("new-device")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.Metadata).To(HaveKeyWithValue("store", "berlin-01"))
+
+		err = store.Execute(func(tx persistence.ITransaction) error {
+			Expect(tx.CreateDevice(&domain.Device{ID: "failed-device", Algorithm: "ECC"})).To(Succeed())
+			tx.AfterCommit(func() { committed = append(committed, "failed-device") })
+			return errors.New("injected failure")
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(committed).To(Equal([]string{"new-device"}))
+		_, err = store.GetDevice("failed-device")
+		Expect(err).To(MatchError(persistence.ErrNotFound))
+	})
+
+	It("should roll back the signature if the work fails between the two steps", func() {
+		injectedError := errors.New("injected failure")
+
+		err := store.Execute(func(tx persistence.ITransaction) error {
+			Expect(tx.CreateSignature(&domain.Signature{
+				ID:               "first",
+				DeviceID:         "test-device",
+				SignatureCounter: 0,
+				SignatureValue:   "first",
+			})).To(Succeed())
+			return injectedError
+		})
+		Expect(err).To(MatchError(injectedError))
+
+		device, err := store.GetDevice("test-device")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.SignatureCounter).To(Equal(0))
+
+		signatures, err := store.GetAllSignaturesByDeviceID("test-device")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signatures).To(BeEmpty())
+	})
+
+	It("should store a rotated key together with its rotation record", func() {
+		rotatedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		err := store.Execute(func(tx persistence.ITransaction) error {
+			if err := tx.CreateSignature(&domain.Signature{
+				ID:               "rotation",
+				DeviceID:         "test-device",
+				SignatureCounter: 0,
+				SignatureValue:   "rotation",
+			}); err != nil {
+				return err
+			}
+			if err := tx.UpdateDevice(&domain.Device{
+				ID:           "test-device",
+				Algorithm:    "ECC",
+				PublicKey:    "new-public-key",
+				KeyReference: "new-key",
+				KeyHistory: []domain.RetiredKey{
+					{PublicKey: "old-public-key", FirstCounter: 0, LastCounter: 0, RotatedAt: rotatedAt},
+				},
+			}); err != nil {
+				return err
+			}
+			return tx.IncrementSignatureCounter("test-device", 0)
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		device, err := store.GetDevice("test-device")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.SignatureCounter).To(Equal(1))
+		Expect(device.KeyReference).To(Equal("new-key"))
+		Expect(device.KeyHistory).To(HaveLen(1))
+		Expect(device.KeyHistory[0].PublicKey).To(Equal("old-public-key"))
+		Expect(device.KeyHistory[0].RotatedAt.Equal(rotatedAt)).To(BeTrue())
+	})
+})
@@ -0,0 +1,237 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+)
+
+// webhookColumns are the columns read by scanWebhook.
+const webhookColumns = `id, url, secret, device_id, created_at`
+
+// deliveryColumns are the columns read by scanDelivery.
+const deliveryColumns = `id, webhook_id, url, event_id, event, payload, status, attempts, next_attempt_at, last_error, created_at`
+
+func (s *Store) CreateWebhook(webhook *domain.Webhook) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(
+			`INSERT INTO webhooks (`+webhookColumns+`) VALUES (?, ?, ?, ?, ?)`,
+			webhook.ID, webhook.URL, webhook.Secret, webhook.DeviceID, webhook.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return alreadyExists(err, "webhook with id "+webhook.ID)
+		}
+
+		for _, event := range webhook.Events {
+			if _, err := tx.Exec(`INSERT INTO webhook_events (webhook_id, event) VALUES (?, ?)`, webhook.ID, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) GetWebhook(id string) (*domain.Webhook, error) {
+	row := s.db.QueryRow(`SELECT `+webhookColumns+` FROM webhooks WHERE id = ?`, id)
+
+	webhook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook with id %s %w", id, persistence.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loadWebhookEvents(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (s *Store) GetAllWebhooks() ([]*domain.Webhook, error) {
+	rows, err := s.db.Query(`SELECT ` + webhookColumns + ` FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := make([]*domain.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, webhook := range webhooks {
+		if err := s.loadWebhookEvents(webhook); err != nil {
+			return nil, err
+		}
+	}
+	return webhooks, nil
+}
+
+// DeleteWebhook removes the webhook together with its events and deliveries.
+func (s *Store) DeleteWebhook(id string) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM webhook_events WHERE webhook_id = ?`, id); err != nil {
+			return err
+		}
+
+		result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+		return expectOneRow(result, fmt.Errorf("webhook with id %s %w", id, persistence.ErrNotFound))
+	})
+}
+
+func (s *Store) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	return withTx(s.db, func(tx *sql.Tx) error {
+		return insertDeliveries(tx, deliveries)
+	})
+}
+
+func (t transaction) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	return insertDeliveries(t.tx, deliveries)
+}
+
+func (t transaction) AfterCommit(fn func()) {
+	*t.committed = append(*t.committed, fn)
+}
+
+// insertDeliveries stores deliveries of existing webhooks and job callbacks. Webhook deliveries
+// are only inserted while their webhook exists, those of a webhook deleted concurrently are
+// skipped so they leave no orphans.
+func insertDeliveries(db execer, deliveries []*domain.WebhookDelivery) error {
+	for _, delivery := range deliveries {
+		statement := `INSERT INTO webhook_deliveries (` + deliveryColumns + `) SELECT ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?`
+		args := []interface{}{
+			delivery.ID, delivery.WebhookID, delivery.URL, delivery.EventID, delivery.Event, delivery.Payload, delivery.Status,
+			delivery.Attempts, delivery.NextAttemptAt.UTC(), delivery.LastError, delivery.CreatedAt.UTC(),
+		}
+		if !delivery.IsCallback() {
+			statement += ` WHERE EXISTS (SELECT 1 FROM webhooks WHERE id = ?)`
+			args = append(args, delivery.WebhookID)
+		}
+
+		if _, err := db.Exec(statement, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetDelivery(id string) (*domain.WebhookDelivery, error) {
+	row := s.db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id)
+
+	delivery, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("delivery with id %s %w", id, persistence.ErrNotFound)
+	}
+	return delivery, err
+}
+
+// UpdateDelivery stores the outcome of a delivery attempt.
+func (s *Store) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	result, err := s.db.Exec(
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(), delivery.LastError, delivery.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result, fmt.Errorf("delivery with id %s %w", delivery.ID, persistence.ErrNotFound))
+}
+
+func (s *Store) DeleteDelivery(id string) error {
+	result, err := s.db.Exec(`DELETE FROM webhook_deliveries WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectOneRow(result, fmt.Errorf("delivery with id %s %w", id, persistence.ErrNotFound))
+}
+
+// ListDeliveries selects outbox deliveries in the order of persistence.DeliveryQuery.
+func (s *Store) ListDeliveries(query persistence.DeliveryQuery) ([]*domain.WebhookDelivery, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Status != "" {
+		conditions = append(conditions, `status = ?`)
+		args = append(args, query.Status)
+	}
+	if !query.DueBy.IsZero() {
+		conditions = append(conditions, `next_attempt_at <= ?`)
+		args = append(args, query.DueBy.UTC())
+	}
+
+	statement := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries` + where(conditions) + ` ORDER BY created_at, id`
+	if query.Limit > 0 {
+		statement += ` LIMIT ?`
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := make([]*domain.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *Store) loadWebhookEvents(webhook *domain.Webhook) error {
+	rows, err := s.db.Query(`SELECT event FROM webhook_events WHERE webhook_id = ? ORDER BY event`, webhook.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event string
+		if err := rows.Scan(&event); err != nil {
+			return err
+		}
+		webhook.Events = append(webhook.Events, event)
+	}
+	return rows.Err()
+}
+
+func scanWebhook(row scanner) (*domain.Webhook, error) {
+	var webhook domain.Webhook
+	if err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.DeviceID, &webhook.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func scanDelivery(row scanner) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.URL, &delivery.EventID, &delivery.Event, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &delivery.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,198 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+// ITransaction exposes the writes that can take part in a unit of work.
+type ITransaction interface {
+	CreateDevice(device *domain.Device) error
+	CreateSignature(signature *domain.Signature) error
+	// IncrementSignatureCounter moves the counter of a device on from counter. The unit of work
+	// fails with ErrCounterMismatch if the stored counter is no longer counter.
+	IncrementSignatureCounter(deviceID string, counter int) error
+	UpdateDevice(device *domain.Device) error
+	// UpdateJob stores the outcome of a job with the signatures it created.
+	UpdateJob(job *domain.Job) error
+	// CreateDeliveries puts deliveries into the outbox. Deliveries to webhooks that no longer
+	// exist are skipped, the others are still stored.
+	CreateDeliveries(deliveries []*domain.WebhookDelivery) error
+	// AfterCommit registers fn to be called once the writes of the unit of work are committed.
+	// It is not called if the unit of work fails.
+	AfterCommit(fn func())
+}
+
+// IUnitOfWork commits the writes issued by work together or not at all.
+// If work returns an error none of its writes are persisted.
+type IUnitOfWork interface {
+	Execute(work func(tx ITransaction) error) error
+}
+
+// stagedTransaction records writes so they can be applied once the work has succeeded.
+type stagedTransaction struct {
+	devices    []*domain.Device
+	signatures []*domain.Signature
+	updates    []*domain.Device
+	increments []counterIncrement
+	jobs       []*domain.Job
+	deliveries []*domain.WebhookDelivery
+	committed  []func()
+}
+
+// counterIncrement is a staged IncrementSignatureCounter.
+type counterIncrement struct {
+	deviceID string
+	counter  int
+}
+
+func (t *stagedTransaction) CreateDevice(device *domain.Device) error {
+	t.devices = append(t.devices, device)
+	return nil
+}
+
+func (t *stagedTransaction) CreateSignature(signature *domain.Signature) error {
+	t.signatures = append(t.signatures, signature)
+	return nil
+}
+
+func (t *stagedTransaction) IncrementSignatureCounter(deviceID string, counter int) error {
+	t.increments = append(t.increments, counterIncrement{deviceID: deviceID, counter: counter})
+	return nil
+}
+
+// checkIncrements makes sure every staged increment starts from the counter it expects, taking
+// the increments staged before it for the same device into account.
+func (t *stagedTransaction) checkIncrements(deviceRepository IDeviceRepository) error {
+	counters := make(map[string]int)
+	for _, increment := range t.increments {
+		counter, staged := counters[increment.deviceID]
+		if !staged {
+			device, err := deviceRepository.GetDevice(increment.deviceID)
+			if err != nil {
+				return err
+			}
+			counter = device.SignatureCounter
+		}
+		if counter != increment.counter {
+			return fmt.Errorf("signature counter of device %s is %d, not %d: %w", increment.deviceID, counter, increment.counter, ErrCounterMismatch)
+		}
+		counters[increment.deviceID] = counter + 1
+	}
+	return nil
+}
+
+func (t *stagedTransaction) UpdateDevice(device *domain.Device) error {
+	t.updates = append(t.updates, device)
+	return nil
+}
+
+func (t *stagedTransaction) UpdateJob(job *domain.Job) error {
+	t.jobs = append(t.jobs, job)
+	return nil
+}
+
+func (t *stagedTransaction) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	t.deliveries = append(t.deliveries, deliveries...)
+	return nil
+}
+
+func (t *stagedTransaction) AfterCommit(fn func()) {
+	t.committed = append(t.committed, fn)
+}
+
+// afterCommit calls the functions registered with AfterCommit.
+func (t *stagedTransaction) afterCommit() {
+	for _, fn := range t.committed {
+		fn()
+	}
+}
+
+// UnitOfWork is the in-memory IUnitOfWork. Writes are staged until the work has
+// succeeded and every referenced record is known to exist, the in-memory repositories
+// cannot fail after that point so the staged writes are applied all together.
+type UnitOfWork struct {
+	mutex               sync.Mutex
+	deviceRepository    IDeviceRepository
+	signatureRepository ISignatureRepository
+	jobRepository       IJobRepository     // Nil if the unit of work cannot store jobs
+	webhookRepository   IWebhookRepository // Nil if the unit of work cannot store deliveries
+}
+
+// NewUnitOfWork creates a unit of work over the given repositories. The job and webhook
+// repositories may be nil, work writing to them fails then.
+func NewUnitOfWork(deviceRepository IDeviceRepository, signatureRepository ISignatureRepository, jobRepository IJobRepository, webhookRepository IWebhookRepository) IUnitOfWork {
+	return &UnitOfWork{
+		deviceRepository:    deviceRepository,
+		signatureRepository: signatureRepository,
+		jobRepository:       jobRepository,
+		webhookRepository:   webhookRepository,
+	}
+}
+
+func (u *UnitOfWork) Execute(work func(tx ITransaction) error) error {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	tx := &stagedTransaction{}
+	if err := work(tx); err != nil {
+		return err
+	}
+
+	if err := tx.checkIncrements(u.deviceRepository); err != nil {
+		return err
+	}
+	for _, device := range tx.updates {
+		if _, err := u.deviceRepository.GetDevice(device.ID); err != nil {
+			return err
+		}
+	}
+	if len(tx.jobs) > 0 && u.jobRepository == nil {
+		return errors.New("unit of work has no job repository")
+	}
+	for _, job := range tx.jobs {
+		if _, err := u.jobRepository.GetJob(job.ID); err != nil {
+			return err
+		}
+	}
+	if len(tx.deliveries) > 0 && u.webhookRepository == nil {
+		return errors.New("unit of work has no webhook repository")
+	}
+
+	for _, device := range tx.devices {
+		if err := u.deviceRepository.CreateDevice(device); err != nil {
+			return err
+		}
+	}
+	for _, signature := range tx.signatures {
+		if err := u.signatureRepository.CreateSignature(signature); err != nil {
+			return err
+		}
+	}
+	for _, device := range tx.updates {
+		if err := u.deviceRepository.UpdateDevice(device); err != nil {
+			return err
+		}
+	}
+	for _, increment := range tx.increments {
+		if err := u.deviceRepository.IncrementSignatureCounter(increment.deviceID); err != nil {
+			return err
+		}
+	}
+	for _, job := range tx.jobs {
+		if err := u.jobRepository.UpdateJob(job); err != nil {
+			return err
+		}
+	}
+	if len(tx.deliveries) > 0 {
+		if err := u.webhookRepository.CreateDeliveries(tx.deliveries); err != nil {
+			return err
+		}
+	}
+
+	tx.afterCommit()
+	return nil
+}
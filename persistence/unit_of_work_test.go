@@ -0,0 +1,192 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unit of Work", func() {
+	var (
+		deviceRepository    IDeviceRepository
+		signatureRepository ISignatureRepository
+		unitOfWork          IUnitOfWork
+		injectedError       = errors.New("injected failure")
+		signature           = &domain.Signature{
+			ID:               "first",
+			DeviceID:         "test-device",
+			SignatureCounter: 0,
+			SignatureValue:   "first",
+		}
+	)
+
+	expectNothingCommitted := func() {
+		device, err := deviceRepository.GetDevice("test-device")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.SignatureCounter).To(Equal(0))
+
+		signatures, err := signatureRepository.GetAllSignaturesByDeviceID("test-device")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(signatures).To(BeEmpty())
+	}
+
+	itCommitsAtomically := func() {
+		It("should commit the signature and the counter bump together", func() {
+			Expect(unitOfWork.Execute(func(tx ITransaction) error {
+				Expect(tx.CreateSignature(signature)).To(Succeed())
+				return tx.IncrementSignatureCounter("test-device", 0)
+			})).To(Succeed())
+
+			device, err := deviceRepository.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.SignatureCounter).To(Equal(1))
+
+			latestSignature, err := signatureRepository.GetLatestSignature("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(latestSignature.SignatureValue).To(Equal("first"))
+		})
+
+		It("should commit nothing if the work fails between the two steps", func() {
+			err := unitOfWork.Execute(func(tx ITransaction) error {
+				Expect(tx.CreateSignature(signature)).To(Succeed())
+				return injectedError
+			})
+			Expect(err).To(MatchError(injectedError))
+
+			expectNothingCommitted()
+		})
+
+		It("should commit nothing if the counter bump cannot be applied", func() {
+			err := unitOfWork.Execute(func(tx ITransaction) error {
+				Expect(tx.CreateSignature(signature)).To(Succeed())
+				return tx.IncrementSignatureCounter("unknown-device", 0)
+			})
+			Expect(err).To(HaveOccurred())
+
+			expectNothingCommitted()
+		})
+
+		It("should commit nothing if the counter moved on", func() {
+			err := unitOfWork.Execute(func(tx ITransaction) error {
+				Expect(tx.CreateSignature(signature)).To(Succeed())
+				return tx.IncrementSignatureCounter("test-device", 1)
+			})
+			Expect(err).To(MatchError(ErrCounterMismatch))
+
+			expectNothingCommitted()
+		})
+
+		It("should let only one of two concurrent units of work move a counter on", func() {
+			var (
+				wait      sync.WaitGroup
+				succeeded atomic.Int32
+			)
+			start := make(chan struct{})
+			for i := 0; i < 10; i++ {
+				wait.Add(1)
+				go func() {
+					defer GinkgoRecover()
+					defer wait.Done()
+					err := unitOfWork.Execute(func(tx ITransaction) error {
+						<-start // The units of work are checked and committed at the same time
+						if err := tx.CreateSignature(&domain.Signature{ID: fmt.Sprintf("signature-%d", i), DeviceID: "test-device"}); err != nil {
+							return err
+						}
+						return tx.IncrementSignatureCounter("test-device", 0)
+					})
+					if err == nil {
+						succeeded.Add(1)
+					} else {
+						Expect(err).To(MatchError(ErrCounterMismatch))
+					}
+				}()
+			}
+			close(start)
+			wait.Wait()
+
+			Expect(succeeded.Load()).To(Equal(int32(1)))
+			device, err := deviceRepository.GetDevice("test-device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(device.SignatureCounter).To(Equal(1))
+		})
+
+		It("should create devices and call AfterCommit only once the work is committed", func() {
+			var committed []string
+			Expect(unitOfWork.Execute(func(tx ITransaction) error {
+				Expect(tx.CreateDevice(&domain.Device{ID: "new-device", Algorithm: "ECC"})).To(Succeed())
+				tx.AfterCommit(func() { committed = append(committed, "new-device") })
+				Expect(committed).To(BeEmpty())
+				return nil
+			})).To(Succeed())
+			Expect(committed).To(Equal([]string{"new-device"}))
+			_, err := deviceRepository.GetDevice("new-device")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = unitOfWork.Execute(func(tx ITransaction) error {
+				Expect(tx.CreateDevice(&domain.Device{ID: "failed-device", Algorithm: "ECC"})).To(Succeed())
+				tx.AfterCommit(func() { committed = append(committed, "failed-device") })
+				return injectedError
+			})
+			Expect(err).To(MatchError(injectedError))
+			Expect(committed).To(Equal([]string{"new-device"}))
+			_, err = deviceRepository.GetDevice("failed-device")
+			Expect(err).To(MatchError(ErrNotFound))
+		})
+	}
+
+	createDevice := func() {
+		Expect(deviceRepository.CreateDevice(&domain.Device{
+			ID:        "test-device",
+			Algorithm: "ECC",
+		})).To(Succeed())
+	}
+
+	Context("When working in memory", func() {
+		BeforeEach(func() {
+			deviceRepository = NewDeviceRepository()
+			signatureRepository = NewSignatureRepository()
+			unitOfWork = NewUnitOfWork(deviceRepository, signatureRepository, nil, nil)
+			createDevice()
+		})
+
+		itCommitsAtomically()
+	})
+
+	Context("When working on the file store", func() {
+		var dir string
+
+		BeforeEach(func() {
+			dir = GinkgoT().TempDir()
+			store, err := NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(store.Close)
+
+			deviceRepository = store
+			signatureRepository = store
+			unitOfWork = store
+			createDevice()
+		})
+
+		itCommitsAtomically()
+
+		It("should recover a failed unit of work as if it never happened", func() {
+			Expect(unitOfWork.Execute(func(tx ITransaction) error {
+				Expect(tx.CreateSignature(signature)).To(Succeed())
+				return injectedError
+			})).NotTo(Succeed())
+
+			reopened, err := NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(reopened.Close)
+
+			deviceRepository = reopened
+			signatureRepository = reopened
+			expectNothingCommitted()
+		})
+	})
+})
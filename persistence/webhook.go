@@ -0,0 +1,169 @@
+package persistence
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+)
+
+// IWebhookRepository keeps the registered webhooks and the outbox of their deliveries, which
+// also holds the callbacks of finished jobs.
+type IWebhookRepository interface {
+	CreateWebhook(webhook *domain.Webhook) error
+	GetWebhook(id string) (*domain.Webhook, error)
+	GetAllWebhooks() ([]*domain.Webhook, error)
+	// DeleteWebhook removes the webhook together with its deliveries.
+	DeleteWebhook(id string) error
+	// CreateDeliveries stores the deliveries. Deliveries to webhooks that no longer exist are
+	// skipped, the others are still stored.
+	CreateDeliveries(deliveries []*domain.WebhookDelivery) error
+	GetDelivery(id string) (*domain.WebhookDelivery, error)
+	UpdateDelivery(delivery *domain.WebhookDelivery) error
+	DeleteDelivery(id string) error
+	ListDeliveries(query DeliveryQuery) ([]*domain.WebhookDelivery, error)
+}
+
+type WebhookRepository struct {
+	mutex sync.RWMutex
+	webhooks map[string]*domain.Webhook
+	deliveries map[string]*domain.WebhookDelivery
+}
+
+func NewWebhookRepository() IWebhookRepository {
+	return newWebhookRepository()
+}
+
+func newWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{
+		webhooks: make(map[string]*domain.Webhook),
+		deliveries: make(map[string]*domain.WebhookDelivery),
+	}
+}
+
+// Webhooks and deliveries are stored and handed out as copies, the dispatcher updating
+// a delivery never races with a reader.
+
+func (m *WebhookRepository) CreateWebhook(webhook *domain.Webhook) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.webhooks[webhook.ID]; exists {
+		return fmt.Errorf("webhook with id %s %w", webhook.ID, ErrAlreadyExists)
+	}
+
+	stored := *webhook
+	m.webhooks[webhook.ID] = &stored
+	return nil
+}
+
+func (m *WebhookRepository) GetWebhook(id string) (*domain.Webhook, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	webhook, exists := m.webhooks[id]
+	if !exists {
+		return nil, fmt.Errorf("webhook with id %s %w", id, ErrNotFound)
+	}
+
+	found := *webhook
+	return &found, nil
+}
+
+func (m *WebhookRepository) GetAllWebhooks() ([]*domain.Webhook, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	webhooks := make([]*domain.Webhook, 0, len(m.webhooks))
+	for _, webhook := range m.webhooks {
+		found := *webhook
+		webhooks = append(webhooks, &found)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks, nil
+}
+
+func (m *WebhookRepository) DeleteWebhook(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.webhooks[id]; !exists {
+		return fmt.Errorf("webhook with id %s %w", id, ErrNotFound)
+	}
+
+	delete(m.webhooks, id)
+	for deliveryID, delivery := range m.deliveries {
+		if delivery.WebhookID == id {
+			delete(m.deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+func (m *WebhookRepository) CreateDeliveries(deliveries []*domain.WebhookDelivery) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, delivery := range deliveries {
+		// The webhook may have been deleted since the delivery was created
+		if _, exists := m.webhooks[delivery.WebhookID]; !exists && !delivery.IsCallback() {
+			continue
+		}
+		stored := *delivery
+		m.deliveries[delivery.ID] = &stored
+	}
+	return nil
+}
+
+func (m *WebhookRepository) GetDelivery(id string) (*domain.WebhookDelivery, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	delivery, exists := m.deliveries[id]
+	if !exists {
+		return nil, fmt.Errorf("delivery with id %s %w", id, ErrNotFound)
+	}
+
+	found := *delivery
+	return &found, nil
+}
+
+func (m *WebhookRepository) UpdateDelivery(delivery *domain.WebhookDelivery) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.deliveries[delivery.ID]; !exists {
+		return fmt.Errorf("delivery with id %s %w", delivery.ID, ErrNotFound)
+	}
+
+	stored := *delivery
+	m.deliveries[delivery.ID] = &stored
+	return nil
+}
+
+func (m *WebhookRepository) DeleteDelivery(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.deliveries[id]; !exists {
+		return fmt.Errorf("delivery with id %s %w", id, ErrNotFound)
+	}
+
+	delete(m.deliveries, id)
+	return nil
+}
+
+func (m *WebhookRepository) ListDeliveries(query DeliveryQuery) ([]*domain.WebhookDelivery, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	deliveries := make([]*domain.WebhookDelivery, 0, len(m.deliveries))
+	for _, delivery := range m.deliveries {
+		found := *delivery
+		deliveries = append(deliveries, &found)
+	}
+	return query.Apply(deliveries), nil
+}
@@ -0,0 +1,156 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhook Outbox", func() {
+	var now = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	delivery := func(id string, webhookID string, status domain.DeliveryStatus, nextAttemptAt time.Time) *domain.WebhookDelivery {
+		return &domain.WebhookDelivery{
+			ID:            id,
+			WebhookID:     webhookID,
+			EventID:       "event-" + id,
+			Event:         domain.EventSignatureCreated,
+			Payload:       `{}`,
+			Status:        status,
+			NextAttemptAt: nextAttemptAt,
+			CreatedAt:     now,
+		}
+	}
+
+	itKeepsTheOutbox := func(repository func() IWebhookRepository) {
+		BeforeEach(func() {
+			Expect(repository().CreateWebhook(&domain.Webhook{ID: "global", URL: "http://localhost/hook", Secret: "secret"})).To(Succeed())
+			Expect(repository().CreateWebhook(&domain.Webhook{ID: "device", URL: "http://localhost/hook", DeviceID: "test-device", Events: []string{domain.EventDeviceCreated}})).To(Succeed())
+		})
+
+		It("should select due deliveries by status", func() {
+			Expect(repository().CreateDeliveries([]*domain.WebhookDelivery{
+				delivery("due", "global", domain.DeliveryStatusPending, now),
+				delivery("later", "global", domain.DeliveryStatusPending, now.Add(time.Minute)),
+				delivery("dead", "device", domain.DeliveryStatusDead, now),
+			})).To(Succeed())
+
+			due, err := repository().ListDeliveries(DeliveryQuery{Status: domain.DeliveryStatusPending, DueBy: now})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(due).To(HaveLen(1))
+			Expect(due[0].ID).To(Equal("due"))
+
+			dead, err := repository().ListDeliveries(DeliveryQuery{Status: domain.DeliveryStatusDead})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dead).To(HaveLen(1))
+			Expect(dead[0].ID).To(Equal("dead"))
+		})
+
+		It("should record attempts and drop delivered events", func() {
+			Expect(repository().CreateDeliveries([]*domain.WebhookDelivery{
+				delivery("first", "global", domain.DeliveryStatusPending, now),
+				delivery("second", "global", domain.DeliveryStatusPending, now),
+			})).To(Succeed())
+
+			failed, err := repository().GetDelivery("first")
+			Expect(err).NotTo(HaveOccurred())
+			failed.Attempts = 1
+			failed.LastError = "connection refused"
+			failed.NextAttemptAt = now.Add(time.Second)
+			Expect(repository().UpdateDelivery(failed)).To(Succeed())
+			Expect(repository().DeleteDelivery("second")).To(Succeed())
+
+			deliveries, err := repository().ListDeliveries(DeliveryQuery{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].Attempts).To(Equal(1))
+			Expect(deliveries[0].LastError).To(Equal("connection refused"))
+			Expect(deliveries[0].NextAttemptAt.Equal(now.Add(time.Second))).To(BeTrue())
+		})
+
+		It("should delete the deliveries of a deleted webhook", func() {
+			Expect(repository().CreateDeliveries([]*domain.WebhookDelivery{
+				delivery("first", "global", domain.DeliveryStatusPending, now),
+				delivery("second", "device", domain.DeliveryStatusPending, now),
+			})).To(Succeed())
+
+			Expect(repository().DeleteWebhook("global")).To(Succeed())
+
+			_, err := repository().GetWebhook("global")
+			Expect(err).To(HaveOccurred())
+			deliveries, err := repository().ListDeliveries(DeliveryQuery{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].ID).To(Equal("second"))
+		})
+
+		It("should skip deliveries to unknown webhooks and store the others", func() {
+			Expect(repository().CreateDeliveries([]*domain.WebhookDelivery{
+				delivery("first", "unknown", domain.DeliveryStatusPending, now),
+				delivery("second", "global", domain.DeliveryStatusPending, now),
+			})).To(Succeed())
+
+			deliveries, err := repository().ListDeliveries(DeliveryQuery{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].ID).To(Equal("second"))
+		})
+	}
+
+	Context("In memory", func() {
+		var repository IWebhookRepository
+
+		BeforeEach(func() {
+			repository = NewWebhookRepository()
+		})
+
+		itKeepsTheOutbox(func() IWebhookRepository { return repository })
+	})
+
+	Context("In the file store", func() {
+		var (
+			dir   string
+			store *FileStore
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir = GinkgoT().TempDir()
+			store, err = NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+			DeferCleanup(func() { store.Close() })
+		})
+
+		itKeepsTheOutbox(func() IWebhookRepository { return store })
+
+		It("should keep webhooks and pending deliveries across restarts", func() {
+			Expect(store.CreateDeliveries([]*domain.WebhookDelivery{
+				delivery("first", "global", domain.DeliveryStatusPending, now),
+				delivery("second", "device", domain.DeliveryStatusPending, now),
+			})).To(Succeed())
+			failed, err := store.GetDelivery("first")
+			Expect(err).NotTo(HaveOccurred())
+			failed.Attempts = 3
+			failed.Status = domain.DeliveryStatusDead
+			Expect(store.UpdateDelivery(failed)).To(Succeed())
+			Expect(store.DeleteDelivery("second")).To(Succeed())
+			Expect(store.Close()).To(Succeed())
+
+			store, err = NewFileStore(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			webhook, err := store.GetWebhook("device")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(webhook.DeviceID).To(Equal("test-device"))
+			Expect(webhook.Events).To(Equal([]string{domain.EventDeviceCreated}))
+
+			deliveries, err := store.ListDeliveries(DeliveryQuery{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deliveries).To(HaveLen(1))
+			Expect(deliveries[0].Status).To(Equal(domain.DeliveryStatusDead))
+			Expect(deliveries[0].Attempts).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,4 @@
+// Package signingv1 holds the Go code generated from signing.proto.
+package signingv1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative signing/v1/signing.proto
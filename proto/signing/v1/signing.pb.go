@@ -0,0 +1,1158 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        (unknown)
+// source: signing/v1/signing.proto
+
+package signingv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Device struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Algorithm string                 `protobuf:"bytes,2,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	// PEM encoded public key.
+	PublicKey        string        `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	SignatureCounter int64         `protobuf:"varint,4,opt,name=signature_counter,json=signatureCounter,proto3" json:"signature_counter,omitempty"`
+	Label            string        `protobuf:"bytes,5,opt,name=label,proto3" json:"label,omitempty"`
+	KeySize          int32         `protobuf:"varint,6,opt,name=key_size,json=keySize,proto3" json:"key_size,omitempty"`
+	Curve            string        `protobuf:"bytes,7,opt,name=curve,proto3" json:"curve,omitempty"`
+	Hash             string        `protobuf:"bytes,8,opt,name=hash,proto3" json:"hash,omitempty"`
+	Padding          string        `protobuf:"bytes,9,opt,name=padding,proto3" json:"padding,omitempty"`
+	KeyHistory       []*RetiredKey `protobuf:"bytes,10,rep,name=key_history,json=keyHistory,proto3" json:"key_history,omitempty"`
+	// One of active, suspended or decommissioned.
+	Status        string                 `protobuf:"bytes,11,opt,name=status,proto3" json:"status,omitempty"`
+	Metadata      map[string]string      `protobuf:"bytes,12,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Device) Reset() {
+	*x = Device{}
+	mi := &file_signing_v1_signing_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Device) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Device) ProtoMessage() {}
+
+func (x *Device) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Device.ProtoReflect.Descriptor instead.
+func (*Device) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Device) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Device) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *Device) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *Device) GetSignatureCounter() int64 {
+	if x != nil {
+		return x.SignatureCounter
+	}
+	return 0
+}
+
+func (x *Device) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Device) GetKeySize() int32 {
+	if x != nil {
+		return x.KeySize
+	}
+	return 0
+}
+
+func (x *Device) GetCurve() string {
+	if x != nil {
+		return x.Curve
+	}
+	return ""
+}
+
+func (x *Device) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *Device) GetPadding() string {
+	if x != nil {
+		return x.Padding
+	}
+	return ""
+}
+
+func (x *Device) GetKeyHistory() []*RetiredKey {
+	if x != nil {
+		return x.KeyHistory
+	}
+	return nil
+}
+
+func (x *Device) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Device) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Device) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+// RetiredKey is a key the device signed the counters first_counter to last_counter with before it was rotated.
+type RetiredKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey     string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	FirstCounter  int64                  `protobuf:"varint,2,opt,name=first_counter,json=firstCounter,proto3" json:"first_counter,omitempty"`
+	LastCounter   int64                  `protobuf:"varint,3,opt,name=last_counter,json=lastCounter,proto3" json:"last_counter,omitempty"`
+	RotatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=rotated_at,json=rotatedAt,proto3" json:"rotated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RetiredKey) Reset() {
+	*x = RetiredKey{}
+	mi := &file_signing_v1_signing_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RetiredKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetiredKey) ProtoMessage() {}
+
+func (x *RetiredKey) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetiredKey.ProtoReflect.Descriptor instead.
+func (*RetiredKey) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RetiredKey) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *RetiredKey) GetFirstCounter() int64 {
+	if x != nil {
+		return x.FirstCounter
+	}
+	return 0
+}
+
+func (x *RetiredKey) GetLastCounter() int64 {
+	if x != nil {
+		return x.LastCounter
+	}
+	return 0
+}
+
+func (x *RetiredKey) GetRotatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.RotatedAt
+	}
+	return nil
+}
+
+type Signature struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Id               string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	DeviceId         string                 `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	SignatureCounter int64                  `protobuf:"varint,3,opt,name=signature_counter,json=signatureCounter,proto3" json:"signature_counter,omitempty"`
+	// Base64 encoded signature of signed_data.
+	SignatureValue string `protobuf:"bytes,4,opt,name=signature_value,json=signatureValue,proto3" json:"signature_value,omitempty"`
+	// The <counter>_<data>_<previous signature> string that was signed.
+	SignedData          string                 `protobuf:"bytes,5,opt,name=signed_data,json=signedData,proto3" json:"signed_data,omitempty"`
+	Data                string                 `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"`
+	Algorithm           string                 `protobuf:"bytes,7,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	PreviousSignatureId string                 `protobuf:"bytes,8,opt,name=previous_signature_id,json=previousSignatureId,proto3" json:"previous_signature_id,omitempty"`
+	CreatedAt           *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Signature) Reset() {
+	*x = Signature{}
+	mi := &file_signing_v1_signing_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Signature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signature) ProtoMessage() {}
+
+func (x *Signature) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signature.ProtoReflect.Descriptor instead.
+func (*Signature) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Signature) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Signature) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *Signature) GetSignatureCounter() int64 {
+	if x != nil {
+		return x.SignatureCounter
+	}
+	return 0
+}
+
+func (x *Signature) GetSignatureValue() string {
+	if x != nil {
+		return x.SignatureValue
+	}
+	return ""
+}
+
+func (x *Signature) GetSignedData() string {
+	if x != nil {
+		return x.SignedData
+	}
+	return ""
+}
+
+func (x *Signature) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *Signature) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *Signature) GetPreviousSignatureId() string {
+	if x != nil {
+		return x.PreviousSignatureId
+	}
+	return ""
+}
+
+func (x *Signature) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type CreateDeviceRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// One of RSA, ECC or Ed25519.
+	Algorithm string `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	Label     string `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	// Key parameters, empty values stand for the defaults of the algorithm.
+	KeySize  int32             `protobuf:"varint,3,opt,name=key_size,json=keySize,proto3" json:"key_size,omitempty"`
+	Curve    string            `protobuf:"bytes,4,opt,name=curve,proto3" json:"curve,omitempty"`
+	Hash     string            `protobuf:"bytes,5,opt,name=hash,proto3" json:"hash,omitempty"`
+	Padding  string            `protobuf:"bytes,6,opt,name=padding,proto3" json:"padding,omitempty"`
+	Metadata map[string]string `protobuf:"bytes,7,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Imports an existing key instead of generating one, a PEM (PKCS#1, PKCS#8 or SEC1) or a JWK.
+	PrivateKey    string `protobuf:"bytes,8,opt,name=private_key,json=privateKey,proto3" json:"private_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDeviceRequest) Reset() {
+	*x = CreateDeviceRequest{}
+	mi := &file_signing_v1_signing_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDeviceRequest) ProtoMessage() {}
+
+func (x *CreateDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDeviceRequest.ProtoReflect.Descriptor instead.
+func (*CreateDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CreateDeviceRequest) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetKeySize() int32 {
+	if x != nil {
+		return x.KeySize
+	}
+	return 0
+}
+
+func (x *CreateDeviceRequest) GetCurve() string {
+	if x != nil {
+		return x.Curve
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetPadding() string {
+	if x != nil {
+		return x.Padding
+	}
+	return ""
+}
+
+func (x *CreateDeviceRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *CreateDeviceRequest) GetPrivateKey() string {
+	if x != nil {
+		return x.PrivateKey
+	}
+	return ""
+}
+
+type GetDeviceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDeviceRequest) Reset() {
+	*x = GetDeviceRequest{}
+	mi := &file_signing_v1_signing_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDeviceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDeviceRequest) ProtoMessage() {}
+
+func (x *GetDeviceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDeviceRequest.ProtoReflect.Descriptor instead.
+func (*GetDeviceRequest) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetDeviceRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListDevicesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// At most 1000, defaults to 100.
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous page.
+	PageToken     string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	Descending    bool   `protobuf:"varint,3,opt,name=descending,proto3" json:"descending,omitempty"`
+	Algorithm     string `protobuf:"bytes,4,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	LabelPrefix   string `protobuf:"bytes,5,opt,name=label_prefix,json=labelPrefix,proto3" json:"label_prefix,omitempty"`
+	Status        string `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesRequest) Reset() {
+	*x = ListDevicesRequest{}
+	mi := &file_signing_v1_signing_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesRequest) ProtoMessage() {}
+
+func (x *ListDevicesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesRequest.ProtoReflect.Descriptor instead.
+func (*ListDevicesRequest) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListDevicesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListDevicesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListDevicesRequest) GetDescending() bool {
+	if x != nil {
+		return x.Descending
+	}
+	return false
+}
+
+func (x *ListDevicesRequest) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *ListDevicesRequest) GetLabelPrefix() string {
+	if x != nil {
+		return x.LabelPrefix
+	}
+	return ""
+}
+
+func (x *ListDevicesRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type ListDevicesResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Devices []*Device              `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	// Empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDevicesResponse) Reset() {
+	*x = ListDevicesResponse{}
+	mi := &file_signing_v1_signing_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDevicesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDevicesResponse) ProtoMessage() {}
+
+func (x *ListDevicesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDevicesResponse.ProtoReflect.Descriptor instead.
+func (*ListDevicesResponse) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListDevicesResponse) GetDevices() []*Device {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+func (x *ListDevicesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type SignTransactionRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Data     string                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	// Lets clients retry without signing twice, a retry is answered with the original signature.
+	IdempotencyKey string `protobuf:"bytes,3,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *SignTransactionRequest) Reset() {
+	*x = SignTransactionRequest{}
+	mi := &file_signing_v1_signing_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignTransactionRequest) ProtoMessage() {}
+
+func (x *SignTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignTransactionRequest.ProtoReflect.Descriptor instead.
+func (*SignTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SignTransactionRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *SignTransactionRequest) GetData() string {
+	if x != nil {
+		return x.Data
+	}
+	return ""
+}
+
+func (x *SignTransactionRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type SignTransactionResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Signature *Signature             `protobuf:"bytes,1,opt,name=signature,proto3" json:"signature,omitempty"`
+	// Set when the signature was created by an earlier request with the same idempotency key.
+	Replayed      bool `protobuf:"varint,2,opt,name=replayed,proto3" json:"replayed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignTransactionResponse) Reset() {
+	*x = SignTransactionResponse{}
+	mi := &file_signing_v1_signing_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignTransactionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignTransactionResponse) ProtoMessage() {}
+
+func (x *SignTransactionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignTransactionResponse.ProtoReflect.Descriptor instead.
+func (*SignTransactionResponse) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SignTransactionResponse) GetSignature() *Signature {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+func (x *SignTransactionResponse) GetReplayed() bool {
+	if x != nil {
+		return x.Replayed
+	}
+	return false
+}
+
+type ListSignaturesRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	// At most 1000, defaults to 100.
+	PageSize int32 `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// The next_page_token of the previous page.
+	PageToken     string                 `protobuf:"bytes,3,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	Descending    bool                   `protobuf:"varint,4,opt,name=descending,proto3" json:"descending,omitempty"`
+	FromCounter   *int64                 `protobuf:"varint,5,opt,name=from_counter,json=fromCounter,proto3,oneof" json:"from_counter,omitempty"`
+	ToCounter     *int64                 `protobuf:"varint,6,opt,name=to_counter,json=toCounter,proto3,oneof" json:"to_counter,omitempty"`
+	CreatedFrom   *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=created_from,json=createdFrom,proto3" json:"created_from,omitempty"`
+	CreatedTo     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_to,json=createdTo,proto3" json:"created_to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSignaturesRequest) Reset() {
+	*x = ListSignaturesRequest{}
+	mi := &file_signing_v1_signing_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSignaturesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSignaturesRequest) ProtoMessage() {}
+
+func (x *ListSignaturesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSignaturesRequest.ProtoReflect.Descriptor instead.
+func (*ListSignaturesRequest) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListSignaturesRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *ListSignaturesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListSignaturesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListSignaturesRequest) GetDescending() bool {
+	if x != nil {
+		return x.Descending
+	}
+	return false
+}
+
+func (x *ListSignaturesRequest) GetFromCounter() int64 {
+	if x != nil && x.FromCounter != nil {
+		return *x.FromCounter
+	}
+	return 0
+}
+
+func (x *ListSignaturesRequest) GetToCounter() int64 {
+	if x != nil && x.ToCounter != nil {
+		return *x.ToCounter
+	}
+	return 0
+}
+
+func (x *ListSignaturesRequest) GetCreatedFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedFrom
+	}
+	return nil
+}
+
+func (x *ListSignaturesRequest) GetCreatedTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedTo
+	}
+	return nil
+}
+
+type ListSignaturesResponse struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Signatures []*Signature           `protobuf:"bytes,1,rep,name=signatures,proto3" json:"signatures,omitempty"`
+	// Empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSignaturesResponse) Reset() {
+	*x = ListSignaturesResponse{}
+	mi := &file_signing_v1_signing_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSignaturesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSignaturesResponse) ProtoMessage() {}
+
+func (x *ListSignaturesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSignaturesResponse.ProtoReflect.Descriptor instead.
+func (*ListSignaturesResponse) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListSignaturesResponse) GetSignatures() []*Signature {
+	if x != nil {
+		return x.Signatures
+	}
+	return nil
+}
+
+func (x *ListSignaturesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type WatchSignaturesRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	DeviceId string                 `protobuf:"bytes,1,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	// The counter of the last signature the client has, the stored signatures after it are sent
+	// first. -1 replays the whole chain, unset starts with the next signature.
+	AfterCounter  *int64 `protobuf:"varint,2,opt,name=after_counter,json=afterCounter,proto3,oneof" json:"after_counter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchSignaturesRequest) Reset() {
+	*x = WatchSignaturesRequest{}
+	mi := &file_signing_v1_signing_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchSignaturesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchSignaturesRequest) ProtoMessage() {}
+
+func (x *WatchSignaturesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_signing_v1_signing_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchSignaturesRequest.ProtoReflect.Descriptor instead.
+func (*WatchSignaturesRequest) Descriptor() ([]byte, []int) {
+	return file_signing_v1_signing_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *WatchSignaturesRequest) GetDeviceId() string {
+	if x != nil {
+		return x.DeviceId
+	}
+	return ""
+}
+
+func (x *WatchSignaturesRequest) GetAfterCounter() int64 {
+	if x != nil && x.AfterCounter != nil {
+		return *x.AfterCounter
+	}
+	return 0
+}
+
+var File_signing_v1_signing_proto protoreflect.FileDescriptor
+
+const file_signing_v1_signing_proto_rawDesc = "" +
+	"\n" +
+	"\x18signing/v1/signing.proto\x12\n" +
+	"signing.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xfe\x03\n" +
+	"\x06Device\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1c\n" +
+	"\talgorithm\x18\x02 \x01(\tR\talgorithm\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x03 \x01(\tR\tpublicKey\x12+\n" +
+	"\x11signature_counter\x18\x04 \x01(\x03R\x10signatureCounter\x12\x14\n" +
+	"\x05label\x18\x05 \x01(\tR\x05label\x12\x19\n" +
+	"\bkey_size\x18\x06 \x01(\x05R\akeySize\x12\x14\n" +
+	"\x05curve\x18\a \x01(\tR\x05curve\x12\x12\n" +
+	"\x04hash\x18\b \x01(\tR\x04hash\x12\x18\n" +
+	"\apadding\x18\t \x01(\tR\apadding\x127\n" +
+	"\vkey_history\x18\n" +
+	" \x03(\v2\x16.signing.v1.RetiredKeyR\n" +
+	"keyHistory\x12\x16\n" +
+	"\x06status\x18\v \x01(\tR\x06status\x12<\n" +
+	"\bmetadata\x18\f \x03(\v2 .signing.v1.Device.MetadataEntryR\bmetadata\x129\n" +
+	"\n" +
+	"created_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xae\x01\n" +
+	"\n" +
+	"RetiredKey\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x01 \x01(\tR\tpublicKey\x12#\n" +
+	"\rfirst_counter\x18\x02 \x01(\x03R\ffirstCounter\x12!\n" +
+	"\flast_counter\x18\x03 \x01(\x03R\vlastCounter\x129\n" +
+	"\n" +
+	"rotated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\trotatedAt\"\xd0\x02\n" +
+	"\tSignature\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tdevice_id\x18\x02 \x01(\tR\bdeviceId\x12+\n" +
+	"\x11signature_counter\x18\x03 \x01(\x03R\x10signatureCounter\x12'\n" +
+	"\x0fsignature_value\x18\x04 \x01(\tR\x0esignatureValue\x12\x1f\n" +
+	"\vsigned_data\x18\x05 \x01(\tR\n" +
+	"signedData\x12\x12\n" +
+	"\x04data\x18\x06 \x01(\tR\x04data\x12\x1c\n" +
+	"\talgorithm\x18\a \x01(\tR\talgorithm\x122\n" +
+	"\x15previous_signature_id\x18\b \x01(\tR\x13previousSignatureId\x129\n" +
+	"\n" +
+	"created_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xd1\x02\n" +
+	"\x13CreateDeviceRequest\x12\x1c\n" +
+	"\talgorithm\x18\x01 \x01(\tR\talgorithm\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\x12\x19\n" +
+	"\bkey_size\x18\x03 \x01(\x05R\akeySize\x12\x14\n" +
+	"\x05curve\x18\x04 \x01(\tR\x05curve\x12\x12\n" +
+	"\x04hash\x18\x05 \x01(\tR\x04hash\x12\x18\n" +
+	"\apadding\x18\x06 \x01(\tR\apadding\x12I\n" +
+	"\bmetadata\x18\a \x03(\v2-.signing.v1.CreateDeviceRequest.MetadataEntryR\bmetadata\x12\x1f\n" +
+	"\vprivate_key\x18\b \x01(\tR\n" +
+	"privateKey\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\"\n" +
+	"\x10GetDeviceRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xc9\x01\n" +
+	"\x12ListDevicesRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x1e\n" +
+	"\n" +
+	"descending\x18\x03 \x01(\bR\n" +
+	"descending\x12\x1c\n" +
+	"\talgorithm\x18\x04 \x01(\tR\talgorithm\x12!\n" +
+	"\flabel_prefix\x18\x05 \x01(\tR\vlabelPrefix\x12\x16\n" +
+	"\x06status\x18\x06 \x01(\tR\x06status\"k\n" +
+	"\x13ListDevicesResponse\x12,\n" +
+	"\adevices\x18\x01 \x03(\v2\x12.signing.v1.DeviceR\adevices\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"r\n" +
+	"\x16SignTransactionRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\tR\x04data\x12'\n" +
+	"\x0fidempotency_key\x18\x03 \x01(\tR\x0eidempotencyKey\"j\n" +
+	"\x17SignTransactionResponse\x123\n" +
+	"\tsignature\x18\x01 \x01(\v2\x15.signing.v1.SignatureR\tsignature\x12\x1a\n" +
+	"\breplayed\x18\x02 \x01(\bR\breplayed\"\xf6\x02\n" +
+	"\x15ListSignaturesRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12\x1b\n" +
+	"\tpage_size\x18\x02 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x03 \x01(\tR\tpageToken\x12\x1e\n" +
+	"\n" +
+	"descending\x18\x04 \x01(\bR\n" +
+	"descending\x12&\n" +
+	"\ffrom_counter\x18\x05 \x01(\x03H\x00R\vfromCounter\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"to_counter\x18\x06 \x01(\x03H\x01R\ttoCounter\x88\x01\x01\x12=\n" +
+	"\fcreated_from\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\vcreatedFrom\x129\n" +
+	"\n" +
+	"created_to\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedToB\x0f\n" +
+	"\r_from_counterB\r\n" +
+	"\v_to_counter\"w\n" +
+	"\x16ListSignaturesResponse\x125\n" +
+	"\n" +
+	"signatures\x18\x01 \x03(\v2\x15.signing.v1.SignatureR\n" +
+	"signatures\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"q\n" +
+	"\x16WatchSignaturesRequest\x12\x1b\n" +
+	"\tdevice_id\x18\x01 \x01(\tR\bdeviceId\x12(\n" +
+	"\rafter_counter\x18\x02 \x01(\x03H\x00R\fafterCounter\x88\x01\x01B\x10\n" +
+	"\x0e_after_counter2\xca\x04\n" +
+	"\x0eSigningService\x12C\n" +
+	"\fCreateDevice\x12\x1f.signing.v1.CreateDeviceRequest\x1a\x12.signing.v1.Device\x12=\n" +
+	"\tGetDevice\x12\x1c.signing.v1.GetDeviceRequest\x1a\x12.signing.v1.Device\x12N\n" +
+	"\vListDevices\x12\x1e.signing.v1.ListDevicesRequest\x1a\x1f.signing.v1.ListDevicesResponse\x12Z\n" +
+	"\x0fSignTransaction\x12\".signing.v1.SignTransactionRequest\x1a#.signing.v1.SignTransactionResponse\x12_\n" +
+	"\x10SignTransactions\x12\".signing.v1.SignTransactionRequest\x1a#.signing.v1.SignTransactionResponse(\x010\x01\x12W\n" +
+	"\x0eListSignatures\x12!.signing.v1.ListSignaturesRequest\x1a\".signing.v1.ListSignaturesResponse\x12N\n" +
+	"\x0fWatchSignatures\x12\".signing.v1.WatchSignaturesRequest\x1a\x15.signing.v1.Signature0\x01B[ZYgithub.com/fiskaly/coding-challenges/signing-service-challenge/proto/signing/v1;signingv1b\x06proto3"
+
+var (
+	file_signing_v1_signing_proto_rawDescOnce sync.Once
+	file_signing_v1_signing_proto_rawDescData []byte
+)
+
+func file_signing_v1_signing_proto_rawDescGZIP() []byte {
+	file_signing_v1_signing_proto_rawDescOnce.Do(func() {
+		file_signing_v1_signing_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_signing_v1_signing_proto_rawDesc), len(file_signing_v1_signing_proto_rawDesc)))
+	})
+	return file_signing_v1_signing_proto_rawDescData
+}
+
+var file_signing_v1_signing_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_signing_v1_signing_proto_goTypes = []any{
+	(*Device)(nil),                  // 0: signing.v1.Device
+	(*RetiredKey)(nil),              // 1: signing.v1.RetiredKey
+	(*Signature)(nil),               // 2: signing.v1.Signature
+	(*CreateDeviceRequest)(nil),     // 3: signing.v1.CreateDeviceRequest
+	(*GetDeviceRequest)(nil),        // 4: signing.v1.GetDeviceRequest
+	(*ListDevicesRequest)(nil),      // 5: signing.v1.ListDevicesRequest
+	(*ListDevicesResponse)(nil),     // 6: signing.v1.ListDevicesResponse
+	(*SignTransactionRequest)(nil),  // 7: signing.v1.SignTransactionRequest
+	(*SignTransactionResponse)(nil), // 8: signing.v1.SignTransactionResponse
+	(*ListSignaturesRequest)(nil),   // 9: signing.v1.ListSignaturesRequest
+	(*ListSignaturesResponse)(nil),  // 10: signing.v1.ListSignaturesResponse
+	(*WatchSignaturesRequest)(nil),  // 11: signing.v1.WatchSignaturesRequest
+	nil,                             // 12: signing.v1.Device.MetadataEntry
+	nil,                             // 13: signing.v1.CreateDeviceRequest.MetadataEntry
+	(*timestamppb.Timestamp)(nil),   // 14: google.protobuf.Timestamp
+}
+var file_signing_v1_signing_proto_depIdxs = []int32{
+	1,  // 0: signing.v1.Device.key_history:type_name -> signing.v1.RetiredKey
+	12, // 1: signing.v1.Device.metadata:type_name -> signing.v1.Device.MetadataEntry
+	14, // 2: signing.v1.Device.created_at:type_name -> google.protobuf.Timestamp
+	14, // 3: signing.v1.RetiredKey.rotated_at:type_name -> google.protobuf.Timestamp
+	14, // 4: signing.v1.Signature.created_at:type_name -> google.protobuf.Timestamp
+	13, // 5: signing.v1.CreateDeviceRequest.metadata:type_name -> signing.v1.CreateDeviceRequest.MetadataEntry
+	0,  // 6: signing.v1.ListDevicesResponse.devices:type_name -> signing.v1.Device
+	2,  // 7: signing.v1.SignTransactionResponse.signature:type_name -> signing.v1.Signature
+	14, // 8: signing.v1.ListSignaturesRequest.created_from:type_name -> google.protobuf.Timestamp
+	14, // 9: signing.v1.ListSignaturesRequest.created_to:type_name -> google.protobuf.Timestamp
+	2,  // 10: signing.v1.ListSignaturesResponse.signatures:type_name -> signing.v1.Signature
+	3,  // 11: signing.v1.SigningService.CreateDevice:input_type -> signing.v1.CreateDeviceRequest
+	4,  // 12: signing.v1.SigningService.GetDevice:input_type -> signing.v1.GetDeviceRequest
+	5,  // 13: signing.v1.SigningService.ListDevices:input_type -> signing.v1.ListDevicesRequest
+	7,  // 14: signing.v1.SigningService.SignTransaction:input_type -> signing.v1.SignTransactionRequest
+	7,  // 15: signing.v1.SigningService.SignTransactions:input_type -> signing.v1.SignTransactionRequest
+	9,  // 16: signing.v1.SigningService.ListSignatures:input_type -> signing.v1.ListSignaturesRequest
+	11, // 17: signing.v1.SigningService.WatchSignatures:input_type -> signing.v1.WatchSignaturesRequest
+	0,  // 18: signing.v1.SigningService.CreateDevice:output_type -> signing.v1.Device
+	0,  // 19: signing.v1.SigningService.GetDevice:output_type -> signing.v1.Device
+	6,  // 20: signing.v1.SigningService.ListDevices:output_type -> signing.v1.ListDevicesResponse
+	8,  // 21: signing.v1.SigningService.SignTransaction:output_type -> signing.v1.SignTransactionResponse
+	8,  // 22: signing.v1.SigningService.SignTransactions:output_type -> signing.v1.SignTransactionResponse
+	10, // 23: signing.v1.SigningService.ListSignatures:output_type -> signing.v1.ListSignaturesResponse
+	2,  // 24: signing.v1.SigningService.WatchSignatures:output_type -> signing.v1.Signature
+	18, // [18:25] is the sub-list for method output_type
+	11, // [11:18] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_signing_v1_signing_proto_init() }
+func file_signing_v1_signing_proto_init() {
+	if File_signing_v1_signing_proto != nil {
+		return
+	}
+	file_signing_v1_signing_proto_msgTypes[9].OneofWrappers = []any{}
+	file_signing_v1_signing_proto_msgTypes[11].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_signing_v1_signing_proto_rawDesc), len(file_signing_v1_signing_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_signing_v1_signing_proto_goTypes,
+		DependencyIndexes: file_signing_v1_signing_proto_depIdxs,
+		MessageInfos:      file_signing_v1_signing_proto_msgTypes,
+	}.Build()
+	File_signing_v1_signing_proto = out.File
+	file_signing_v1_signing_proto_goTypes = nil
+	file_signing_v1_signing_proto_depIdxs = nil
+}
@@ -0,0 +1,367 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: signing/v1/signing.proto
+
+package signingv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SigningService_CreateDevice_FullMethodName     = "/signing.v1.SigningService/CreateDevice"
+	SigningService_GetDevice_FullMethodName        = "/signing.v1.SigningService/GetDevice"
+	SigningService_ListDevices_FullMethodName      = "/signing.v1.SigningService/ListDevices"
+	SigningService_SignTransaction_FullMethodName  = "/signing.v1.SigningService/SignTransaction"
+	SigningService_SignTransactions_FullMethodName = "/signing.v1.SigningService/SignTransactions"
+	SigningService_ListSignatures_FullMethodName   = "/signing.v1.SigningService/ListSignatures"
+	SigningService_WatchSignatures_FullMethodName  = "/signing.v1.SigningService/WatchSignatures"
+)
+
+// SigningServiceClient is the client API for SigningService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SigningService manages signature devices and signs transactions with them. It offers the
+// device and signing operations of the HTTP API to clients that talk gRPC.
+type SigningServiceClient interface {
+	// CreateDevice generates a key pair for a new device, or imports an existing private key.
+	CreateDevice(ctx context.Context, in *CreateDeviceRequest, opts ...grpc.CallOption) (*Device, error)
+	GetDevice(ctx context.Context, in *GetDeviceRequest, opts ...grpc.CallOption) (*Device, error)
+	// ListDevices returns a page of devices, ordered by creation time.
+	ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error)
+	// SignTransaction signs data with the device key and chains it to the previous signature.
+	SignTransaction(ctx context.Context, in *SignTransactionRequest, opts ...grpc.CallOption) (*SignTransactionResponse, error)
+	// SignTransactions signs every request sent on the stream and answers them in order. The first
+	// request that fails ends the stream with its error, the responses received before it are stored.
+	SignTransactions(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SignTransactionRequest, SignTransactionResponse], error)
+	// ListSignatures returns a page of the signatures of a device, ordered by signature counter.
+	ListSignatures(ctx context.Context, in *ListSignaturesRequest, opts ...grpc.CallOption) (*ListSignaturesResponse, error)
+	// WatchSignatures streams the signatures of a device in counter order while they are created.
+	WatchSignatures(ctx context.Context, in *WatchSignaturesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Signature], error)
+}
+
+type signingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSigningServiceClient(cc grpc.ClientConnInterface) SigningServiceClient {
+	return &signingServiceClient{cc}
+}
+
+func (c *signingServiceClient) CreateDevice(ctx context.Context, in *CreateDeviceRequest, opts ...grpc.CallOption) (*Device, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Device)
+	err := c.cc.Invoke(ctx, SigningService_CreateDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signingServiceClient) GetDevice(ctx context.Context, in *GetDeviceRequest, opts ...grpc.CallOption) (*Device, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Device)
+	err := c.cc.Invoke(ctx, SigningService_GetDevice_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signingServiceClient) ListDevices(ctx context.Context, in *ListDevicesRequest, opts ...grpc.CallOption) (*ListDevicesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDevicesResponse)
+	err := c.cc.Invoke(ctx, SigningService_ListDevices_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signingServiceClient) SignTransaction(ctx context.Context, in *SignTransactionRequest, opts ...grpc.CallOption) (*SignTransactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SignTransactionResponse)
+	err := c.cc.Invoke(ctx, SigningService_SignTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signingServiceClient) SignTransactions(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SignTransactionRequest, SignTransactionResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SigningService_ServiceDesc.Streams[0], SigningService_SignTransactions_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SignTransactionRequest, SignTransactionResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SigningService_SignTransactionsClient = grpc.BidiStreamingClient[SignTransactionRequest, SignTransactionResponse]
+
+func (c *signingServiceClient) ListSignatures(ctx context.Context, in *ListSignaturesRequest, opts ...grpc.CallOption) (*ListSignaturesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSignaturesResponse)
+	err := c.cc.Invoke(ctx, SigningService_ListSignatures_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *signingServiceClient) WatchSignatures(ctx context.Context, in *WatchSignaturesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Signature], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SigningService_ServiceDesc.Streams[1], SigningService_WatchSignatures_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchSignaturesRequest, Signature]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SigningService_WatchSignaturesClient = grpc.ServerStreamingClient[Signature]
+
+// SigningServiceServer is the server API for SigningService service.
+// All implementations must embed UnimplementedSigningServiceServer
+// for forward compatibility.
+//
+// SigningService manages signature devices and signs transactions with them. It offers the
+// device and signing operations of the HTTP API to clients that talk gRPC.
+type SigningServiceServer interface {
+	// CreateDevice generates a key pair for a new device, or imports an existing private key.
+	CreateDevice(context.Context, *CreateDeviceRequest) (*Device, error)
+	GetDevice(context.Context, *GetDeviceRequest) (*Device, error)
+	// ListDevices returns a page of devices, ordered by creation time.
+	ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error)
+	// SignTransaction signs data with the device key and chains it to the previous signature.
+	SignTransaction(context.Context, *SignTransactionRequest) (*SignTransactionResponse, error)
+	// SignTransactions signs every request sent on the stream and answers them in order. The first
+	// request that fails ends the stream with its error, the responses received before it are stored.
+	SignTransactions(grpc.BidiStreamingServer[SignTransactionRequest, SignTransactionResponse]) error
+	// ListSignatures returns a page of the signatures of a device, ordered by signature counter.
+	ListSignatures(context.Context, *ListSignaturesRequest) (*ListSignaturesResponse, error)
+	// WatchSignatures streams the signatures of a device in counter order while they are created.
+	WatchSignatures(*WatchSignaturesRequest, grpc.ServerStreamingServer[Signature]) error
+	mustEmbedUnimplementedSigningServiceServer()
+}
+
+// UnimplementedSigningServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSigningServiceServer struct{}
+
+func (UnimplementedSigningServiceServer) CreateDevice(context.Context, *CreateDeviceRequest) (*Device, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDevice not implemented")
+}
+func (UnimplementedSigningServiceServer) GetDevice(context.Context, *GetDeviceRequest) (*Device, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDevice not implemented")
+}
+func (UnimplementedSigningServiceServer) ListDevices(context.Context, *ListDevicesRequest) (*ListDevicesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDevices not implemented")
+}
+func (UnimplementedSigningServiceServer) SignTransaction(context.Context, *SignTransactionRequest) (*SignTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SignTransaction not implemented")
+}
+func (UnimplementedSigningServiceServer) SignTransactions(grpc.BidiStreamingServer[SignTransactionRequest, SignTransactionResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SignTransactions not implemented")
+}
+func (UnimplementedSigningServiceServer) ListSignatures(context.Context, *ListSignaturesRequest) (*ListSignaturesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSignatures not implemented")
+}
+func (UnimplementedSigningServiceServer) WatchSignatures(*WatchSignaturesRequest, grpc.ServerStreamingServer[Signature]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchSignatures not implemented")
+}
+func (UnimplementedSigningServiceServer) mustEmbedUnimplementedSigningServiceServer() {}
+func (UnimplementedSigningServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeSigningServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SigningServiceServer will
+// result in compilation errors.
+type UnsafeSigningServiceServer interface {
+	mustEmbedUnimplementedSigningServiceServer()
+}
+
+func RegisterSigningServiceServer(s grpc.ServiceRegistrar, srv SigningServiceServer) {
+	// If the following call pancis, it indicates UnimplementedSigningServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SigningService_ServiceDesc, srv)
+}
+
+func _SigningService_CreateDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SigningServiceServer).CreateDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SigningService_CreateDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SigningServiceServer).CreateDevice(ctx, req.(*CreateDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SigningService_GetDevice_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDeviceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SigningServiceServer).GetDevice(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SigningService_GetDevice_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SigningServiceServer).GetDevice(ctx, req.(*GetDeviceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SigningService_ListDevices_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDevicesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SigningServiceServer).ListDevices(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SigningService_ListDevices_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SigningServiceServer).ListDevices(ctx, req.(*ListDevicesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SigningService_SignTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SigningServiceServer).SignTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SigningService_SignTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SigningServiceServer).SignTransaction(ctx, req.(*SignTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SigningService_SignTransactions_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SigningServiceServer).SignTransactions(&grpc.GenericServerStream[SignTransactionRequest, SignTransactionResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SigningService_SignTransactionsServer = grpc.BidiStreamingServer[SignTransactionRequest, SignTransactionResponse]
+
+func _SigningService_ListSignatures_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSignaturesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SigningServiceServer).ListSignatures(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SigningService_ListSignatures_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SigningServiceServer).ListSignatures(ctx, req.(*ListSignaturesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SigningService_WatchSignatures_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchSignaturesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SigningServiceServer).WatchSignatures(m, &grpc.GenericServerStream[WatchSignaturesRequest, Signature]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SigningService_WatchSignaturesServer = grpc.ServerStreamingServer[Signature]
+
+// SigningService_ServiceDesc is the grpc.ServiceDesc for SigningService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SigningService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "signing.v1.SigningService",
+	HandlerType: (*SigningServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateDevice",
+			Handler:    _SigningService_CreateDevice_Handler,
+		},
+		{
+			MethodName: "GetDevice",
+			Handler:    _SigningService_GetDevice_Handler,
+		},
+		{
+			MethodName: "ListDevices",
+			Handler:    _SigningService_ListDevices_Handler,
+		},
+		{
+			MethodName: "SignTransaction",
+			Handler:    _SigningService_SignTransaction_Handler,
+		},
+		{
+			MethodName: "ListSignatures",
+			Handler:    _SigningService_ListSignatures_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SignTransactions",
+			Handler:       _SigningService_SignTransactions_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchSignatures",
+			Handler:       _SigningService_WatchSignatures_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "signing/v1/signing.proto",
+}
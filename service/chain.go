@@ -0,0 +1,21 @@
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+)
+
+// ChainGenesis returns the reference a device's first signature is chained to.
+func ChainGenesis(deviceID string) string {
+	return base64.StdEncoding.EncodeToString([]byte(deviceID))
+}
+
+// BuildSignedData builds the <counter>_<data>_<last_signature> string that gets signed.
+func BuildSignedData(counter int, data string, lastSignature string) string {
+	return fmt.Sprintf("%d_%s_%s", counter, data, lastSignature)
+}
+
+// RotationData is the data of the rotation record, the signature the old key puts on the new public key.
+func RotationData(publicKey string) string {
+	return "key_rotation:" + base64.StdEncoding.EncodeToString([]byte(publicKey))
+}
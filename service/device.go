@@ -0,0 +1,226 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/google/uuid"
+)
+
+// DeviceService creates signature devices and manages their lifecycle.
+type DeviceService struct {
+	DeviceRepository persistence.IDeviceRepository
+	UnitOfWork       persistence.IUnitOfWork
+	KeyProvider      crypto.KeyProvider // Holds the device private keys, nil keeps them unencrypted in the process
+
+	// OnDeviceCreated, OnDeviceActivated and OnDeviceDeactivated are called inside the unit of
+	// work storing a new device, a suspended device being activated again or an active device
+	// being suspended or decommissioned, their writes are committed together with the change.
+	// May be nil.
+	OnDeviceCreated     func(tx persistence.ITransaction, device *domain.Device) error
+	OnDeviceActivated   func(tx persistence.ITransaction, device *domain.Device) error
+	OnDeviceDeactivated func(tx persistence.ITransaction, device *domain.Device) error
+}
+
+// NewDevice describes a device to create. Empty key parameters stand for the algorithm defaults.
+type NewDevice struct {
+	Algorithm     string
+	Label         string
+	KeyParameters domain.KeyParameters
+	Metadata      map[string]string
+	PrivateKey    []byte // Imports this key instead of generating one, a PEM (PKCS#1, PKCS#8 or SEC1) or a JWK
+}
+
+// DeviceUpdate changes the fields that are set. Metadata is merged into the existing metadata
+// of the device, a nil value removes the key.
+type DeviceUpdate struct {
+	Label    *string
+	Metadata map[string]*string
+	Status   *domain.DeviceStatus
+}
+
+// CreateDevice generates a key pair for a new device, or imports the given private key, and stores the device.
+func (s *DeviceService) CreateDevice(newDevice NewDevice) (*domain.Device, error) {
+	algorithm, err := crypto.LookupAlgorithm(newDevice.Algorithm)
+	if err != nil {
+		return nil, &InvalidArgumentError{Err: err}
+	}
+
+	spec := crypto.KeySpec{
+		Algorithm: algorithm.Name(),
+	}
+
+	var keyReference string
+	if len(newDevice.PrivateKey) > 0 {
+		// Checks the key type and strength, the parameters are completed from the key
+		var privateKey []byte
+		spec.Parameters, privateKey, err = algorithm.Import(newDevice.PrivateKey, newDevice.KeyParameters)
+		if err != nil {
+			return nil, &InvalidArgumentError{Err: err}
+		}
+
+		keyReference, err = s.keyProvider().ImportKey(spec, privateKey)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		spec.Parameters, err = algorithm.ResolveParameters(newDevice.KeyParameters)
+		if err != nil {
+			return nil, &InvalidArgumentError{Err: err}
+		}
+
+		keyReference, err = s.keyProvider().CreateKey(spec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	public, err := s.keyProvider().PublicKey(spec, keyReference)
+	if err != nil {
+		return nil, discardKey(s.keyProvider(), keyReference, err)
+	}
+
+	device := &domain.Device{
+		ID:               uuid.New().String(),
+		Algorithm:        algorithm.Name(),
+		PublicKey:        string(public),
+		KeyReference:     keyReference,
+		SignatureCounter: 0,
+		Label:            newDevice.Label,
+		KeyParameters:    spec.Parameters,
+		Status:           domain.DeviceStatusActive,
+		Metadata:         newDevice.Metadata,
+		CreatedAt:        time.Now().UTC(),
+	}
+
+	err = s.UnitOfWork.Execute(func(tx persistence.ITransaction) error {
+		if err := tx.CreateDevice(device); err != nil {
+			return err
+		}
+		if s.OnDeviceCreated != nil {
+			return s.OnDeviceCreated(tx, device)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, discardKey(s.keyProvider(), keyReference, err)
+	}
+
+	return device, nil
+}
+
+func (s *DeviceService) GetDevice(id string) (*domain.Device, error) {
+	return s.DeviceRepository.GetDevice(id)
+}
+
+func (s *DeviceService) ListDevices(query persistence.DeviceQuery) ([]*domain.Device, error) {
+	return s.DeviceRepository.ListDevices(query)
+}
+
+// UpdateDevice changes the label, metadata or status of a device. Suspended devices can be
+// activated again, decommissioned devices are archived and can no longer be changed.
+func (s *DeviceService) UpdateDevice(deviceID string, update DeviceUpdate) (*domain.Device, error) {
+	if update.Status != nil {
+		if _, err := domain.ParseDeviceStatus(string(*update.Status)); err != nil {
+			return nil, &InvalidArgumentError{Err: err}
+		}
+	}
+	for key := range update.Metadata {
+		if key == "" {
+			return nil, &InvalidArgumentError{Err: errors.New("metadata keys must not be empty")}
+		}
+	}
+
+	// Serialized with signing, so a transaction in flight finishes before a device is suspended
+	deviceMutex := s.DeviceRepository.GetDeviceMutex(deviceID)
+	deviceMutex.Lock()
+	defer deviceMutex.Unlock()
+
+	device, err := s.DeviceRepository.GetDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if device.CurrentStatus() == domain.DeviceStatusDecommissioned {
+		return nil, &ConflictError{
+			Message: fmt.Sprintf("device %s is decommissioned and can no longer be changed", device.ID),
+			Err:     domain.ErrDeviceDecommissioned,
+		}
+	}
+
+	// Repositories may hand out the stored device itself, so the changes are made on a copy
+	updatedDevice := *device
+	if update.Label != nil {
+		updatedDevice.Label = *update.Label
+	}
+	if update.Metadata != nil {
+		updatedDevice.Metadata = maps.Clone(device.Metadata)
+		if updatedDevice.Metadata == nil {
+			updatedDevice.Metadata = make(map[string]string)
+		}
+		for key, value := range update.Metadata {
+			if value == nil {
+				delete(updatedDevice.Metadata, key)
+			} else {
+				updatedDevice.Metadata[key] = *value
+			}
+		}
+	}
+	if update.Status != nil {
+		updatedDevice.Status = *update.Status
+	}
+
+	err = s.UnitOfWork.Execute(func(tx persistence.ITransaction) error {
+		if err := tx.UpdateDevice(&updatedDevice); err != nil {
+			return err
+		}
+		if device.IsActive() && !updatedDevice.IsActive() && s.OnDeviceDeactivated != nil {
+			return s.OnDeviceDeactivated(tx, &updatedDevice)
+		}
+		if !device.IsActive() && updatedDevice.IsActive() && s.OnDeviceActivated != nil {
+			return s.OnDeviceActivated(tx, &updatedDevice)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.DeviceRepository.GetDevice(deviceID)
+}
+
+// keyProvider returns the configured KeyProvider, or an unencrypted in-process one.
+func (s *DeviceService) keyProvider() crypto.KeyProvider {
+	return keyProviderOrLocal(s.KeyProvider)
+}
+
+// keyProviderOrLocal returns provider, or an unencrypted in-process KeyProvider if it is nil.
+func keyProviderOrLocal(provider crypto.KeyProvider) crypto.KeyProvider {
+	if provider == nil {
+		return crypto.NewLocalKeyProvider(nil)
+	}
+	return provider
+}
+
+// discardKey deletes a key that was created for a change that failed with err. It returns err,
+// joined with the reason the key could not be deleted if that failed too.
+func discardKey(provider crypto.KeyProvider, reference string, err error) error {
+	if deleteErr := provider.DeleteKey(reference); deleteErr != nil {
+		// The reference of a local key is the key itself, it stays out of the message
+		return errors.Join(err, fmt.Errorf("the new key could not be deleted: %w", deleteErr))
+	}
+	return err
+}
+
+// keySpec describes the key of a device to its KeyProvider.
+func keySpec(device *domain.Device) crypto.KeySpec {
+	return crypto.KeySpec{
+		Algorithm:  device.Algorithm,
+		Parameters: device.KeyParameters,
+	}
+}
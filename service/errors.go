@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+)
+
+// The kinds of errors the services return, check them with errors.Is. Transports translate
+// them to their status codes, any other error is a failure of the service itself.
+var (
+	// ErrNotFound is matched by errors for a device or another record that does not exist.
+	ErrNotFound = persistence.ErrNotFound
+
+	// ErrDeviceNotActive is matched by a DeviceNotActiveError.
+	ErrDeviceNotActive = domain.ErrDeviceNotActive
+
+	// ErrConflict is matched by a ConflictError.
+	ErrConflict = errors.New("conflict")
+)
+
+// ErrIdempotencyKeyReused is returned when an idempotency key comes back with a different transaction.
+var ErrIdempotencyKeyReused error = &ConflictError{Message: "Idempotency-Key was already used for different transaction data"}
+
+// ErrWatchDisabled is returned by WatchSignatures when the service has no SignatureBroker.
+var ErrWatchDisabled = errors.New("signature streams are not enabled")
+
+// DeviceNotActiveError is returned when a device that is not active is asked to sign.
+type DeviceNotActiveError struct {
+	Device *domain.Device
+}
+
+func (e *DeviceNotActiveError) Error() string {
+	return fmt.Sprintf("device %s is %s, only active devices can sign", e.Device.ID, e.Device.CurrentStatus())
+}
+
+func (e *DeviceNotActiveError) Is(target error) bool {
+	return target == ErrDeviceNotActive
+}
+
+// ConflictError is returned for a request that contradicts the state of a record, e.g. a
+// change to a decommissioned device. Err tells the kind of conflict, if there is one.
+type ConflictError struct {
+	Message string
+	Err     error
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// InvalidArgumentError is returned for input the service rejects, e.g. an unsupported algorithm.
+type InvalidArgumentError struct {
+	Err error
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *InvalidArgumentError) Unwrap() error {
+	return e.Err
+}
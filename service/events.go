@@ -0,0 +1,134 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/google/uuid"
+)
+
+// WebhookEvent is the body posted to a webhook.
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// EventOutbox announces device and signature changes to the registered webhooks. Its methods are
+// the hooks of DeviceService and SigningService: the deliveries are written in the unit of work
+// of the change they announce, whichever transport made it.
+type EventOutbox struct {
+	WebhookRepository persistence.IWebhookRepository // Nil emits no events
+	Notify            func()                         // Called once deliveries are committed, e.g. to wake their dispatcher, may be nil
+
+	// DeviceData and SignatureData return the data of an event about a record, the way clients
+	// get to see it. Both must be set, the domain records hold key references.
+	DeviceData    func(device *domain.Device) interface{}
+	SignatureData func(signature *domain.Signature) interface{}
+}
+
+// event is an event that is about to be put into the outbox.
+type event struct {
+	Type     string
+	DeviceID string
+	Data     interface{}
+}
+
+// DeviceCreated announces a new device, it is an OnDeviceCreated hook.
+func (o *EventOutbox) DeviceCreated(tx persistence.ITransaction, device *domain.Device) error {
+	return o.deviceEvent(tx, domain.EventDeviceCreated, device)
+}
+
+// DeviceDeactivated announces a device that was suspended or decommissioned, it is an
+// OnDeviceDeactivated hook.
+func (o *EventOutbox) DeviceDeactivated(tx persistence.ITransaction, device *domain.Device) error {
+	return o.deviceEvent(tx, domain.EventDeviceDeactivated, device)
+}
+
+// SignaturesCreated announces new signatures, it is an OnSignatures hook.
+func (o *EventOutbox) SignaturesCreated(tx persistence.ITransaction, signatures []*domain.Signature) error {
+	if o.SignatureData == nil {
+		return errors.New("event outbox has no signature data")
+	}
+
+	events := make([]event, 0, len(signatures))
+	for _, signature := range signatures {
+		events = append(events, event{
+			Type:     domain.EventSignatureCreated,
+			DeviceID: signature.DeviceID,
+			Data:     o.SignatureData(signature),
+		})
+	}
+	return o.emit(tx, events...)
+}
+
+func (o *EventOutbox) deviceEvent(tx persistence.ITransaction, eventType string, device *domain.Device) error {
+	if o.DeviceData == nil {
+		return errors.New("event outbox has no device data")
+	}
+
+	return o.emit(tx, event{
+		Type:     eventType,
+		DeviceID: device.ID,
+		Data:     o.DeviceData(device),
+	})
+}
+
+// emit puts a delivery of every event into the outbox for each webhook subscribing to it.
+// The deliveries are written as part of tx, so they are stored if and only if the change the
+// events announce is.
+func (o *EventOutbox) emit(tx persistence.ITransaction, events ...event) error {
+	if o.WebhookRepository == nil {
+		return nil
+	}
+
+	webhooks, err := o.WebhookRepository.GetAllWebhooks()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var deliveries []*domain.WebhookDelivery
+	for _, event := range events {
+		var payload []byte
+		eventID := uuid.New().String()
+		for _, webhook := range webhooks {
+			if !webhook.Subscribes(event.Type, event.DeviceID) {
+				continue
+			}
+			if payload == nil {
+				payload, err = json.Marshal(WebhookEvent{ID: eventID, Type: event.Type, CreatedAt: now, Data: event.Data})
+				if err != nil {
+					return fmt.Errorf("%s: %w", event.Type, err)
+				}
+			}
+			deliveries = append(deliveries, &domain.WebhookDelivery{
+				ID:            uuid.New().String(),
+				WebhookID:     webhook.ID,
+				EventID:       eventID,
+				Event:         event.Type,
+				Payload:       string(payload),
+				Status:        domain.DeliveryStatusPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// A webhook deleted since it was listed is skipped, the other webhooks still get their delivery
+	if err := tx.CreateDeliveries(deliveries); err != nil {
+		return err
+	}
+	if o.Notify != nil {
+		tx.AfterCommit(o.Notify)
+	}
+	return nil
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestServiceSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Service Suite")
+}
+
+// newTestServices returns a DeviceService and a SigningService on shared in-memory repositories.
+func newTestServices() (*DeviceService, *SigningService) {
+	deviceRepository := persistence.NewDeviceRepository()
+	signatureRepository := persistence.NewSignatureRepository()
+	unitOfWork := persistence.NewUnitOfWork(deviceRepository, signatureRepository, nil, nil)
+	devices := &DeviceService{
+		DeviceRepository: deviceRepository,
+		UnitOfWork:       unitOfWork,
+	}
+	signing := &SigningService{
+		DeviceRepository:    deviceRepository,
+		SignatureRepository: signatureRepository,
+		UnitOfWork:          unitOfWork,
+		Broker:              NewSignatureBroker(DefaultStreamBufferSize),
+	}
+	return devices, signing
+}
+
+var _ = Describe("DeviceService", func() {
+	var devices *DeviceService
+
+	BeforeEach(func() {
+		devices, _ = newTestServices()
+	})
+
+	It("should reject an unsupported algorithm as an invalid argument", func() {
+		_, err := devices.CreateDevice(NewDevice{Algorithm: "DSA"})
+
+		var invalid *InvalidArgumentError
+		Expect(errors.As(err, &invalid)).To(BeTrue())
+	})
+
+	It("should report an unknown device as not found", func() {
+		_, err := devices.GetDevice("unknown")
+		Expect(err).To(MatchError(ErrNotFound))
+
+		_, err = devices.UpdateDevice("unknown", DeviceUpdate{})
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should merge metadata and call the hooks when a device is deactivated and activated", func() {
+		var deactivated, activated []*domain.Device
+		devices.OnDeviceDeactivated = func(tx persistence.ITransaction, device *domain.Device) error {
+			deactivated = append(deactivated, device)
+			return nil
+		}
+		devices.OnDeviceActivated = func(tx persistence.ITransaction, device *domain.Device) error {
+			activated = append(activated, device)
+			return nil
+		}
+
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519", Metadata: map[string]string{"store": "berlin-01", "till": "3"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		label := "till-3"
+		store := "berlin-02"
+		suspended := domain.DeviceStatusSuspended
+		updated, err := devices.UpdateDevice(device.ID, DeviceUpdate{
+			Label:    &label,
+			Metadata: map[string]*string{"store": &store, "till": nil},
+			Status:   &suspended,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Label).To(Equal("till-3"))
+		Expect(updated.Metadata).To(Equal(map[string]string{"store": "berlin-02"}))
+		Expect(updated.Status).To(Equal(domain.DeviceStatusSuspended))
+		Expect(device.Metadata).To(HaveKey("till"))
+		Expect(deactivated).To(HaveLen(1))
+
+		_, err = devices.UpdateDevice(device.ID, DeviceUpdate{Label: &label})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deactivated).To(HaveLen(1))
+		Expect(activated).To(BeEmpty())
+
+		active := domain.DeviceStatusActive
+		_, err = devices.UpdateDevice(device.ID, DeviceUpdate{Status: &active})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(activated).To(HaveLen(1))
+		Expect(activated[0].Status).To(Equal(domain.DeviceStatusActive))
+	})
+
+	It("should reject an unknown status and empty metadata keys as invalid arguments", func() {
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+
+		var invalid *InvalidArgumentError
+		unknown := domain.DeviceStatus("retired")
+		_, err = devices.UpdateDevice(device.ID, DeviceUpdate{Status: &unknown})
+		Expect(errors.As(err, &invalid)).To(BeTrue())
+
+		value := "value"
+		_, err = devices.UpdateDevice(device.ID, DeviceUpdate{Metadata: map[string]*string{"": &value}})
+		Expect(errors.As(err, &invalid)).To(BeTrue())
+	})
+
+	It("should refuse to change a decommissioned device", func() {
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+		decommissioned := domain.DeviceStatusDecommissioned
+		_, err = devices.UpdateDevice(device.ID, DeviceUpdate{Status: &decommissioned})
+		Expect(err).NotTo(HaveOccurred())
+
+		active := domain.DeviceStatusActive
+		_, err = devices.UpdateDevice(device.ID, DeviceUpdate{Status: &active})
+		Expect(err).To(MatchError(ErrConflict))
+	})
+})
+
+// failingUnitOfWork fails every unit of work without running it.
+type failingUnitOfWork struct {
+	err error
+}
+
+func (u failingUnitOfWork) Execute(work func(tx persistence.ITransaction) error) error {
+	return u.err
+}
+
+var _ = Describe("SigningService", func() {
+	var (
+		devices *DeviceService
+		signing *SigningService
+	)
+
+	BeforeEach(func() {
+		devices, signing = newTestServices()
+	})
+
+	It("should chain every signature to its predecessor", func() {
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+
+		first, replayed, err := signing.Sign(device.ID, "first", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayed).To(BeFalse())
+		Expect(first.SignedData).To(Equal(BuildSignedData(0, "first", ChainGenesis(device.ID))))
+
+		second, _, err := signing.Sign(device.ID, "second", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.SignedData).To(Equal(BuildSignedData(1, "second", first.SignatureValue)))
+		Expect(second.PreviousSignatureID).To(Equal(first.ID))
+
+		device, err = devices.GetDevice(device.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.SignatureCounter).To(Equal(2))
+	})
+
+	It("should call the hooks once devices and signatures are stored", func() {
+		var created []*domain.Device
+		var signed []*domain.Signature
+		devices.OnDeviceCreated = func(tx persistence.ITransaction, device *domain.Device) error {
+			created = append(created, device)
+			return nil
+		}
+		signing.OnSignatures = func(tx persistence.ITransaction, signatures []*domain.Signature) error {
+			signed = append(signed, signatures...)
+			return nil
+		}
+
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "ECC"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = signing.SignSequence(device.ID, []string{"a", "b"})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(created).To(ConsistOf(device))
+		Expect(signed).To(HaveLen(2))
+		Expect(signed[1].SignatureCounter).To(Equal(1))
+	})
+
+	It("should store neither the device nor the signatures if a hook fails", func() {
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+
+		hookErr := errors.New("outbox unavailable")
+		devices.OnDeviceCreated = func(tx persistence.ITransaction, device *domain.Device) error {
+			return hookErr
+		}
+		signing.OnSignatures = func(tx persistence.ITransaction, signatures []*domain.Signature) error {
+			return hookErr
+		}
+
+		_, err = devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).To(MatchError(hookErr))
+		_, _, err = signing.Sign(device.ID, "receipt", "")
+		Expect(err).To(MatchError(hookErr))
+
+		stored, err := devices.ListDevices(persistence.DeviceQuery{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored).To(HaveLen(1))
+		device, err = devices.GetDevice(device.ID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(device.SignatureCounter).To(BeZero())
+	})
+
+	It("should refuse to sign with an unknown device", func() {
+		_, _, err := signing.Sign("unknown", "data", "")
+		Expect(err).To(MatchError(ErrNotFound))
+	})
+
+	It("should refuse to sign with a suspended device", func() {
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+		suspended := *device
+		suspended.Status = domain.DeviceStatusSuspended
+		Expect(signing.DeviceRepository.UpdateDevice(&suspended)).To(Succeed())
+
+		_, _, err = signing.Sign(device.ID, "data", "")
+
+		Expect(err).To(MatchError(ErrDeviceNotActive))
+		var notActive *DeviceNotActiveError
+		Expect(errors.As(err, &notActive)).To(BeTrue())
+		Expect(notActive.Device.ID).To(Equal(device.ID))
+	})
+
+	It("should replay a signature for a retried idempotency key", func() {
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+
+		original, _, err := signing.Sign(device.ID, "receipt", "key-1")
+		Expect(err).NotTo(HaveOccurred())
+
+		retried, replayed, err := signing.Sign(device.ID, "receipt", "key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(replayed).To(BeTrue())
+		Expect(retried.ID).To(Equal(original.ID))
+
+		_, _, err = signing.Sign(device.ID, "other receipt", "key-1")
+		Expect(err).To(MatchError(ErrIdempotencyKeyReused))
+		Expect(err).To(MatchError(ErrConflict))
+	})
+
+	It("should delete the new key if the rotation cannot be committed", func() {
+		keyDir := GinkgoT().TempDir()
+		keyProvider, err := crypto.NewFileKeyProvider(keyDir, crypto.NewLocalKeyProvider(nil))
+		Expect(err).NotTo(HaveOccurred())
+		devices.KeyProvider = keyProvider
+		signing.KeyProvider = keyProvider
+
+		device, err := devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+		Expect(err).NotTo(HaveOccurred())
+
+		commitError := errors.New("commit failed")
+		signing.UnitOfWork = failingUnitOfWork{err: commitError}
+
+		_, _, err = signing.RotateKey(device.ID)
+		Expect(err).To(MatchError(commitError))
+
+		keys, err := os.ReadDir(keyDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keys).To(HaveLen(1))
+		Expect(keys[0].Name()).To(Equal(device.KeyReference + ".pem"))
+	})
+
+	Describe("WatchSignatures", func() {
+		var device *domain.Device
+
+		watch := func(ctx context.Context, afterCounter int) <-chan *domain.Signature {
+			signatures := make(chan *domain.Signature, 100)
+			go func() {
+				defer GinkgoRecover()
+				signing.WatchSignatures(ctx, device.ID, afterCounter, func(signature *domain.Signature) error {
+					signatures <- signature
+					return nil
+				})
+			}()
+			return signatures
+		}
+
+		BeforeEach(func() {
+			var err error
+			device, err = devices.CreateDevice(NewDevice{Algorithm: "Ed25519"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should replay the stored signatures and then follow new ones", func() {
+			_, err := signing.SignSequence(device.ID, []string{"a", "b"})
+			Expect(err).NotTo(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			signatures := watch(ctx, 0)
+
+			var received *domain.Signature
+			Eventually(signatures).Should(Receive(&received))
+			Expect(received.Data).To(Equal("b"))
+
+			_, _, err = signing.Sign(device.ID, "c", "")
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(signatures).Should(Receive(&received))
+			Expect(received.SignatureCounter).To(Equal(2))
+			Consistently(signatures).ShouldNot(Receive())
+		})
+
+		It("should catch up from storage after falling behind", func() {
+			signing.Broker = NewSignatureBroker(1)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			signatures := watch(ctx, -1)
+
+			_, err := signing.SignSequence(device.ID, []string{"a", "b", "c", "d"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for counter := 0; counter < 4; counter++ {
+				var received *domain.Signature
+				Eventually(signatures).Should(Receive(&received))
+				Expect(received.SignatureCounter).To(Equal(counter))
+			}
+			Consistently(signatures).ShouldNot(Receive())
+		})
+
+		It("should return once the context is done", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() {
+				done <- signing.WatchSignatures(ctx, device.ID, -1, func(*domain.Signature) error { return nil })
+			}()
+
+			cancel()
+			Eventually(done).Should(Receive(MatchError(context.Canceled)))
+		})
+
+		It("should fail without a broker", func() {
+			signing.Broker = nil
+
+			err := signing.WatchSignatures(context.Background(), device.ID, -1, func(*domain.Signature) error { return nil })
+			Expect(err).To(MatchError(ErrWatchDisabled))
+		})
+	})
+
+	It("should drop subscribers that fall behind instead of blocking", func() {
+		broker := NewSignatureBroker(1)
+		subscription := broker.subscribe("device")
+
+		broker.Publish(&domain.Signature{DeviceID: "device", SignatureCounter: 0}, &domain.Signature{DeviceID: "device", SignatureCounter: 1})
+
+		Expect(subscription.signatures).To(Receive())
+		Expect(subscription.signatures).To(BeClosed())
+		broker.unsubscribe(subscription)
+	})
+})
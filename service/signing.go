@@ -0,0 +1,432 @@
+// Package service holds the device and signing operations independent of the transport that
+// exposes them, the HTTP API in package api and the gRPC API in package grpcapi both call into it.
+package service
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/crypto"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+	"github.com/google/uuid"
+)
+
+const (
+	// DefaultIdempotencyRetention is how long an idempotency key is honored unless configured otherwise.
+	DefaultIdempotencyRetention = 24 * time.Hour
+
+	// MaxIdempotencyKeyLength is the longest idempotency key a client may send.
+	MaxIdempotencyKeyLength = 255
+)
+
+// SigningService signs transactions with the devices of a DeviceService. Every signature is chained
+// to the previous signature of its device, signing is serialized per device through its mutex.
+type SigningService struct {
+	DeviceRepository     persistence.IDeviceRepository
+	SignatureRepository  persistence.ISignatureRepository
+	UnitOfWork           persistence.IUnitOfWork
+	KeyProvider          crypto.KeyProvider // Holds the device private keys, nil keeps them unencrypted in the process
+	IdempotencyRetention time.Duration      // Zero means DefaultIdempotencyRetention
+	Broker               *SignatureBroker   // Feeds WatchSignatures, nil disables it
+	JobRepository        persistence.IJobRepository
+
+	// OnSignatures is called inside the unit of work storing signatures, in counter order, its
+	// writes are committed together with them. May be nil.
+	OnSignatures func(tx persistence.ITransaction, signatures []*domain.Signature) error
+	// OnJobFinished is called inside the unit of work storing the outcome of a job, its writes
+	// are committed together with that outcome. May be nil.
+	OnJobFinished func(tx persistence.ITransaction, job *domain.Job) error
+	// OnKeyRotated is called inside the unit of work giving a device its new key, with the
+	// device as it is stored. May be nil.
+	OnKeyRotated func(tx persistence.ITransaction, device *domain.Device) error
+}
+
+// ListSignatures lists the signatures of the device query.DeviceID, an unknown device is not found.
+func (s *SigningService) ListSignatures(query persistence.SignatureQuery) ([]*domain.Signature, error) {
+	if _, err := s.DeviceRepository.GetDevice(query.DeviceID); err != nil {
+		return nil, err
+	}
+	return s.SignatureRepository.ListSignatures(query)
+}
+
+// Sign signs data with the key of the device as the next link of its chain. With an idempotency
+// key, a retry within the retention window is answered with the original signature and replayed
+// set, even if the device was suspended since. The key cannot be reused for different data.
+func (s *SigningService) Sign(deviceID string, data string, idempotencyKey string) (signature *domain.Signature, replayed bool, err error) {
+	if len(idempotencyKey) > MaxIdempotencyKeyLength {
+		return nil, false, &InvalidArgumentError{
+			Err: fmt.Errorf("idempotency key must not be longer than %d characters", MaxIdempotencyKeyLength),
+		}
+	}
+
+	// For locking per device to avoid race conditions when incrementing the signature counter
+	deviceMutex := s.DeviceRepository.GetDeviceMutex(deviceID)
+	deviceMutex.Lock()
+	defer deviceMutex.Unlock()
+
+	// Load the device while holding its lock, repositories may return copies with a stale counter
+	device, err := s.DeviceRepository.GetDevice(deviceID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	originalSignature, err := s.idempotentSignature(device.ID, idempotencyKey, data)
+	if err != nil {
+		return nil, false, err
+	}
+	if originalSignature != nil {
+		return originalSignature, true, nil
+	}
+
+	if !device.IsActive() {
+		return nil, false, &DeviceNotActiveError{Device: device}
+	}
+
+	signature, err = s.signData(device, data)
+	if err != nil {
+		return nil, false, err
+	}
+	signature.IdempotencyKey = idempotencyKey
+
+	// Save the signature and bump the counter together, so the chain and the counter never disagree
+	err = s.UnitOfWork.Execute(func(tx persistence.ITransaction) error {
+		if err := tx.CreateSignature(signature); err != nil {
+			return err
+		}
+		if err := tx.IncrementSignatureCounter(device.ID, signature.SignatureCounter); err != nil {
+			return err
+		}
+		return s.signaturesCreated(tx, signature)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	s.publish(signature)
+
+	return signature, false, nil
+}
+
+// SignSequence signs data in order while holding the device lock, each item chained to its
+// predecessor, and stores the signatures together. If any item fails, none is stored.
+func (s *SigningService) SignSequence(deviceID string, data []string) ([]*domain.Signature, error) {
+	return s.signSequence(deviceID, data, nil)
+}
+
+// RunJob signs the data of a job like SignSequence. The outcome of the job is stored in the unit
+// of work of its signatures, so a job that was interrupted, e.g. by a restart, can safely be run
+// again: either its signatures and its outcome were stored or neither was. It returns the job
+// as stored, an error means the outcome could not be stored.
+func (s *SigningService) RunJob(jobID string) (*domain.Job, error) {
+	job, err := s.JobRepository.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.IsFinished() {
+		return job, nil
+	}
+
+	job.Status = domain.JobStatusRunning
+	job.StartedAt = time.Now().UTC()
+	if err := s.JobRepository.UpdateJob(job); err != nil {
+		return nil, err
+	}
+
+	succeeded := *job
+	_, err = s.signSequence(job.DeviceID, job.Data, func(tx persistence.ITransaction, signatures []*domain.Signature) error {
+		succeeded.Status = domain.JobStatusSucceeded
+		succeeded.Signatures = signatures
+		succeeded.FinishedAt = time.Now().UTC()
+		return s.finishJob(tx, &succeeded)
+	})
+	if err == nil {
+		return &succeeded, nil
+	}
+
+	// Like a batch a job is all or nothing, none of its items was signed
+	failed := *job
+	failed.Status = domain.JobStatusFailed
+	failed.Error = err.Error()
+	failed.FinishedAt = time.Now().UTC()
+	err = s.UnitOfWork.Execute(func(tx persistence.ITransaction) error {
+		return s.finishJob(tx, &failed)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &failed, nil
+}
+
+// finishJob stores the outcome of a job as part of tx.
+func (s *SigningService) finishJob(tx persistence.ITransaction, job *domain.Job) error {
+	if err := tx.UpdateJob(job); err != nil {
+		return err
+	}
+	if s.OnJobFinished != nil {
+		return s.OnJobFinished(tx, job)
+	}
+	return nil
+}
+
+// signSequence implements SignSequence, commit adds its writes to the unit of work storing the
+// signatures, it may be nil.
+func (s *SigningService) signSequence(deviceID string, data []string, commit func(tx persistence.ITransaction, signatures []*domain.Signature) error) ([]*domain.Signature, error) {
+	deviceMutex := s.DeviceRepository.GetDeviceMutex(deviceID)
+	deviceMutex.Lock()
+	defer deviceMutex.Unlock()
+
+	device, err := s.DeviceRepository.GetDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !device.IsActive() {
+		return nil, &DeviceNotActiveError{Device: device}
+	}
+
+	var previous *domain.Signature
+	if device.SignatureCounter > 0 {
+		previous, err = s.SignatureRepository.GetLatestSignature(device.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Chain the items in memory, nothing is stored until every item is signed
+	signatures := make([]*domain.Signature, 0, len(data))
+	for index, item := range data {
+		signature, err := s.signNext(device, item, previous)
+		if err != nil {
+			return nil, fmt.Errorf("data[%d]: %w, no signature of the batch was stored", index, err)
+		}
+		signatures = append(signatures, signature)
+		previous = signature
+	}
+
+	err = s.UnitOfWork.Execute(func(tx persistence.ITransaction) error {
+		for _, signature := range signatures {
+			if err := tx.CreateSignature(signature); err != nil {
+				return err
+			}
+			if err := tx.IncrementSignatureCounter(device.ID, signature.SignatureCounter); err != nil {
+				return err
+			}
+		}
+		if err := s.signaturesCreated(tx, signatures...); err != nil {
+			return err
+		}
+		if commit != nil {
+			return commit(tx, signatures)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publish(signatures...)
+
+	return signatures, nil
+}
+
+// RotateKey gives a device a new key pair. The old key signs a rotation record binding the new
+// public key as the next link of the chain, and is kept in the key history of the device.
+// It returns the rotated device and the rotation record.
+func (s *SigningService) RotateKey(deviceID string) (*domain.Device, *domain.Signature, error) {
+	// The rotation record takes a counter, so it is serialized with signing
+	deviceMutex := s.DeviceRepository.GetDeviceMutex(deviceID)
+	deviceMutex.Lock()
+	defer deviceMutex.Unlock()
+
+	device, err := s.DeviceRepository.GetDevice(deviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !device.IsActive() {
+		return nil, nil, &DeviceNotActiveError{Device: device}
+	}
+
+	algorithm, err := crypto.LookupAlgorithm(device.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Devices created before key parameters were stored generate with the algorithm defaults,
+	// their parameters stay untouched so they keep signing the way they always did
+	spec := keySpec(device)
+	spec.Parameters, err = algorithm.ResolveParameters(device.KeyParameters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyReference, err := s.keyProvider().CreateKey(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := s.keyProvider().PublicKey(spec, keyReference)
+	if err != nil {
+		return nil, nil, discardKey(s.keyProvider(), keyReference, err)
+	}
+
+	// Signed with the old key, as the device still refers to it
+	rotationRecord, err := s.signData(device, RotationData(string(publicKey)))
+	if err != nil {
+		return nil, nil, discardKey(s.keyProvider(), keyReference, err)
+	}
+
+	rotatedDevice := *device
+	rotatedDevice.KeyHistory = append(slices.Clone(device.KeyHistory), domain.RetiredKey{
+		PublicKey:    device.PublicKey,
+		FirstCounter: device.CurrentKeyFirstCounter(),
+		LastCounter:  rotationRecord.SignatureCounter,
+		RotatedAt:    rotationRecord.CreatedAt,
+	})
+	rotatedDevice.PublicKey = string(publicKey)
+	rotatedDevice.KeyReference = keyReference
+
+	err = s.UnitOfWork.Execute(func(tx persistence.ITransaction) error {
+		if err := tx.CreateSignature(rotationRecord); err != nil {
+			return err
+		}
+		if err := tx.UpdateDevice(&rotatedDevice); err != nil {
+			return err
+		}
+		if err := tx.IncrementSignatureCounter(device.ID, rotationRecord.SignatureCounter); err != nil {
+			return err
+		}
+		if err := s.signaturesCreated(tx, rotationRecord); err != nil {
+			return err
+		}
+		if s.OnKeyRotated != nil {
+			return s.OnKeyRotated(tx, &rotatedDevice)
+		}
+		return nil
+	})
+	if err != nil {
+		// Nothing refers to the new key, it must not outlive the failed rotation
+		return nil, nil, discardKey(s.keyProvider(), keyReference, err)
+	}
+	s.publish(rotationRecord)
+
+	// Reload the device for its counter, which the update above leaves to the repository
+	device, err = s.DeviceRepository.GetDevice(deviceID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return device, rotationRecord, nil
+}
+
+// signaturesCreated hands signatures about to be stored as part of tx to OnSignatures.
+func (s *SigningService) signaturesCreated(tx persistence.ITransaction, signatures ...*domain.Signature) error {
+	if s.OnSignatures != nil {
+		return s.OnSignatures(tx, signatures)
+	}
+	return nil
+}
+
+// publish announces signatures once they are committed. Callers publish while holding the
+// device lock, so watchers see the signatures of a device in counter order.
+func (s *SigningService) publish(signatures ...*domain.Signature) {
+	if s.Broker != nil {
+		s.Broker.Publish(signatures...)
+	}
+}
+
+// signData signs data with the device key and chains it to the latest signature of the device.
+// The returned signature is not persisted yet.
+func (s *SigningService) signData(device *domain.Device, data string) (*domain.Signature, error) {
+	var latestSignature *domain.Signature
+	if device.SignatureCounter > 0 {
+		var err error
+		latestSignature, err = s.SignatureRepository.GetLatestSignature(device.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.signNext(device, data, latestSignature)
+}
+
+// signNext signs data as the successor of previous, or as the first signature of the device
+// if previous is nil. The returned signature is not persisted yet.
+func (s *SigningService) signNext(device *domain.Device, data string, previous *domain.Signature) (*domain.Signature, error) {
+	algorithm, err := crypto.LookupAlgorithm(device.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Build the raw string format
+	signature := &domain.Signature{
+		ID:        uuid.New().String(),
+		DeviceID:  device.ID,
+		Data:      data,
+		Algorithm: device.Algorithm,
+	}
+
+	if previous == nil {
+		signature.SignatureCounter = 0
+		signature.SignedData = BuildSignedData(0, data, ChainGenesis(device.ID))
+	} else {
+		signature.SignatureCounter = previous.SignatureCounter + 1
+		signature.SignedData = BuildSignedData(signature.SignatureCounter, data, previous.SignatureValue)
+		signature.PreviousSignatureID = previous.ID
+	}
+
+	// Sign the data, only its digest is handed to the key provider
+	digest, err := algorithm.Digest([]byte(signature.SignedData), device.KeyParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := s.keyProvider().SignDigest(keySpec(device), device.KeyReference, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	signature.SignatureValue = base64.StdEncoding.EncodeToString(value)
+	signature.CreatedAt = time.Now().UTC()
+
+	return signature, nil
+}
+
+// idempotentSignature returns the signature an earlier request with the same key created for
+// the device, or nil if there is none within the retention window. The key is bound to the
+// data of that request, it cannot be reused for a different transaction while it is retained.
+func (s *SigningService) idempotentSignature(deviceID string, key string, data string) (*domain.Signature, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	if err := s.SignatureRepository.DeleteIdempotencyKeysBefore(time.Now().Add(-s.idempotencyRetention())); err != nil {
+		return nil, err
+	}
+	signature, err := s.SignatureRepository.GetSignatureByIdempotencyKey(deviceID, key)
+	if err != nil || signature == nil {
+		return nil, err
+	}
+	if time.Since(signature.CreatedAt) >= s.idempotencyRetention() {
+		return nil, nil
+	}
+
+	if signature.Data != data {
+		return nil, ErrIdempotencyKeyReused
+	}
+	return signature, nil
+}
+
+// idempotencyRetention returns the configured retention window, or the default.
+func (s *SigningService) idempotencyRetention() time.Duration {
+	if s.IdempotencyRetention <= 0 {
+		return DefaultIdempotencyRetention
+	}
+	return s.IdempotencyRetention
+}
+
+// keyProvider returns the configured KeyProvider, or an unencrypted in-process one.
+func (s *SigningService) keyProvider() crypto.KeyProvider {
+	return keyProviderOrLocal(s.KeyProvider)
+}
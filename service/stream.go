@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/domain"
+	"github.com/fiskaly/coding-challenges/signing-service-challenge/persistence"
+)
+
+const (
+	// DefaultStreamBufferSize is how many signatures a watcher may fall behind before it is
+	// dropped and has to catch up from storage.
+	DefaultStreamBufferSize = 256
+
+	replayPageSize = 100
+)
+
+// SignatureBroker fans the signatures of a device out to its watchers. Publishing never
+// blocks: a subscriber that fell behind by more than its buffer is dropped, its channel is closed.
+type SignatureBroker struct {
+	mutex       sync.Mutex
+	bufferSize  int
+	subscribers map[string]map[*signatureSubscription]struct{}
+}
+
+type signatureSubscription struct {
+	deviceID   string
+	signatures chan *domain.Signature
+}
+
+func NewSignatureBroker(bufferSize int) *SignatureBroker {
+	if bufferSize <= 0 {
+		bufferSize = DefaultStreamBufferSize
+	}
+	return &SignatureBroker{
+		bufferSize:  bufferSize,
+		subscribers: make(map[string]map[*signatureSubscription]struct{}),
+	}
+}
+
+func (b *SignatureBroker) subscribe(deviceID string) *signatureSubscription {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	subscription := &signatureSubscription{
+		deviceID:   deviceID,
+		signatures: make(chan *domain.Signature, b.bufferSize),
+	}
+	if b.subscribers[deviceID] == nil {
+		b.subscribers[deviceID] = make(map[*signatureSubscription]struct{})
+	}
+	b.subscribers[deviceID][subscription] = struct{}{}
+	return subscription
+}
+
+// unsubscribe removes the subscription, it is a no-op if the subscription was dropped already.
+func (b *SignatureBroker) unsubscribe(subscription *signatureSubscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.remove(subscription)
+}
+
+func (b *SignatureBroker) remove(subscription *signatureSubscription) {
+	subscriptions := b.subscribers[subscription.deviceID]
+	if _, exists := subscriptions[subscription]; !exists {
+		return
+	}
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(b.subscribers, subscription.deviceID)
+	}
+	close(subscription.signatures)
+}
+
+// Publish hands stored signatures to the subscribers of their device.
+func (b *SignatureBroker) Publish(signatures ...*domain.Signature) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, signature := range signatures {
+		for subscription := range b.subscribers[signature.DeviceID] {
+			select {
+			case subscription.signatures <- signature:
+			default:
+				b.remove(subscription)
+			}
+		}
+	}
+}
+
+// WatchSignatures calls send with the signatures of a device after afterCounter in counter order,
+// first the stored ones and then those created while it runs. An afterCounter of -1 starts with
+// the first signature of the device. It returns when ctx is done or send fails.
+func (s *SigningService) WatchSignatures(ctx context.Context, deviceID string, afterCounter int, send func(*domain.Signature) error) error {
+	if s.Broker == nil {
+		return ErrWatchDisabled
+	}
+
+	for {
+		// Subscribe before catching up, so nothing created in between is missed
+		subscription := s.Broker.subscribe(deviceID)
+		lastCounter, err := s.replaySignatures(deviceID, afterCounter, send)
+		if err != nil {
+			s.Broker.unsubscribe(subscription)
+			return err
+		}
+		afterCounter = lastCounter
+
+		dropped, err := forwardSignatures(ctx, subscription, &afterCounter, send)
+		s.Broker.unsubscribe(subscription)
+		if !dropped || err != nil {
+			return err
+		}
+		// The watcher fell behind, catch up from storage and subscribe again
+	}
+}
+
+// replaySignatures sends the stored signatures after lastCounter and returns the last counter sent.
+func (s *SigningService) replaySignatures(deviceID string, lastCounter int, send func(*domain.Signature) error) (int, error) {
+	for {
+		afterCounter := lastCounter
+		signatures, err := s.SignatureRepository.ListSignatures(persistence.SignatureQuery{
+			DeviceID:     deviceID,
+			AfterCounter: &afterCounter,
+			Limit:        replayPageSize,
+		})
+		if err != nil {
+			return lastCounter, err
+		}
+
+		for _, signature := range signatures {
+			if err := send(signature); err != nil {
+				return lastCounter, err
+			}
+			lastCounter = signature.SignatureCounter
+		}
+		if len(signatures) < replayPageSize {
+			return lastCounter, nil
+		}
+	}
+}
+
+// forwardSignatures sends published signatures until ctx is done or the subscription is dropped.
+func forwardSignatures(ctx context.Context, subscription *signatureSubscription, lastCounter *int, send func(*domain.Signature) error) (bool, error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case signature, ok := <-subscription.signatures:
+			if !ok {
+				return true, nil
+			}
+			// Already sent while catching up from storage
+			if signature.SignatureCounter <= *lastCounter {
+				continue
+			}
+			if err := send(signature); err != nil {
+				return false, err
+			}
+			*lastCounter = signature.SignatureCounter
+		}
+	}
+}